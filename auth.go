@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+)
+
+// authVerifier builds the bearer token verifier chosen by AUTH_MODE:
+//
+//	jwks    tokens from an identity provider, checked against AUTH_JWKS_URL
+//	static  HS256 tokens signed with AUTH_STATIC_KEY, for development
+//	none    no authentication; the audit actor is taken from X-Actor
+//
+// AUTH_ISSUER and AUTH_AUDIENCE, when set, must match the token's iss and
+// aud claims. It returns nil for none.
+func authVerifier() (*auth.Verifier, error) {
+	opts := authOptions()
+	switch mode := os.Getenv("AUTH_MODE"); mode {
+	case "jwks":
+		url := os.Getenv("AUTH_JWKS_URL")
+		if url == "" {
+			return nil, errors.New("AUTH_JWKS_URL must be set when AUTH_MODE is jwks")
+		}
+		return auth.NewJWKSVerifier(url, nil, opts), nil
+	case "static":
+		return auth.NewStaticVerifier([]byte(os.Getenv("AUTH_STATIC_KEY")), opts)
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown AUTH_MODE %q, want jwks, static or none", mode)
+	}
+}
+
+func authOptions() auth.Options {
+	return auth.Options{
+		Issuer:   os.Getenv("AUTH_ISSUER"),
+		Audience: os.Getenv("AUTH_AUDIENCE"),
+		Leeway:   30 * time.Second,
+	}
+}
+
+// allowAll stands in for handlers.Authorize when authentication is off.
+func allowAll(...auth.Action) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc { return next }
+}
+
+var errTokenUsage = errors.New("usage: token [-ttl duration] [-roles r1,r2] [-funds id1,id2] [-investors id1,id2] [-tenant id] subject")
+
+// runToken prints a token for subject signed with AUTH_STATIC_KEY, for
+// calling a server in static mode.
+func runToken(args []string) error {
+	fs := flag.NewFlagSet("token", flag.ContinueOnError)
+	ttl := fs.Duration("ttl", time.Hour, "how long the token is valid")
+	roles := fs.String("roles", string(auth.RoleAdmin), "comma-separated roles")
+	funds := fs.String("funds", "", "comma-separated fund IDs, for fund-manager")
+	investors := fs.String("investors", "", "comma-separated investor IDs, for lp")
+	tenant := fs.String("tenant", "", "tenant to bind the token to; default is the default tenant, or none for operators")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errTokenUsage
+	}
+	key := []byte(os.Getenv("AUTH_STATIC_KEY"))
+	if len(key) < auth.MinStaticKeyLength {
+		return fmt.Errorf("AUTH_STATIC_KEY must be at least %d bytes", auth.MinStaticKeyLength)
+	}
+	roleList := splitList(*roles)
+	claims := map[string]any{"roles": roleList}
+	if *funds != "" {
+		claims["fund_ids"] = splitList(*funds)
+	}
+	if *investors != "" {
+		claims["investor_ids"] = splitList(*investors)
+	}
+	if *tenant == "" && !slices.Contains(roleList, string(auth.RoleOperator)) {
+		*tenant = models.DefaultTenant
+	}
+	if *tenant != "" {
+		claims["tenant_id"] = *tenant
+	}
+	token, err := auth.Sign(key, fs.Arg(0), *ttl, authOptions(), claims)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,154 @@
+// Package auth verifies the JWT bearer tokens and API keys that authenticate
+// API callers and carries the resulting Identity in a request's context.
+//
+// Tokens are checked against either the keys an OIDC provider publishes as a
+// JWKS, or a static HMAC key for development and tests. Either way the
+// signature, expiry and, when configured, the issuer and audience must be
+// valid.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// MinStaticKeyLength is the shortest HMAC key NewStaticVerifier accepts.
+const MinStaticKeyLength = 32
+
+// Identity is the authenticated caller.
+type Identity struct {
+	// Subject is the token's sub claim, which identifies the caller to its
+	// issuer.
+	Subject string
+	Issuer  string
+	// Tenant is the tenant the caller is bound to, read from the tenant_id
+	// claim. Operators bound to none may choose one per request; other
+	// callers bound to none may do nothing.
+	Tenant string
+	// Roles, FundIDs and InvestorIDs are read from the roles, fund_ids and
+	// investor_ids claims, and decide what the caller may do; see Policy
+	// and Identity.Scope.
+	Roles       []Role
+	FundIDs     []uuid.UUID
+	InvestorIDs []uuid.UUID
+	// Actions are granted directly, rather than by a role. Only API keys
+	// have them: their scopes.
+	Actions []Action
+	// Claims holds every claim in the token, including the registered ones.
+	// It is nil for API keys.
+	Claims jwt.MapClaims
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the Identity stored in ctx, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(contextKey{}).(Identity)
+	return id, ok
+}
+
+// Options are the claims a Verifier requires beyond a valid signature and
+// expiry. Empty fields are not checked.
+type Options struct {
+	Issuer   string
+	Audience string
+	// Leeway allows for clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+}
+
+// keyFunc looks up the key that verifies token.
+type keyFunc func(ctx context.Context, token *jwt.Token) (any, error)
+
+// Verifier checks bearer tokens.
+type Verifier struct {
+	key    keyFunc
+	parser *jwt.Parser
+}
+
+func newVerifier(key keyFunc, algs []string, opts Options) *Verifier {
+	parserOpts := []jwt.ParserOption{
+		jwt.WithValidMethods(algs),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(opts.Leeway),
+	}
+	if opts.Issuer != "" {
+		parserOpts = append(parserOpts, jwt.WithIssuer(opts.Issuer))
+	}
+	if opts.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(opts.Audience))
+	}
+	return &Verifier{key: key, parser: jwt.NewParser(parserOpts...)}
+}
+
+// NewStaticVerifier returns a Verifier for HS256 tokens signed with key. It
+// is meant for development and tests, where there is no identity provider;
+// see Sign.
+func NewStaticVerifier(key []byte, opts Options) (*Verifier, error) {
+	if len(key) < MinStaticKeyLength {
+		return nil, fmt.Errorf("static key must be at least %d bytes", MinStaticKeyLength)
+	}
+	return newVerifier(func(context.Context, *jwt.Token) (any, error) {
+		return key, nil
+	}, []string{jwt.SigningMethodHS256.Alg()}, opts), nil
+}
+
+// Verify checks token and returns the caller it identifies. A token without
+// a subject is rejected, since there would be nobody to attribute changes to.
+func (v *Verifier) Verify(ctx context.Context, token string) (Identity, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		return v.key(ctx, t)
+	})
+	if err != nil {
+		return Identity{}, err
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return Identity{}, errors.New("token has no subject")
+	}
+	iss, _ := claims.GetIssuer()
+	id := Identity{
+		Subject:     sub,
+		Issuer:      iss,
+		FundIDs:     idsClaim(claims["fund_ids"]),
+		InvestorIDs: idsClaim(claims["investor_ids"]),
+		Claims:      claims,
+	}
+	id.Tenant, _ = claims["tenant_id"].(string)
+	for _, role := range stringsClaim(claims["roles"]) {
+		id.Roles = append(id.Roles, Role(role))
+	}
+	return id, nil
+}
+
+// Sign returns an HS256 token for subject, valid for ttl, that a static
+// Verifier with the same key and options accepts. Extra claims are added to
+// the token as is.
+func Sign(key []byte, subject string, ttl time.Duration, opts Options, extra map[string]any) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	if opts.Issuer != "" {
+		claims["iss"] = opts.Issuer
+	}
+	if opts.Audience != "" {
+		claims["aud"] = opts.Audience
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
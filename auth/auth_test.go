@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+var staticKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestStaticVerifier(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{Issuer: "https://issuer.example.com", Audience: "titanbay"}
+	v, err := NewStaticVerifier(staticKey, opts)
+	assert.NoError(t, err)
+
+	token, err := Sign(staticKey, "alice", time.Minute, opts, map[string]any{"email": "alice@example.com"})
+	assert.NoError(t, err)
+	id, err := v.Verify(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", id.Subject)
+	assert.Equal(t, "https://issuer.example.com", id.Issuer)
+	assert.Equal(t, "alice@example.com", id.Claims["email"])
+
+	for name, token := range map[string]string{
+		"expired":      must(Sign(staticKey, "alice", -time.Minute, opts, nil)),
+		"wrong key":    must(Sign([]byte("another key that is long enough!"), "alice", time.Minute, opts, nil)),
+		"wrong issuer": must(Sign(staticKey, "alice", time.Minute, Options{Issuer: "https://evil.example.com", Audience: "titanbay"}, nil)),
+		"no audience":  must(Sign(staticKey, "alice", time.Minute, Options{Issuer: opts.Issuer}, nil)),
+		"no subject":   must(Sign(staticKey, "", time.Minute, opts, nil)),
+		"no expiry":    must(jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "alice", "iss": opts.Issuer, "aud": opts.Audience}).SignedString(staticKey)),
+		"unsigned":     must(jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Minute).Unix()}).SignedString(jwt.UnsafeAllowNoneSignatureType)),
+		"garbage":      "not.a.token",
+	} {
+		_, err := v.Verify(ctx, token)
+		assert.Error(t, err, name)
+	}
+
+	_, err = NewStaticVerifier([]byte("short"), opts)
+	assert.Error(t, err)
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	ctx := context.Background()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	var fetches atomic.Int32
+	keys := []map[string]string{rsaJWK("rsa-1", &rsaKey.PublicKey)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	defer srv.Close()
+	v := NewJWKSVerifier(srv.URL, srv.Client(), Options{Issuer: "https://issuer.example.com"})
+	sign := func(method jwt.SigningMethod, kid string, key any) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{
+			"sub": "bob",
+			"iss": "https://issuer.example.com",
+			"exp": time.Now().Add(time.Minute).Unix(),
+		})
+		token.Header["kid"] = kid
+		return must(token.SignedString(key))
+	}
+
+	id, err := v.Verify(ctx, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey))
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", id.Subject)
+	_, err = v.Verify(ctx, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load(), "keys are cached")
+
+	_, err = v.Verify(ctx, sign(jwt.SigningMethodHS256, "rsa-1", staticKey))
+	assert.Error(t, err, "HMAC tokens are rejected")
+
+	keys = append(keys, ecJWK("ec-1", &ecKey.PublicKey))
+	_, err = v.Verify(ctx, sign(jwt.SigningMethodES256, "ec-1", ecKey))
+	assert.Error(t, err, "unknown kids are not refetched more than once a minute")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	keys = keys[:1]
+	set := &jwks{url: srv.URL, client: srv.Client(), maxAge: time.Hour}
+	v = newVerifier(set.key, jwksAlgorithms, Options{})
+	_, err = v.Verify(ctx, sign(jwt.SigningMethodRS256, "rsa-1", rsaKey))
+	assert.NoError(t, err)
+	keys = append(keys, ecJWK("ec-1", &ecKey.PublicKey))
+	id, err = v.Verify(ctx, sign(jwt.SigningMethodES256, "ec-1", ecKey))
+	assert.NoError(t, err, "a rotated key is picked up")
+	assert.Equal(t, "bob", id.Subject)
+}
+
+func TestJWKSVerifier_FetchFails(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	v := NewJWKSVerifier(srv.URL, srv.Client(), Options{})
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	token := must(jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "bob", "exp": time.Now().Add(time.Minute).Unix()}).SignedString(rsaKey))
+	_, err = v.Verify(context.Background(), token)
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+	id, ok := FromContext(NewContext(context.Background(), Identity{Subject: "alice"}))
+	assert.True(t, ok)
+	assert.Equal(t, "alice", id.Subject)
+}
+
+func must(s string, err error) string {
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   encode(key.N),
+		"e":   encode(big.NewInt(int64(key.E))),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   encode(key.X),
+		"y":   encode(key.Y),
+	}
+}
+
+func encode(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// jwksAlgorithms are the asymmetric algorithms accepted from a JWKS. HMAC is
+// left out, so that a public key can never be used as a shared secret.
+var jwksAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// NewJWKSVerifier returns a Verifier for tokens signed with the keys
+// published at url, such as an OIDC provider's jwks_uri. Keys are fetched
+// on first use and cached for an hour. A token with an unknown kid triggers
+// a refetch, at most once a minute, so that rotated keys are picked up.
+func NewJWKSVerifier(url string, client *http.Client, opts Options) *Verifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	set := &jwks{url: url, client: client, maxAge: time.Hour, minRefresh: time.Minute}
+	return newVerifier(set.key, jwksAlgorithms, opts)
+}
+
+// jwks caches the keys of a JSON Web Key Set by kid.
+type jwks struct {
+	url        string
+	client     *http.Client
+	maxAge     time.Duration
+	minRefresh time.Duration
+
+	mu      sync.Mutex
+	keys    map[string]any
+	fetched time.Time
+}
+
+func (s *jwks) key(ctx context.Context, token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stale := time.Since(s.fetched) > s.maxAge
+	_, known := s.lookup(kid)
+	if stale || (!known && time.Since(s.fetched) > s.minRefresh) {
+		if err := s.fetch(ctx); err != nil && s.keys == nil {
+			return nil, err
+		}
+	}
+	key, ok := s.lookup(kid)
+	if !ok {
+		return nil, fmt.Errorf("no key with kid %q", kid)
+	}
+	return key, nil
+}
+
+// lookup finds kid among the cached keys. A token without a kid matches a
+// set with a single key.
+func (s *jwks) lookup(kid string) (any, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// fetch replaces the cached keys with those now published. Keys that cannot
+// be parsed, or that are not for signatures, are skipped.
+func (s *jwks) fetch(ctx context.Context) error {
+	s.fetched = time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: unexpected response status %s", resp.Status)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	s.keys = keys
+	return nil
+}
+
+// jwk is a public JSON Web Key (RFC 7517), RSA or EC.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() {
+			return nil, errors.New("RSA exponent is too large")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter %q", s)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+)
+
+// Role is a set of permitted actions, granted to callers by their token's
+// roles claim.
+type Role string
+
+const (
+	RoleAdmin       Role = "admin"
+	RoleOps         Role = "ops"
+	RoleFundManager Role = "fund-manager"
+	RoleReadOnly    Role = "read-only"
+	RoleLP          Role = "lp"
+	// RoleOperator permits no actions of its own. It lets a caller that is
+	// not bound to a tenant choose one per request; see Identity.Operator.
+	RoleOperator Role = "operator"
+)
+
+// Action is something a route does, named resource:verb.
+type Action string
+
+const (
+	FundRead         Action = "fund:read"
+	FundCreate       Action = "fund:create"
+	FundUpdate       Action = "fund:update"
+	FundDelete       Action = "fund:delete"
+	InvestorRead     Action = "investor:read"
+	InvestorCreate   Action = "investor:create"
+	InvestorUpdate   Action = "investor:update"
+	InvestorDelete   Action = "investor:delete"
+	InvestmentRead   Action = "investment:read"
+	InvestmentCreate Action = "investment:create"
+	InvestmentDelete Action = "investment:delete"
+	AuditRead        Action = "audit:read"
+	WebhookRead      Action = "webhook:read"
+	WebhookWrite     Action = "webhook:write"
+	APIKeyManage     Action = "api-key:manage"
+)
+
+// Actions lists every action.
+var Actions = []Action{
+	FundRead, FundCreate, FundUpdate, FundDelete,
+	InvestorRead, InvestorCreate, InvestorUpdate, InvestorDelete,
+	InvestmentRead, InvestmentCreate, InvestmentDelete,
+	AuditRead, WebhookRead, WebhookWrite, APIKeyManage,
+}
+
+// Policy maps each role to the actions it permits. Fund managers and LPs
+// are also limited to their own data; see Identity.Scope.
+var Policy = map[Role][]Action{
+	RoleAdmin: Actions,
+	RoleOps: {
+		FundRead,
+		InvestorRead, InvestorCreate, InvestorUpdate, InvestorDelete,
+		InvestmentRead, InvestmentCreate, InvestmentDelete,
+		AuditRead, WebhookRead, WebhookWrite,
+	},
+	RoleFundManager: {
+		FundRead, FundUpdate,
+		InvestorRead,
+		InvestmentRead, InvestmentCreate, InvestmentDelete,
+	},
+	RoleReadOnly: {FundRead, InvestorRead, InvestmentRead, AuditRead},
+	RoleLP:       {FundRead, InvestorRead, InvestmentRead},
+}
+
+// Operator reports whether the caller operates the deployment, and so may
+// act for any tenant unless its token binds it to one.
+func (id Identity) Operator() bool {
+	return slices.Contains(id.Roles, RoleOperator)
+}
+
+// Can reports whether the caller was granted action, directly or by any of
+// its roles.
+func (id Identity) Can(action Action) bool {
+	return slices.Contains(id.Actions, action) || slices.ContainsFunc(id.Roles, func(r Role) bool {
+		return slices.Contains(Policy[r], action)
+	})
+}
+
+// Scope returns the data the caller may see. Admins, ops and read-only
+// callers see everything, as do API keys, which only admins can issue.
+// Otherwise fund managers are limited to the funds
+// in their token's fund_ids claim, and LPs to the investors in its
+// investor_ids claim. A caller with neither kind of role sees nothing.
+func (id Identity) Scope() access.Scope {
+	if len(id.Actions) > 0 {
+		return access.Scope{}
+	}
+	if slices.ContainsFunc(id.Roles, func(r Role) bool {
+		return r == RoleAdmin || r == RoleOps || r == RoleReadOnly
+	}) {
+		return access.Scope{}
+	}
+	s := access.Scope{}
+	if slices.Contains(id.Roles, RoleFundManager) {
+		s.Funds = nonNilIDs(id.FundIDs)
+	}
+	if slices.Contains(id.Roles, RoleLP) {
+		s.Investors = nonNilIDs(id.InvestorIDs)
+	}
+	if s.Unrestricted() {
+		s.Funds = []uuid.UUID{}
+	}
+	return s
+}
+
+func nonNilIDs(ids []uuid.UUID) []uuid.UUID {
+	if ids == nil {
+		return []uuid.UUID{}
+	}
+	return ids
+}
+
+// stringsClaim reads a claim holding a list of strings, or a single string.
+func stringsClaim(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		s := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				s = append(s, str)
+			}
+		}
+		return s
+	}
+	return nil
+}
+
+// idsClaim reads a claim holding a list of UUIDs, skipping any that do not
+// parse.
+func idsClaim(value any) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, s := range stringsClaim(value) {
+		if id, err := uuid.Parse(s); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentity_Can(t *testing.T) {
+	admin := Identity{Roles: []Role{RoleAdmin}}
+	for _, action := range Actions {
+		assert.True(t, admin.Can(action), action)
+	}
+	lp := Identity{Roles: []Role{RoleLP}}
+	assert.True(t, lp.Can(FundRead))
+	assert.False(t, lp.Can(FundCreate))
+	assert.False(t, lp.Can(AuditRead))
+	both := Identity{Roles: []Role{RoleLP, RoleOps}}
+	assert.True(t, both.Can(InvestorCreate), "roles add up")
+	assert.False(t, Identity{Roles: []Role{"superuser"}}.Can(FundRead), "unknown roles permit nothing")
+	assert.False(t, Identity{}.Can(FundRead))
+
+	for role, actions := range Policy {
+		for _, action := range actions {
+			assert.Contains(t, Actions, action, role)
+		}
+	}
+}
+
+func TestIdentity_Scope(t *testing.T) {
+	fund, investor := uuid.New(), uuid.New()
+	assert.True(t, Identity{Roles: []Role{RoleReadOnly}, FundIDs: []uuid.UUID{fund}}.Scope().Unrestricted())
+	assert.True(t, Identity{Roles: []Role{RoleFundManager, RoleAdmin}}.Scope().Unrestricted())
+	assert.Equal(t, access.Scope{Funds: []uuid.UUID{fund}}, Identity{Roles: []Role{RoleFundManager}, FundIDs: []uuid.UUID{fund}}.Scope())
+	assert.Equal(t, access.Scope{Funds: []uuid.UUID{}}, Identity{Roles: []Role{RoleFundManager}}.Scope(), "a manager without funds sees none")
+	assert.Equal(t, access.Scope{Investors: []uuid.UUID{investor}}, Identity{Roles: []Role{RoleLP}, InvestorIDs: []uuid.UUID{investor}}.Scope())
+	assert.Equal(t, access.Scope{Funds: []uuid.UUID{}}, Identity{}.Scope(), "no roles sees nothing")
+}
+
+func TestAPIKeyIdentity(t *testing.T) {
+	key := uuid.New()
+	id := APIKeyIdentity(key, "acme", []string{"fund:read"})
+	assert.Equal(t, "api-key:"+key.String(), id.Subject)
+	assert.Equal(t, "acme", id.Tenant)
+	assert.True(t, id.Can(FundRead))
+	assert.False(t, id.Can(FundCreate))
+	assert.True(t, id.Scope().Unrestricted())
+
+	scopes := make([]Action, len(models.APIKeyScopes))
+	for i, s := range models.APIKeyScopes {
+		scopes[i] = Action(s)
+	}
+	assert.ElementsMatch(t, Actions, scopes, "every action can be granted to a key")
+
+	secret, prefix, hash, err := NewAPIKey()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, APIKeyPrefix))
+	assert.True(t, strings.HasPrefix(secret, prefix))
+	assert.Equal(t, HashAPIKey(secret), hash)
+	assert.NotContains(t, hash, secret[len(APIKeyPrefix):])
+}
+
+func TestVerify_RoleClaims(t *testing.T) {
+	v, err := NewStaticVerifier(staticKey, Options{})
+	assert.NoError(t, err)
+	fund := uuid.New()
+	token := must(Sign(staticKey, "maria", time.Minute, Options{}, map[string]any{
+		"roles":    []string{"fund-manager"},
+		"fund_ids": []string{fund.String(), "not-a-uuid"},
+	}))
+	id, err := v.Verify(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, []Role{RoleFundManager}, id.Roles)
+	assert.Equal(t, []uuid.UUID{fund}, id.FundIDs)
+	assert.Empty(t, id.InvestorIDs)
+	assert.Empty(t, id.Tenant)
+
+	id, err = v.Verify(context.Background(), must(Sign(staticKey, "ops", time.Minute, Options{}, map[string]any{"roles": "ops"})))
+	assert.NoError(t, err)
+	assert.Equal(t, []Role{RoleOps}, id.Roles, "a single role may be a string")
+
+	id, err = v.Verify(context.Background(), must(Sign(staticKey, "gp", time.Minute, Options{}, map[string]any{"tenant_id": "acme"})))
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", id.Tenant)
+}
@@ -0,0 +1,83 @@
+// Package access carries the data a caller may see, worked out from their
+// tenant and roles, from the HTTP layer down to the database.Db that filters
+// its queries by it.
+package access
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+)
+
+// Scope limits the funds, investors and investments a caller may read and
+// write. Callers only ever see the rows of their tenant. Within it, the zero
+// Scope is unrestricted. A caller limited to some funds sees
+// those funds, the investments in them and the investors who made those
+// investments; a caller limited to some investors sees those investors,
+// their investments and the funds they invested in. When both are set,
+// only rows that satisfy both are seen.
+type Scope struct {
+	// Tenant is the tenant the caller acts for; empty means
+	// models.DefaultTenant. See TenantID.
+	Tenant string
+	// Funds, if not nil, are the only funds the caller may see.
+	Funds []uuid.UUID
+	// Investors, if not nil, are the only investors the caller may see.
+	Investors []uuid.UUID
+}
+
+// TenantID returns the tenant whose rows s allows.
+func (s Scope) TenantID() string {
+	return cmp.Or(s.Tenant, models.DefaultTenant)
+}
+
+// Unrestricted reports whether s allows everything in its tenant.
+func (s Scope) Unrestricted() bool {
+	return s.Funds == nil && s.Investors == nil
+}
+
+// HasFund reports whether s names the fund, or does not limit funds.
+func (s Scope) HasFund(id uuid.UUID) bool {
+	return s.Funds == nil || slices.Contains(s.Funds, id)
+}
+
+// HasInvestor reports whether s names the investor, or does not limit
+// investors.
+func (s Scope) HasInvestor(id uuid.UUID) bool {
+	return s.Investors == nil || slices.Contains(s.Investors, id)
+}
+
+// HasInvestment reports whether s allows an investment by investorID in
+// fundID.
+func (s Scope) HasInvestment(fundID, investorID uuid.UUID) bool {
+	return s.HasFund(fundID) && s.HasInvestor(investorID)
+}
+
+// CanWriteFund reports whether a caller with s may change the fund. Callers
+// limited to investors may see funds, but not change them.
+func (s Scope) CanWriteFund(id uuid.UUID) bool {
+	return s.Investors == nil && s.HasFund(id)
+}
+
+// CanWriteInvestor reports whether a caller with s may change the investor.
+// Callers limited to funds may see investors, but not change them.
+func (s Scope) CanWriteInvestor(id uuid.UUID) bool {
+	return s.Funds == nil && s.HasInvestor(id)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying s.
+func NewContext(ctx context.Context, s Scope) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the Scope stored in ctx, or the zero Scope, which
+// allows everything in the default tenant, for work done outside a request.
+func FromContext(ctx context.Context) Scope {
+	s, _ := ctx.Value(contextKey{}).(Scope)
+	return s
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lastUsedResolution is how stale an API key's LastUsedAt may get before
+// UseAPIKey updates it, so that a busy client does not write on every
+// request.
+const lastUsedResolution = time.Minute
+
+func (g *gormDb) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	key = newAPIKey(key, tenantOf(ctx), g.db.NowFunc())
+	err := g.db.WithContext(ctx).Create(&key).Error
+	return key, g.translate(err, "api key")
+}
+
+func (g *gormDb) ReadAPIKeys(ctx context.Context, query models.APIKeyQuery) (models.Page[models.APIKey], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.APIKey]{}, err
+	}
+	tx := inTenant(ctx, g.db.WithContext(ctx))
+	if !query.IncludeRevoked {
+		tx = tx.Where("revoked_at IS NULL")
+	}
+	return paginate[models.APIKey](tx, query.PageQuery)
+}
+
+func (g *gormDb) ReadAPIKeyByID(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	var key models.APIKey
+	return key, g.translate(inTenant(ctx, g.db.WithContext(ctx)).First(&key, "id = ?", id).Error, "api key")
+}
+
+func (g *gormDb) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (models.APIKey, error) {
+	var key models.APIKey
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := inTenant(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&key, "id = ? AND revoked_at IS NULL", id).Error
+		if err != nil {
+			return err
+		}
+		key = rotateAPIKey(key, hash, prefix, tx.NowFunc())
+		return tx.Save(&key).Error
+	})
+	if err != nil {
+		return models.APIKey{}, g.translate(err, "api key")
+	}
+	return key, nil
+}
+
+func (g *gormDb) RevokeAPIKey(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	var key models.APIKey
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := inTenant(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&key, "id = ? AND revoked_at IS NULL", id).Error
+		if err != nil {
+			return err
+		}
+		now := tx.NowFunc()
+		key.RevokedAt = &now
+		return tx.Model(&key).Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return models.APIKey{}, g.translate(err, "api key")
+	}
+	return key, nil
+}
+
+func (g *gormDb) UseAPIKey(ctx context.Context, hash string) (models.APIKey, error) {
+	tx := g.db.WithContext(ctx)
+	now := tx.NowFunc()
+	var key models.APIKey
+	err := tx.First(&key, "hash = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", hash, now).Error
+	if err != nil {
+		return models.APIKey{}, g.translate(err, "api key")
+	}
+	if !usedRecently(key, now) {
+		key.LastUsedAt = &now
+		if err := tx.Model(&key).Update("last_used_at", now).Error; err != nil {
+			return models.APIKey{}, g.translate(err, "api key")
+		}
+	}
+	return key, nil
+}
+
+// newAPIKey returns key as created at now, in tenant. MockDb uses it too.
+func newAPIKey(key models.APIKey, tenant string, now time.Time) models.APIKey {
+	return models.APIKey{
+		ID:        uuid.New(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Scopes:    nonNil(key.Scopes),
+		CreatedBy: key.CreatedBy,
+		CreatedAt: now,
+		ExpiresAt: key.ExpiresAt,
+		Tenancy:   models.Tenancy{TenantID: tenant},
+	}
+}
+
+// rotateAPIKey replaces the secret of key at now, keeping everything else.
+func rotateAPIKey(key models.APIKey, hash, prefix string, now time.Time) models.APIKey {
+	key.Hash = hash
+	key.Prefix = prefix
+	key.RotatedAt = &now
+	return key
+}
+
+func usedRecently(key models.APIKey, now time.Time) bool {
+	return key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < lastUsedResolution
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/audit"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"gorm.io/gorm"
+)
+
+// actorOf returns who is making the changes of ctx: its audit actor, or
+// "system" outside a request.
+func actorOf(ctx context.Context) string {
+	return cmp.Or(audit.FromContext(ctx).Actor, "system")
+}
+
+// newAuditEntry describes the change of an entity from before to after,
+// either of which is nil when there is no such state. The actor, request ID
+// and tenant come from ctx; changes made outside a request are attributed to
+// "system".
+func newAuditEntry(ctx context.Context, entity string, id uuid.UUID, action string, before, after any) (models.AuditEntry, error) {
+	changes, err := diffFields(before, after)
+	if err != nil {
+		return models.AuditEntry{}, err
+	}
+	src := audit.FromContext(ctx)
+	return models.AuditEntry{
+		Entity:    entity,
+		EntityID:  id,
+		Action:    action,
+		Actor:     actorOf(ctx),
+		RequestID: src.RequestID,
+		Changes:   changes,
+		Tenancy:   models.Tenancy{TenantID: tenantOf(ctx)},
+	}, nil
+}
+
+// diffFields compares the JSON representations of before and after, so that
+// the audit log names fields as the API does.
+func diffFields(before, after any) (map[string]models.FieldChange, error) {
+	b, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+	changes := make(map[string]models.FieldChange)
+	for field, value := range a {
+		if !bytes.Equal(b[field], value) {
+			changes[field] = models.FieldChange{Before: b[field], After: value}
+		}
+	}
+	for field, value := range b {
+		if _, ok := a[field]; !ok {
+			changes[field] = models.FieldChange{Before: value}
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	return fields, json.Unmarshal(b, &fields)
+}
+
+// record appends an entry to the audit log. tx must be the transaction that
+// made the change, so that the two are committed or rolled back together.
+func record(ctx context.Context, tx *gorm.DB, entity string, id uuid.UUID, action string, before, after any) error {
+	entry, err := newAuditEntry(ctx, entity, id, action, before, after)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&entry).Error
+}
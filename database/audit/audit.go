@@ -0,0 +1,26 @@
+// Package audit carries the source of a change, meaning who made it and in
+// which request, from the HTTP layer down to the database.Db that records it
+// in the audit log.
+package audit
+
+import "context"
+
+// Source identifies who made a change.
+type Source struct {
+	Actor     string
+	RequestID string
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying src.
+func NewContext(ctx context.Context, src Source) context.Context {
+	return context.WithValue(ctx, contextKey{}, src)
+}
+
+// FromContext returns the Source stored in ctx, or the zero Source for
+// changes made outside a request.
+func FromContext(ctx context.Context) Source {
+	src, _ := ctx.Value(contextKey{}).(Source)
+	return src
+}
@@ -0,0 +1,21 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestConformance(t *testing.T) {
+	dbtest.RunAll(t)
+}
+
+// TestConformance_Traced checks that the tracing decorator passes every
+// call through unchanged.
+func TestConformance_Traced(t *testing.T) {
+	dbtest.Run(t, func(*testing.T) database.Db {
+		return database.Traced(database.NewMockDb(), noop.NewTracerProvider())
+	})
+}
@@ -1,30 +1,80 @@
 package database
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 
-	"github.com/iuhmirza/titanbay-take-home/models"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-
-
-func ConnectToDB() (Db, error) {
+// Open connects to the database named by DB_URL without checking its schema,
+// for use by the migrate subcommand. A sqlite:// URL, e.g. sqlite://demo.db
+// or sqlite://:memory:, opens an SQLite database; anything else is passed to
+// the PostgreSQL driver. Queries are logged as DB_LOG_LEVEL and
+// DB_SLOW_QUERY say.
+func Open() (*gorm.DB, error) {
 	dbUrl := os.Getenv("DB_URL")
 	if dbUrl == "" {
-		log.Fatal("Environment variable DB_URL not set.")
+		return nil, errors.New("environment variable DB_URL not set")
+	}
+	queries, err := newQueryLogger()
+	if err != nil {
+		return nil, err
 	}
+	var db *gorm.DB
+	if path, ok := strings.CutPrefix(dbUrl, "sqlite://"); ok {
+		db, err = OpenSQLite(path)
+	} else {
+		db, err = OpenPostgres(dbUrl)
+	}
+	if err != nil {
+		return nil, err
+	}
+	db.Logger = queries
+	return db, nil
+}
 
-	db, err := gorm.Open(postgres.Open(dbUrl), &gorm.Config{})
+// OpenPostgres opens the PostgreSQL database described by dsn.
+func OpenPostgres(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+}
+
+// ConnectToDB opens the database and refuses to return it unless every
+// embedded migration has been applied. SQLite databases are migrated here
+// instead, as they are never shared between replicas.
+func ConnectToDB() (Db, error) {
+	db, err := Open()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := db.AutoMigrate(&models.Fund{}, &models.Investor{}, &models.Investment{}); err != nil {
-		log.Fatalf("Failed to automatically migrate database: %v", err)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if db.Dialector.Name() == "sqlite" {
+		if err := migrator.Up(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	current, err := migrator.Current(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if current < migrator.Latest() {
+		return nil, fmt.Errorf("database schema is at version %d but %d is required, run `migrate up` first", current, migrator.Latest())
+	}
+	if current > migrator.Latest() {
+		slog.Warn("Database schema is newer than this binary", "version", current, "latest", migrator.Latest())
+	}
+	slog.Info("Database schema is up to date", "version", current)
+	if db.Dialector.Name() == "sqlite" {
+		return NewSQLiteDB(db), nil
 	}
-	log.Println("Migrated database successfully")
-	return &PGDB{db}, nil
+	return NewPGDB(db), nil
 }
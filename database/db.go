@@ -1,83 +1,175 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/iuhmirza/titanbay-take-home/models"
 	"gorm.io/gorm"
 )
 
-type Db interface {
-	CreateFund(models.CreateFund) (models.Fund, error)
-	ReadFunds() ([]models.Fund, error)
-	UpdateFund(models.Fund) (models.Fund, error)
-	ReadFundByID(uuid.UUID) (models.Fund, error)
-	CreateInvestor(models.CreateInvestor) (models.Investor, error)
-	ReadInvestors() ([]models.Investor, error)
-	CreateInvestment(models.CreateInvestment) (models.Investment, error)
-	ReadInvestments(uuid.UUID) ([]models.Investment, error)
-}
-
-type PGDB struct {
-	db *gorm.DB
+// Reader holds the Db methods that read funds, investors and investments,
+// which are all that a view of the past returned by Db.AsOf supports. They
+// only see the rows allowed by the access.Scope in their context, and the
+// Db methods that write them report other rows as not found.
+type Reader interface {
+	ReadFunds(context.Context, models.FundQuery) (models.Page[models.Fund], error)
+	// ReadFundByID returns soft-deleted funds too, unlike ReadFunds, so that
+	// they can be inspected and restored. ReadInvestorByID does the same.
+	ReadFundByID(context.Context, uuid.UUID) (models.Fund, error)
+	ReadInvestors(context.Context, models.InvestorQuery) (models.Page[models.Investor], error)
+	ReadInvestorByID(context.Context, uuid.UUID) (models.Investor, error)
+	ReadInvestments(context.Context, uuid.UUID, models.InvestmentQuery) (models.Page[models.Investment], error)
+	Search(context.Context, models.SearchQuery) (models.Page[models.SearchResult], error)
 }
 
-func (pgdb *PGDB) CreateFund(createFund models.CreateFund) (models.Fund, error) {
-	fund := models.Fund{
-		Name:          createFund.Name,
-		VintageYear:   createFund.VintageYear,
-		TargetSizeUsd: createFund.TargetSizeUsd,
-		Status:        createFund.Status,
-	}
-	return fund, pgdb.db.Create(&fund).Error
+type Db interface {
+	Reader
+	CreateFund(context.Context, models.CreateFund) (models.Fund, error)
+	// UpdateFund replaces every field of the fund and increments its version.
+	// A non-zero Version must match the stored one, or a dberr.VersionMismatch
+	// is returned. UpdateInvestor behaves the same way.
+	UpdateFund(context.Context, models.Fund) (models.Fund, error)
+	// DeleteFund soft-deletes a live fund and increments its version. It
+	// fails with dberr.InUse while the fund has live investments.
+	// RestoreFund undoes it, and is a no-op for a live fund. Deleted funds
+	// cannot be updated or invested in. The investor methods behave the
+	// same way.
+	DeleteFund(context.Context, uuid.UUID, models.DeleteResource) (models.Fund, error)
+	RestoreFund(context.Context, uuid.UUID) (models.Fund, error)
+	CreateInvestor(context.Context, models.CreateInvestor) (models.Investor, error)
+	UpdateInvestor(context.Context, models.Investor) (models.Investor, error)
+	DeleteInvestor(context.Context, uuid.UUID, models.DeleteResource) (models.Investor, error)
+	RestoreInvestor(context.Context, uuid.UUID) (models.Investor, error)
+	CreateInvestment(context.Context, models.CreateInvestment) (models.Investment, error)
+	// DeleteInvestment and RestoreInvestment take the fund and investment
+	// IDs. An investment can only be restored while its fund and investor
+	// are live.
+	DeleteInvestment(ctx context.Context, fundID, id uuid.UUID, del models.DeleteResource) (models.Investment, error)
+	RestoreInvestment(ctx context.Context, fundID, id uuid.UUID) (models.Investment, error)
+	// Every method that changes a fund, investor or investment appends to the
+	// audit log in the same transaction, attributing the change to the
+	// audit.Source in its context.
+	ReadAuditLog(context.Context, models.AuditQuery) (models.Page[models.AuditEntry], error)
+	// AsOf returns a view of the funds, investors and investments as they
+	// were at t, reconstructed from the versions that every change records
+	// alongside its audit entry. Rows created after t are absent, and rows
+	// deleted before t are soft-deleted in the view.
+	AsOf(t time.Time) Reader
+	// Writes append to an event store (see package events) and the tables
+	// are projections of it. RebuildProjections discards them and replays
+	// every event, returning how many there were.
+	RebuildProjections(context.Context) (int, error)
+	// Webhooks subscribe to events: every write queues a delivery of its
+	// event to each enabled webhook subscribed to it, in the same
+	// transaction. UpdateWebhook checks Version as UpdateFund does, and
+	// clears the failure count when it enables a webhook.
+	CreateWebhook(context.Context, models.CreateWebhook) (models.Webhook, error)
+	ReadWebhooks(context.Context, models.WebhookQuery) (models.Page[models.Webhook], error)
+	ReadWebhookByID(context.Context, uuid.UUID) (models.Webhook, error)
+	UpdateWebhook(context.Context, models.Webhook) (models.Webhook, error)
+	// DeleteWebhook deletes a webhook and its deliveries.
+	DeleteWebhook(context.Context, uuid.UUID) error
+	ReadWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, query models.WebhookDeliveryQuery) (models.Page[models.WebhookDelivery], error)
+	// ReplayWebhookDelivery queues the event of a past delivery again, as a
+	// new delivery.
+	ReplayWebhookDelivery(ctx context.Context, webhookID uuid.UUID, deliveryID int) (models.WebhookDelivery, error)
+	// ClaimWebhookDeliveries returns up to limit pending deliveries that are
+	// due, oldest first, skipping those of disabled webhooks. Claimed
+	// deliveries are not due again until lease has passed, so that several
+	// dispatchers can share the queue; RecordWebhookAttempt reports the
+	// outcome of sending one.
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error)
+	RecordWebhookAttempt(context.Context, models.WebhookAttempt) error
+	// ReserveIdempotencyKey stores key, as in progress and expiring after
+	// ttl, unless the key is already stored and has not expired. It returns
+	// the stored key and whether this call reserved it. The reservation is
+	// then either completed with the response, or released so that the
+	// request can be retried.
+	ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl time.Duration) (models.IdempotencyKey, bool, error)
+	CompleteIdempotencyKey(context.Context, models.IdempotencyKey) error
+	ReleaseIdempotencyKey(context.Context, models.IdempotencyKey) error
+	// PurgeIdempotencyKeys deletes expired keys, returning how many.
+	PurgeIdempotencyKeys(context.Context) (int, error)
+	// API keys authenticate machine clients. Only a hash of each key is
+	// stored: CreateAPIKey and RotateAPIKey take it from the caller, which
+	// generates the key. Rotating or revoking a key that is already revoked
+	// fails with dberr.NotFound, and ReadAPIKeys omits revoked keys unless
+	// asked for them.
+	CreateAPIKey(context.Context, models.APIKey) (models.APIKey, error)
+	ReadAPIKeys(context.Context, models.APIKeyQuery) (models.Page[models.APIKey], error)
+	ReadAPIKeyByID(context.Context, uuid.UUID) (models.APIKey, error)
+	RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (models.APIKey, error)
+	RevokeAPIKey(context.Context, uuid.UUID) (models.APIKey, error)
+	// UseAPIKey returns the active key with the given hash, recording that
+	// it was used, or dberr.NotFound if the key is unknown, revoked or
+	// expired.
+	UseAPIKey(ctx context.Context, hash string) (models.APIKey, error)
+	// ReadFundStats summarises the live funds of every tenant, by status,
+	// for monitoring. It ignores the access.Scope of its context.
+	ReadFundStats(context.Context) ([]models.FundStats, error)
+	// ReadFundCommitments returns the limit live funds, of every tenant,
+	// with the most capital committed, most first, for monitoring. It
+	// ignores the access.Scope of its context.
+	ReadFundCommitments(ctx context.Context, limit int) ([]models.FundCommitment, error)
+	// WithTx runs fn inside a transaction, committing if it returns nil and
+	// rolling back otherwise. Nested calls use savepoints.
+	WithTx(context.Context, func(Db) error) error
 }
 
-func (pgdb *PGDB) ReadFunds() ([]models.Fund, error) {
-	funds := make([]models.Fund, 0, 32)
-	return funds, pgdb.db.Find(&funds).Error
+type PGDB struct {
+	gormDb
 }
 
-func (pgdb *PGDB) ReadFundByID(id uuid.UUID) (models.Fund, error) {
-	var fund models.Fund
-	return fund, pgdb.db.First(&fund, "id = ?", id).Error
+func NewPGDB(db *gorm.DB) *PGDB {
+	return &PGDB{gormDb{db: db, translate: translatePGError}}
 }
 
-func (pgdb *PGDB) UpdateFund(fund models.Fund) (models.Fund, error) {
-	f := models.Fund{ID: fund.ID}
-	if err := pgdb.db.First(&f).Error; err != nil {
-		return models.Fund{}, err
+func (pgdb *PGDB) Search(ctx context.Context, query models.SearchQuery) (models.Page[models.SearchResult], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.SearchResult]{}, err
 	}
+	args := searchArgs(query)
+	args["funds"] = pgdb.from(ctx, "funds")
+	args["investors"] = pgdb.from(ctx, "investors")
 
-	return fund, pgdb.db.Save(&fund).Error
-}
+	var fundRows []struct {
+		models.Fund
+		Score float64
+	}
+	err := pgdb.db.WithContext(ctx).Raw(`SELECT * FROM (SELECT funds.*, `+scoreSQL("name", true)+` AS score
+		FROM (@funds) funds WHERE deleted_at IS NULL AND `+matchSQL("name", true)+`) matches
+		ORDER BY score DESC, name, id LIMIT @limit`, args).Scan(&fundRows).Error
+	if err != nil {
+		return models.Page[models.SearchResult]{}, err
+	}
 
-func (pgdb *PGDB) CreateInvestor(createInvestor models.CreateInvestor) (models.Investor, error) {
-	investor := models.Investor{
-		Name:         createInvestor.Name,
-		InvestorType: createInvestor.InvestorType,
-		Email:        createInvestor.Email,
+	var investorRows []struct {
+		models.Investor
+		Score float64
+	}
+	err = pgdb.db.WithContext(ctx).Raw(`SELECT * FROM (SELECT investors.*, GREATEST(`+scoreSQL("name", true)+`, `+scoreSQL("email", false)+`) AS score
+		FROM (@investors) investors WHERE deleted_at IS NULL AND (`+matchSQL("name", true)+` OR `+matchSQL("email", false)+`)) matches
+		ORDER BY score DESC, name, id LIMIT @limit`, args).Scan(&investorRows).Error
+	if err != nil {
+		return models.Page[models.SearchResult]{}, err
 	}
 
-	return investor, pgdb.db.Create(&investor).Error
+	results := make([]models.SearchResult, 0, len(fundRows)+len(investorRows))
+	for _, f := range fundRows {
+		results = append(results, models.SearchResult{Type: "fund", Score: f.Score, Fund: &f.Fund})
+	}
+	for _, i := range investorRows {
+		results = append(results, models.SearchResult{Type: "investor", Score: i.Score, Investor: &i.Investor})
+	}
+	return models.Page[models.SearchResult]{Items: rankResults(results, query.PageSize())}, nil
 }
 
-func (pgdb *PGDB) ReadInvestors() ([]models.Investor, error) {
-	investors := make([]models.Investor, 0, 32)
-	return investors, pgdb.db.Find(&investors).Error
+func (pgdb *PGDB) AsOf(t time.Time) Reader {
+	return &PGDB{pgdb.asOfView(t)}
 }
 
-func (pgdb *PGDB) CreateInvestment(createInvestment models.CreateInvestment) (models.Investment, error) {
-	investment := models.Investment{
-		InvestorID:     createInvestment.InvestorID,
-		AmountUsd:      createInvestment.AmountUsd,
-		InvestmentDate: createInvestment.InvestmentDate,
-		FundID: createInvestment.FundID,
-	}
-
-	return investment, pgdb.db.Create(&investment).Error
+func (pgdb *PGDB) WithTx(ctx context.Context, fn func(Db) error) error {
+	return pgdb.withTx(ctx, func(g gormDb) Db { return &PGDB{g} }, fn)
 }
-
-func (pgdb *PGDB) ReadInvestments(fundID uuid.UUID) ([]models.Investment, error) {
-	investments := make([]models.Investment, 0, 32)
-	return investments, pgdb.db.Where("fund_id = ?", fundID).Find(&investments).Error
-	
-}
\ No newline at end of file
@@ -0,0 +1,82 @@
+// Package dberr defines the storage errors shared by every database.Db
+// implementation, so that handlers can map them to HTTP statuses without
+// knowing which backend produced them.
+package dberr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kind classifies an Error. Kinds are themselves errors so that callers can
+// write errors.Is(err, dberr.NotFound).
+type Kind string
+
+const (
+	NotFound            Kind = "not found"
+	Conflict            Kind = "conflict"
+	ForeignKeyViolation Kind = "foreign key violation"
+	CheckViolation      Kind = "check violation"
+	// VersionMismatch means an update named a version other than the
+	// current one, i.e. the row changed since the caller read it.
+	VersionMismatch Kind = "version mismatch"
+	// InUse means a row cannot be deleted while live rows reference it.
+	// Only investments reference other rows, so that is what the message
+	// names.
+	InUse Kind = "in use"
+)
+
+func (k Kind) Error() string { return string(k) }
+
+// Error describes a failed operation on Entity ("fund", "investor" or
+// "investment"). Field names the offending column when it is known.
+type Error struct {
+	Kind   Kind
+	Entity string
+	Field  string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	switch e.Kind {
+	case NotFound:
+		return e.Entity + " not found"
+	case Conflict:
+		if e.Field != "" {
+			return fmt.Sprintf("%s with this %s already exists", e.Entity, e.Field)
+		}
+		return e.Entity + " already exists"
+	case VersionMismatch:
+		return e.Entity + " has been modified since it was read"
+	case InUse:
+		return e.Entity + " has live investments"
+	case ForeignKeyViolation:
+		if e.Field != "" {
+			return fmt.Sprintf("%s does not reference an existing %s", e.Field, strings.TrimSuffix(e.Field, "_id"))
+		}
+		return e.Entity + " references a row that does not exist"
+	default:
+		if e.Field != "" {
+			return fmt.Sprintf("invalid %s for %s", e.Field, e.Entity)
+		}
+		if e.Err != nil {
+			return fmt.Sprintf("invalid %s: %v", e.Entity, e.Err)
+		}
+		return "invalid " + e.Entity
+	}
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(Kind)
+	return ok && kind == e.Kind
+}
+
+// As returns the *Error in err's chain, if any.
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
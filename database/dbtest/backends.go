@@ -0,0 +1,92 @@
+package dbtest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+)
+
+// postgresLockID serialises tests that share the TEST_DATABASE_URL database,
+// as go test runs packages in parallel and each test truncates every table.
+const postgresLockID = 7_391_604_219
+
+// Backend constructs an empty database for a single test.
+type Backend struct {
+	Name string
+	New  func(t *testing.T) database.Db
+}
+
+// Backends lists every Db implementation: the mock, in-memory SQLite, and
+// PostgreSQL. The PostgreSQL backend skips the test unless TEST_DATABASE_URL
+// is set; that database is migrated and truncated, so it must not hold
+// anything worth keeping.
+func Backends() []Backend {
+	return []Backend{
+		{Name: "mock", New: func(*testing.T) database.Db { return database.NewMockDb() }},
+		{Name: "sqlite", New: newSQLite},
+		{Name: "postgres", New: newPostgres},
+	}
+}
+
+// EachBackend runs test once per backend, each time against an empty
+// database.
+func EachBackend(t *testing.T, test func(t *testing.T, db database.Db)) {
+	for _, backend := range Backends() {
+		t.Run(backend.Name, func(t *testing.T) {
+			test(t, backend.New(t))
+		})
+	}
+}
+
+func newSQLite(t *testing.T) database.Db {
+	db, err := database.NewMemorySQLiteDB()
+	if err != nil {
+		t.Fatalf("failed to open SQLite: %v", err)
+	}
+	return db
+}
+
+func newPostgres(t *testing.T) database.Db {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	ctx := context.Background()
+	db, err := database.OpenPostgres(dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to PostgreSQL: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Hold a session-level lock on a dedicated connection until the test
+	// finishes.
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, postgresLockID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, postgresLockID)
+		_ = conn.Close()
+		_ = sqlDB.Close()
+	})
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("failed to migrate PostgreSQL: %v", err)
+	}
+	if err := db.Exec(`TRUNCATE rate_limits, api_keys, idempotency_keys, webhook_deliveries, webhooks, outbox, events, audit_log, investments_history, investors_history, funds_history, investments, investors, funds CASCADE`).Error; err != nil {
+		t.Fatal(err)
+	}
+	return database.NewPGDB(db)
+}
@@ -0,0 +1,1515 @@
+// Package dbtest is a conformance suite for database.Db implementations, so
+// that the mock the handler tests run against behaves like the real backends.
+package dbtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/iuhmirza/titanbay-take-home/database/audit"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// Run checks that the Db returned by newDb behaves as every implementation
+// must. newDb is called once per case and must return an empty database.
+func Run(t *testing.T, newDb func(t *testing.T) database.Db) {
+	cases := []struct {
+		name string
+		test func(t *testing.T, db database.Db)
+	}{
+		{"FundRoundTrip", testFundRoundTrip},
+		{"UpdateFund", testUpdateFund},
+		{"StaleVersion", testStaleVersion},
+		{"InvestorRoundTrip", testInvestorRoundTrip},
+		{"UpdateInvestor", testUpdateInvestor},
+		{"InvestmentRoundTrip", testInvestmentRoundTrip},
+		{"UniqueEmail", testUniqueEmail},
+		{"ForeignKeys", testForeignKeys},
+		{"CheckConstraints", testCheckConstraints},
+		{"NotFound", testNotFound},
+		{"SoftDelete", testSoftDelete},
+		{"DeleteInUse", testDeleteInUse},
+		{"RestoreInvestor", testRestoreInvestor},
+		{"RestoreInvestment", testRestoreInvestment},
+		{"AuditLog", testAuditLog},
+		{"AuditLogRollback", testAuditLogRollback},
+		{"AsOf", testAsOf},
+		{"RebuildProjections", testRebuildProjections},
+		{"Webhooks", testWebhooks},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"APIKeys", testAPIKeys},
+		{"AccessScope", testAccessScope},
+		{"Tenants", testTenants},
+		{"FundStats", testFundStats},
+		{"FundCommitments", testFundCommitments},
+		{"Pagination", testPagination},
+		{"Search", testSearch},
+		{"Transactions", testTransactions},
+		{"CancelledContext", testCancelledContext},
+		{"ConcurrentWriters", testConcurrentWriters},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.test(t, newDb(t))
+		})
+	}
+}
+
+// RunAll runs the suite against every backend in Backends.
+func RunAll(t *testing.T) {
+	for _, backend := range Backends() {
+		t.Run(backend.Name, func(t *testing.T) {
+			Run(t, backend.New)
+		})
+	}
+}
+
+func validFund(name string) models.CreateFund {
+	return models.CreateFund{
+		Name:          name,
+		VintageYear:   2020,
+		TargetSizeUsd: decimal.RequireFromString("250000000.50"),
+		Status:        "Fundraising",
+	}
+}
+
+func validInvestor(email string) models.CreateInvestor {
+	return models.CreateInvestor{Name: "Alex Rivera", InvestorType: "Individual", Email: email}
+}
+
+func mustCreateFund(t *testing.T, db database.Db, cf models.CreateFund) models.Fund {
+	t.Helper()
+	fund, err := db.CreateFund(context.Background(), cf)
+	if err != nil {
+		t.Fatalf("CreateFund: %v", err)
+	}
+	return fund
+}
+
+func mustCreateInvestor(t *testing.T, db database.Db, ci models.CreateInvestor) models.Investor {
+	t.Helper()
+	investor, err := db.CreateInvestor(context.Background(), ci)
+	if err != nil {
+		t.Fatalf("CreateInvestor: %v", err)
+	}
+	return investor
+}
+
+// assertDbErr checks err's kind, entity and, if field is not empty, field.
+func assertDbErr(t *testing.T, err error, kind dberr.Kind, entity, field string) {
+	t.Helper()
+	if !assert.ErrorIs(t, err, kind) {
+		return
+	}
+	e, _ := dberr.As(err)
+	assert.Equal(t, entity, e.Entity)
+	if field != "" {
+		assert.Equal(t, field, e.Field)
+	}
+}
+
+// Timestamps lose precision in some backends (PostgreSQL keeps microseconds).
+const timestampPrecision = time.Millisecond
+
+func testFundRoundTrip(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	created := mustCreateFund(t, db, validFund("Fund I"))
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.WithinDuration(t, time.Now(), created.CreatedAt, 5*time.Second)
+
+	got, err := db.ReadFundByID(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, created.ID, got.ID)
+	assert.Equal(t, "Fund I", got.Name)
+	assert.Equal(t, 2020, got.VintageYear)
+	assert.True(t, created.TargetSizeUsd.Equal(got.TargetSizeUsd), "target_size_usd %s != %s", created.TargetSizeUsd, got.TargetSizeUsd)
+	assert.Equal(t, "Fundraising", got.Status)
+	assert.WithinDuration(t, created.CreatedAt, got.CreatedAt, timestampPrecision)
+
+	page, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, created.ID, page.Items[0].ID)
+	}
+	assert.Empty(t, page.NextCursor)
+}
+
+func testUpdateFund(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	created := mustCreateFund(t, db, validFund("Fund I"))
+
+	updated, err := db.UpdateFund(ctx, models.Fund{
+		ID:            created.ID,
+		Name:          "Fund I (renamed)",
+		VintageYear:   2021,
+		TargetSizeUsd: decimal.NewFromInt(300_000_000),
+		Status:        "Investing",
+	})
+	assert.NoError(t, err)
+	assert.WithinDuration(t, created.CreatedAt, updated.CreatedAt, timestampPrecision, "a zero created_at is preserved")
+	assert.Equal(t, 1, created.Version)
+	assert.Equal(t, 2, updated.Version)
+
+	got, err := db.ReadFundByID(ctx, created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fund I (renamed)", got.Name)
+	assert.Equal(t, 2021, got.VintageYear)
+	assert.Equal(t, "Investing", got.Status)
+	assert.WithinDuration(t, created.CreatedAt, got.CreatedAt, timestampPrecision)
+	assert.Equal(t, 2, got.Version)
+}
+
+func testStaleVersion(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+
+	fund.Name = "First writer"
+	first, err := db.UpdateFund(ctx, fund)
+	assert.NoError(t, err)
+
+	fund.Name = "Second writer"
+	_, err = db.UpdateFund(ctx, fund)
+	assertDbErr(t, err, dberr.VersionMismatch, "fund", "")
+
+	got, err := db.ReadFundByID(ctx, fund.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "First writer", got.Name)
+	assert.Equal(t, first.Version, got.Version)
+
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	investor.Version = 7
+	_, err = db.UpdateInvestor(ctx, investor)
+	assertDbErr(t, err, dberr.VersionMismatch, "investor", "")
+}
+
+func testInvestorRoundTrip(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	created := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	assert.NotEqual(t, uuid.Nil, created.ID)
+
+	page, err := db.ReadInvestors(ctx, models.InvestorQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		got := page.Items[0]
+		assert.Equal(t, created.ID, got.ID)
+		assert.Equal(t, "Alex Rivera", got.Name)
+		assert.Equal(t, "Individual", got.InvestorType)
+		assert.Equal(t, "alex@example.com", got.Email)
+		assert.WithinDuration(t, created.CreatedAt, got.CreatedAt, timestampPrecision)
+	}
+}
+
+func testUpdateInvestor(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	alex := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	sam := mustCreateInvestor(t, db, validInvestor("sam@example.com"))
+
+	alex.Email = "alex@rivera.net"
+	alex.InvestorType = "Family Office"
+	updated, err := db.UpdateInvestor(ctx, alex)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, updated.Version)
+
+	got, err := db.ReadInvestorByID(ctx, alex.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "alex@rivera.net", got.Email)
+	assert.Equal(t, "Family Office", got.InvestorType)
+	assert.WithinDuration(t, alex.CreatedAt, got.CreatedAt, timestampPrecision)
+
+	// The old address is free again; the new one is taken.
+	_, err = db.CreateInvestor(ctx, validInvestor("alex@example.com"))
+	assert.NoError(t, err)
+	sam.Email = "alex@rivera.net"
+	sam.Version = 0
+	_, err = db.UpdateInvestor(ctx, sam)
+	assertDbErr(t, err, dberr.Conflict, "investor", "email")
+
+	_, err = db.ReadInvestorByID(ctx, uuid.New())
+	assertDbErr(t, err, dberr.NotFound, "investor", "")
+	_, err = db.UpdateInvestor(ctx, models.Investor{ID: uuid.New(), Name: "Ghost", InvestorType: "Individual", Email: "ghost@example.com"})
+	assertDbErr(t, err, dberr.NotFound, "investor", "")
+}
+
+func testInvestmentRoundTrip(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+
+	created, err := db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID:     investor.ID,
+		FundID:         fund.ID,
+		AmountUsd:      decimal.RequireFromString("1234567.89"),
+		InvestmentDate: "2024-03-15",
+	})
+	assert.NoError(t, err)
+
+	page, err := db.ReadInvestments(ctx, fund.ID, models.InvestmentQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		got := page.Items[0]
+		assert.Equal(t, created.ID, got.ID)
+		assert.Equal(t, investor.ID, got.InvestorID)
+		assert.Equal(t, fund.ID, got.FundID)
+		assert.Equal(t, "1234567.89", got.AmountUsd.StringFixed(2))
+		assert.Equal(t, "2024-03-15", got.InvestmentDate)
+	}
+
+	other, err := db.ReadInvestments(ctx, uuid.New(), models.InvestmentQuery{})
+	assert.NoError(t, err, "an unknown fund has no investments")
+	assert.Empty(t, other.Items)
+}
+
+func testUniqueEmail(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+
+	_, err := db.CreateInvestor(ctx, validInvestor("alex@example.com"))
+	assertDbErr(t, err, dberr.Conflict, "investor", "email")
+
+	_, err = db.CreateInvestor(ctx, validInvestor("sam@example.com"))
+	assert.NoError(t, err)
+}
+
+func testForeignKeys(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	investment := models.CreateInvestment{
+		InvestorID:     investor.ID,
+		FundID:         fund.ID,
+		AmountUsd:      decimal.NewFromInt(100),
+		InvestmentDate: "2024-01-01",
+	}
+
+	unknownInvestor := investment
+	unknownInvestor.InvestorID = uuid.New()
+	_, err := db.CreateInvestment(ctx, unknownInvestor)
+	assertDbErr(t, err, dberr.ForeignKeyViolation, "investment", "investor_id")
+
+	unknownFund := investment
+	unknownFund.FundID = uuid.New()
+	_, err = db.CreateInvestment(ctx, unknownFund)
+	assertDbErr(t, err, dberr.ForeignKeyViolation, "investment", "fund_id")
+
+	page, err := db.ReadInvestments(ctx, fund.ID, models.InvestmentQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items, "rejected investments are not stored")
+}
+
+// testCheckConstraints bypasses request validation, which belongs to the
+// handlers, to reach the constraints every backend must enforce itself.
+func testCheckConstraints(t *testing.T, db database.Db) {
+	ctx := context.Background()
+
+	badStatus := validFund("Fund I")
+	badStatus.Status = "Open"
+	_, err := db.CreateFund(ctx, badStatus)
+	assertDbErr(t, err, dberr.CheckViolation, "fund", "status")
+
+	badYear := validFund("Fund I")
+	badYear.VintageYear = 1850
+	_, err = db.CreateFund(ctx, badYear)
+	assertDbErr(t, err, dberr.CheckViolation, "fund", "vintage_year")
+
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	fund.Status = "Open"
+	_, err = db.UpdateFund(ctx, fund)
+	assertDbErr(t, err, dberr.CheckViolation, "fund", "status")
+	got, err := db.ReadFundByID(ctx, fund.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fundraising", got.Status, "a rejected update is not applied")
+
+	badType := validInvestor("alex@example.com")
+	badType.InvestorType = "Robot"
+	_, err = db.CreateInvestor(ctx, badType)
+	assertDbErr(t, err, dberr.CheckViolation, "investor", "investor_type")
+
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	_, err = db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID: investor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(-1), InvestmentDate: "2024-01-01",
+	})
+	assertDbErr(t, err, dberr.CheckViolation, "investment", "amount_usd")
+
+	_, err = db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID: investor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(1), InvestmentDate: "2024-02-30",
+	})
+	assertDbErr(t, err, dberr.CheckViolation, "investment", "")
+}
+
+func testNotFound(t *testing.T, db database.Db) {
+	ctx := context.Background()
+
+	_, err := db.ReadFundByID(ctx, uuid.New())
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+
+	_, err = db.UpdateFund(ctx, models.Fund{
+		ID:            uuid.New(),
+		Name:          "Ghost",
+		VintageYear:   2020,
+		TargetSizeUsd: decimal.NewFromInt(1_000_000),
+		Status:        "Closed",
+	})
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+
+	page, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items, "updating a missing fund does not create it")
+}
+
+var deletion = models.DeleteResource{Reason: "created in error"}
+
+func testSoftDelete(t *testing.T, db database.Db) {
+	ctx := audit.NewContext(context.Background(), audit.Source{Actor: "ops@example.com"})
+	kept := mustCreateFund(t, db, validFund("Growth I"))
+	fund := mustCreateFund(t, db, validFund("Growth II"))
+
+	deleted, err := db.DeleteFund(ctx, fund.ID, deletion)
+	assert.NoError(t, err)
+	if assert.True(t, deleted.Deleted()) {
+		assert.WithinDuration(t, time.Now(), *deleted.DeletedAt, 5*time.Second)
+	}
+	assert.Equal(t, "ops@example.com", deleted.DeletedBy, "the deleter is the audit actor")
+	assert.Equal(t, "created in error", deleted.DeleteReason)
+	assert.Equal(t, 2, deleted.Version)
+
+	got, err := db.ReadFundByID(ctx, fund.ID)
+	assert.NoError(t, err, "deleted funds can still be read by id")
+	if assert.True(t, got.Deleted()) {
+		assert.WithinDuration(t, *deleted.DeletedAt, *got.DeletedAt, timestampPrecision)
+	}
+	assert.Equal(t, "created in error", got.DeleteReason)
+
+	page, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, kept.ID, page.Items[0].ID)
+	}
+	page, err = db.ReadFunds(ctx, models.FundQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+
+	results, err := db.Search(ctx, models.SearchQuery{Q: "growth"})
+	assert.NoError(t, err)
+	if assert.Len(t, results.Items, 1) {
+		assert.Equal(t, kept.ID, results.Items[0].Fund.ID)
+	}
+
+	_, err = db.DeleteFund(ctx, fund.ID, deletion)
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+	fund.Name = "Growth II (renamed)"
+	fund.Version = 0
+	_, err = db.UpdateFund(ctx, fund)
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	_, err = db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID: investor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(100), InvestmentDate: "2024-01-01",
+	})
+	assertDbErr(t, err, dberr.ForeignKeyViolation, "investment", "fund_id")
+
+	restored, err := db.RestoreFund(ctx, fund.ID)
+	assert.NoError(t, err)
+	assert.False(t, restored.Deleted())
+	assert.Empty(t, restored.DeletedBy)
+	assert.Empty(t, restored.DeleteReason)
+	assert.Equal(t, 3, restored.Version)
+	assert.Equal(t, "Growth II", restored.Name)
+
+	again, err := db.RestoreFund(ctx, fund.ID)
+	assert.NoError(t, err, "restoring a live fund is a no-op")
+	assert.Equal(t, 3, again.Version)
+
+	_, err = db.DeleteFund(ctx, uuid.New(), deletion)
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+	_, err = db.RestoreInvestor(ctx, uuid.New())
+	assertDbErr(t, err, dberr.NotFound, "investor", "")
+}
+
+func testDeleteInUse(t *testing.T, db database.Db) {
+	ctx := audit.NewContext(context.Background(), audit.Source{Actor: "ops@example.com"})
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	investment, err := db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID: investor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(100), InvestmentDate: "2024-01-01",
+	})
+	assert.NoError(t, err)
+
+	_, err = db.DeleteFund(ctx, fund.ID, deletion)
+	assertDbErr(t, err, dberr.InUse, "fund", "")
+	_, err = db.DeleteInvestor(ctx, investor.ID, deletion)
+	assertDbErr(t, err, dberr.InUse, "investor", "")
+
+	_, err = db.DeleteInvestment(ctx, uuid.New(), investment.ID, deletion)
+	assertDbErr(t, err, dberr.NotFound, "investment", "")
+	deleted, err := db.DeleteInvestment(ctx, fund.ID, investment.ID, deletion)
+	assert.NoError(t, err)
+	assert.True(t, deleted.Deleted())
+
+	page, err := db.ReadInvestments(ctx, fund.ID, models.InvestmentQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+	page, err = db.ReadInvestments(ctx, fund.ID, models.InvestmentQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, "ops@example.com", page.Items[0].DeletedBy)
+	}
+
+	_, err = db.DeleteFund(ctx, fund.ID, deletion)
+	assert.NoError(t, err, "deleted investments do not hold on to their fund")
+	_, err = db.DeleteInvestor(ctx, investor.ID, deletion)
+	assert.NoError(t, err)
+}
+
+func testRestoreInvestor(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	alex := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	_, err := db.DeleteInvestor(ctx, alex.ID, deletion)
+	assert.NoError(t, err)
+
+	page, err := db.ReadInvestors(ctx, models.InvestorQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	// A deleted investor's email can be registered again, after which the
+	// investor cannot be restored.
+	mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	_, err = db.RestoreInvestor(ctx, alex.ID)
+	assertDbErr(t, err, dberr.Conflict, "investor", "email")
+
+	got, err := db.ReadInvestorByID(ctx, alex.ID)
+	assert.NoError(t, err)
+	assert.True(t, got.Deleted(), "a failed restore leaves the investor deleted")
+
+	page, err = db.ReadInvestors(ctx, models.InvestorQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+}
+
+func testRestoreInvestment(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	investment, err := db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID: investor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(100), InvestmentDate: "2024-01-01",
+	})
+	assert.NoError(t, err)
+	_, err = db.DeleteInvestment(ctx, fund.ID, investment.ID, deletion)
+	assert.NoError(t, err)
+	_, err = db.DeleteInvestor(ctx, investor.ID, deletion)
+	assert.NoError(t, err)
+
+	_, err = db.RestoreInvestment(ctx, fund.ID, investment.ID)
+	assertDbErr(t, err, dberr.ForeignKeyViolation, "investment", "investor_id")
+
+	_, err = db.RestoreInvestor(ctx, investor.ID)
+	assert.NoError(t, err)
+	restored, err := db.RestoreInvestment(ctx, fund.ID, investment.ID)
+	assert.NoError(t, err)
+	assert.False(t, restored.Deleted())
+	assert.Equal(t, "2024-01-01", restored.InvestmentDate)
+
+	page, err := db.ReadInvestments(ctx, fund.ID, models.InvestmentQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+}
+
+func auditTrail(t *testing.T, db database.Db, entity string, id uuid.UUID) []models.AuditEntry {
+	t.Helper()
+	page, err := db.ReadAuditLog(context.Background(), models.AuditQuery{Entity: entity, EntityID: &id})
+	assert.NoError(t, err)
+	return page.Items
+}
+
+func testAuditLog(t *testing.T, db database.Db) {
+	ctx := audit.NewContext(context.Background(), audit.Source{Actor: "alice@example.com", RequestID: "req-1"})
+	fund, err := db.CreateFund(ctx, validFund("Fund I"))
+	assert.NoError(t, err)
+	fund.Name = "Fund I (renamed)"
+	_, err = db.UpdateFund(ctx, fund)
+	assert.NoError(t, err)
+	_, err = db.DeleteFund(ctx, fund.ID, deletion)
+	assert.NoError(t, err)
+	_, err = db.RestoreFund(ctx, fund.ID)
+	assert.NoError(t, err)
+	_, err = db.RestoreFund(ctx, fund.ID)
+	assert.NoError(t, err)
+
+	trail := auditTrail(t, db, "fund", fund.ID)
+	var actions []string
+	for _, entry := range trail {
+		actions = append(actions, entry.Action)
+		assert.Equal(t, "fund", entry.Entity)
+		assert.Equal(t, fund.ID, entry.EntityID)
+		assert.Equal(t, "alice@example.com", entry.Actor)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.WithinDuration(t, time.Now(), entry.CreatedAt, 5*time.Second)
+	}
+	assert.Equal(t, []string{"create", "update", "delete", "restore"}, actions, "a no-op restore is not recorded")
+	if len(trail) != 4 {
+		return
+	}
+
+	created := trail[0].Changes
+	assert.Nil(t, created["name"].Before)
+	assert.JSONEq(t, `"Fund I"`, string(created["name"].After))
+	assert.JSONEq(t, `1`, string(created["version"].After))
+
+	assert.Equal(t, map[string]models.FieldChange{
+		"name":    {Before: []byte(`"Fund I"`), After: []byte(`"Fund I (renamed)"`)},
+		"version": {Before: []byte(`1`), After: []byte(`2`)},
+	}, trail[1].Changes, "only changed fields are recorded")
+
+	deleted := trail[2].Changes
+	assert.Nil(t, deleted["deleted_at"].Before)
+	assert.NotNil(t, deleted["deleted_at"].After)
+	assert.JSONEq(t, `"created in error"`, string(deleted["delete_reason"].After))
+	assert.JSONEq(t, `"alice@example.com"`, string(trail[3].Changes["deleted_by"].Before))
+	assert.Nil(t, trail[3].Changes["deleted_by"].After)
+
+	// Changes made outside a request are attributed to the system.
+	investor, err := db.CreateInvestor(context.Background(), validInvestor("alex@example.com"))
+	assert.NoError(t, err)
+	investment, err := db.CreateInvestment(context.Background(), models.CreateInvestment{
+		InvestorID: investor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(100), InvestmentDate: "2024-01-01",
+	})
+	assert.NoError(t, err)
+	if trail := auditTrail(t, db, "investment", investment.ID); assert.Len(t, trail, 1) {
+		assert.Equal(t, "system", trail[0].Actor)
+		assert.Empty(t, trail[0].RequestID)
+		assert.JSONEq(t, `"2024-01-01"`, string(trail[0].Changes["investment_date"].After))
+	}
+
+	query := models.AuditQuery{PageQuery: models.PageQuery{Limit: 2, Sort: "-created_at"}}
+	var ids []int
+	for pages := 0; pages < 5; pages++ {
+		page, err := db.ReadAuditLog(ctx, query)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, entry := range page.Items {
+			ids = append(ids, entry.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	if assert.Len(t, ids, 6) {
+		assert.True(t, slices.IsSortedFunc(ids, func(a, b int) int { return b - a }), "newest first: %v", ids)
+	}
+
+	_, err = db.ReadAuditLog(ctx, models.AuditQuery{Entity: "unicorn"})
+	assert.Error(t, err)
+}
+
+func testAuditLogRollback(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+
+	stale := fund
+	stale.Version = 7
+	_, err := db.UpdateFund(ctx, stale)
+	assertDbErr(t, err, dberr.VersionMismatch, "fund", "")
+
+	errRollback := errors.New("roll back")
+	err = db.WithTx(ctx, func(tx database.Db) error {
+		fund.Name = "Rolled back"
+		if _, err := tx.UpdateFund(ctx, fund); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	trail := auditTrail(t, db, "fund", fund.ID)
+	if assert.Len(t, trail, 1, "failed and rolled back changes are not recorded") {
+		assert.Equal(t, "create", trail[0].Action)
+	}
+}
+
+// between returns an instant strictly between the writes made before and
+// after calling it, whatever the precision of the backend's clock.
+func between() time.Time {
+	time.Sleep(5 * time.Millisecond)
+	t := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	return t
+}
+
+func testAsOf(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	beforeCreate := between()
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	created := between()
+
+	fund.Name = "Fund I (renamed)"
+	fund, err := db.UpdateFund(ctx, fund)
+	assert.NoError(t, err)
+	investment, err := db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID:     investor.ID,
+		FundID:         fund.ID,
+		AmountUsd:      decimal.NewFromInt(1000),
+		InvestmentDate: "2024-03-15",
+	})
+	assert.NoError(t, err)
+	invested := between()
+
+	_, err = db.DeleteInvestment(ctx, fund.ID, investment.ID, deletion)
+	assert.NoError(t, err)
+	_, err = db.DeleteFund(ctx, fund.ID, deletion)
+	assert.NoError(t, err)
+	deleted := between()
+
+	_, err = db.AsOf(beforeCreate).ReadFundByID(ctx, fund.ID)
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+	page, err := db.AsOf(beforeCreate).ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	got, err := db.AsOf(created).ReadFundByID(ctx, fund.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fund I", got.Name)
+	assert.Equal(t, 1, got.Version)
+	investments, err := db.AsOf(created).ReadInvestments(ctx, fund.ID, models.InvestmentQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, investments.Items, "the investment did not exist yet")
+	results, err := db.AsOf(created).Search(ctx, models.SearchQuery{Q: "renamed"})
+	assert.NoError(t, err)
+	assert.Empty(t, results.Items)
+
+	got, err = db.AsOf(invested).ReadFundByID(ctx, fund.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Fund I (renamed)", got.Name)
+	assert.False(t, got.Deleted())
+	investments, err = db.AsOf(invested).ReadInvestments(ctx, fund.ID, models.InvestmentQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, investments.Items, 1) {
+		assert.Equal(t, investment.ID, investments.Items[0].ID)
+		assert.Equal(t, "2024-03-15", investments.Items[0].InvestmentDate)
+	}
+	results, err = db.AsOf(invested).Search(ctx, models.SearchQuery{Q: "renamed"})
+	assert.NoError(t, err)
+	assert.Len(t, results.Items, 1)
+
+	page, err = db.AsOf(deleted).ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items, "deleted rows are hidden as they are now")
+	page, err = db.AsOf(deleted).ReadFunds(ctx, models.FundQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.True(t, page.Items[0].Deleted())
+		assert.Equal(t, fund.Version+1, page.Items[0].Version)
+	}
+	investors, err := db.AsOf(deleted).ReadInvestors(ctx, models.InvestorQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, investors.Items, 1)
+}
+
+// snapshot reads everything a projection rebuild must reproduce.
+type snapshot struct {
+	Funds       []models.Fund
+	Investors   []models.Investor
+	Investments []models.Investment
+	Past        []models.Fund
+}
+
+func takeSnapshot(t *testing.T, db database.Db, fundID uuid.UUID, past time.Time) snapshot {
+	ctx := context.Background()
+	funds, err := db.ReadFunds(ctx, models.FundQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	investors, err := db.ReadInvestors(ctx, models.InvestorQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	investments, err := db.ReadInvestments(ctx, fundID, models.InvestmentQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	pastFunds, err := db.AsOf(past).ReadFunds(ctx, models.FundQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	return snapshot{funds.Items, investors.Items, investments.Items, pastFunds.Items}
+}
+
+func testRebuildProjections(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	other := mustCreateFund(t, db, validFund("Fund II"))
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	past := between()
+
+	fund.Status = "Closed"
+	fund, err := db.UpdateFund(ctx, fund)
+	assert.NoError(t, err)
+	investment, err := db.CreateInvestment(ctx, models.CreateInvestment{
+		InvestorID:     investor.ID,
+		FundID:         fund.ID,
+		AmountUsd:      decimal.RequireFromString("1234567.89"),
+		InvestmentDate: "2024-03-15",
+	})
+	assert.NoError(t, err)
+	_, err = db.DeleteInvestment(ctx, fund.ID, investment.ID, deletion)
+	assert.NoError(t, err)
+	_, err = db.DeleteFund(ctx, other.ID, deletion)
+	assert.NoError(t, err)
+	_, err = db.DeleteInvestor(ctx, investor.ID, deletion)
+	assert.NoError(t, err)
+	_, err = db.RestoreInvestor(ctx, investor.ID)
+	assert.NoError(t, err)
+	errRollback := errors.New("roll back")
+	err = db.WithTx(ctx, func(tx database.Db) error {
+		if _, err := tx.CreateFund(ctx, validFund("Fund III")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	before := takeSnapshot(t, db, fund.ID, past)
+	n, err := db.RebuildProjections(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, n, "rolled back writes leave no events")
+	assert.Equal(t, before, takeSnapshot(t, db, fund.ID, past))
+
+	_, err = db.CreateInvestor(ctx, validInvestor("alex@example.com"))
+	assertDbErr(t, err, dberr.Conflict, "investor", "email")
+	fund, err = db.UpdateFund(ctx, fund)
+	assert.NoError(t, err, "rebuilt rows can be written to")
+	assert.Equal(t, 3, fund.Version)
+}
+
+func testWebhooks(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	webhook, err := db.CreateWebhook(ctx, models.CreateWebhook{URL: "https://example.com/hook", Secret: "0123456789abcdef"})
+	assert.NoError(t, err)
+	assert.True(t, webhook.Enabled)
+	assert.Equal(t, []string{}, webhook.EventTypes)
+	assert.Equal(t, 1, webhook.Version)
+
+	read, err := db.ReadWebhookByID(ctx, webhook.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef", read.Secret)
+	assert.WithinDuration(t, webhook.CreatedAt, read.CreatedAt, timestampPrecision)
+
+	update := webhook
+	update.URL = "https://example.com/other"
+	update.EventTypes = []string{"FundCreated"}
+	update.Enabled = false
+	updated, err := db.UpdateWebhook(ctx, update)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"FundCreated"}, updated.EventTypes)
+	assert.False(t, updated.Enabled)
+	assert.Equal(t, 2, updated.Version)
+	_, err = db.UpdateWebhook(ctx, update)
+	assertDbErr(t, err, dberr.VersionMismatch, "webhook", "")
+
+	enabled := false
+	page, err := db.ReadWebhooks(ctx, models.WebhookQuery{Enabled: &enabled})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	enabled = true
+	page, err = db.ReadWebhooks(ctx, models.WebhookQuery{Enabled: &enabled})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	assert.NoError(t, db.DeleteWebhook(ctx, webhook.ID))
+	_, err = db.ReadWebhookByID(ctx, webhook.ID)
+	assertDbErr(t, err, dberr.NotFound, "webhook", "")
+	assertDbErr(t, db.DeleteWebhook(ctx, webhook.ID), dberr.NotFound, "webhook", "")
+}
+
+func testWebhookDeliveries(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	all, err := db.CreateWebhook(ctx, models.CreateWebhook{URL: "https://example.com/all", Secret: "0123456789abcdef"})
+	assert.NoError(t, err)
+	created, err := db.CreateWebhook(ctx, models.CreateWebhook{URL: "https://example.com/created", EventTypes: []string{"FundCreated"}, Secret: "0123456789abcdef"})
+	assert.NoError(t, err)
+	disabled, err := db.CreateWebhook(ctx, models.CreateWebhook{URL: "https://example.com/disabled", Secret: "0123456789abcdef"})
+	assert.NoError(t, err)
+	disabled.Enabled = false
+	_, err = db.UpdateWebhook(ctx, disabled)
+	assert.NoError(t, err)
+
+	fund := mustCreateFund(t, db, validFund("Fund I"))
+	fund.Status = "Closed"
+	_, err = db.UpdateFund(ctx, fund)
+	assert.NoError(t, err)
+	errRollback := errors.New("roll back")
+	err = db.WithTx(ctx, func(tx database.Db) error {
+		if _, err := tx.CreateFund(ctx, validFund("Fund II")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	deliveries := func(webhookID uuid.UUID) []models.WebhookDelivery {
+		t.Helper()
+		page, err := db.ReadWebhookDeliveries(ctx, webhookID, models.WebhookDeliveryQuery{})
+		assert.NoError(t, err)
+		return page.Items
+	}
+	toAll := deliveries(all.ID)
+	if assert.Len(t, toAll, 2, "rolled back writes are not delivered") {
+		assert.Equal(t, "FundCreated", toAll[0].EventType)
+		assert.Equal(t, "FundUpdated", toAll[1].EventType)
+		assert.Equal(t, models.DeliveryPending, toAll[0].Status)
+		var ev struct {
+			Type     string    `json:"type"`
+			StreamID uuid.UUID `json:"stream_id"`
+		}
+		assert.NoError(t, json.Unmarshal(toAll[0].Payload, &ev))
+		assert.Equal(t, "FundCreated", ev.Type)
+		assert.Equal(t, fund.ID, ev.StreamID)
+	}
+	assert.Len(t, deliveries(created.ID), 1, "only subscribed event types are delivered")
+	assert.Empty(t, deliveries(disabled.ID), "disabled webhooks are not delivered to")
+
+	due, err := db.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	if !assert.Len(t, due, 3) {
+		return
+	}
+	for _, d := range due {
+		assert.NotEqual(t, disabled.URL, d.URL)
+		assert.Equal(t, "0123456789abcdef", d.Secret)
+	}
+	again, err := db.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, again, "claimed deliveries are leased")
+
+	retryAt := time.Now().UTC().Add(-time.Second)
+	first := toAll[0].ID
+	assert.NoError(t, db.RecordWebhookAttempt(ctx, models.WebhookAttempt{DeliveryID: first, StatusCode: 500, Error: "boom", RetryAt: &retryAt, DisableAfter: 2}))
+	due, err = db.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1, "failed deliveries are retried at RetryAt") {
+		assert.Equal(t, first, due[0].ID)
+		assert.Equal(t, 1, due[0].Attempts)
+		assert.Equal(t, 500, due[0].LastStatusCode)
+	}
+	assert.NoError(t, db.RecordWebhookAttempt(ctx, models.WebhookAttempt{DeliveryID: first, Error: "connection refused", DisableAfter: 2}))
+
+	toAll = deliveries(all.ID)
+	assert.Equal(t, models.DeliveryFailed, toAll[0].Status)
+	assert.Equal(t, 2, toAll[0].Attempts)
+	assert.Nil(t, toAll[0].NextAttemptAt)
+	all, err = db.ReadWebhookByID(ctx, all.ID)
+	assert.NoError(t, err)
+	assert.False(t, all.Enabled, "webhooks that keep failing are disabled")
+	assert.Equal(t, 2, all.ConsecutiveFailures)
+	assert.NotEmpty(t, all.DisabledReason)
+	assert.Equal(t, 2, all.Version)
+
+	replay, err := db.ReplayWebhookDelivery(ctx, all.ID, first)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryPending, replay.Status)
+	if assert.NotNil(t, replay.ReplayOf) {
+		assert.Equal(t, first, *replay.ReplayOf)
+	}
+	assert.Equal(t, toAll[0].EventSeq, replay.EventSeq)
+	assert.JSONEq(t, string(toAll[0].Payload), string(replay.Payload))
+	_, err = db.ReplayWebhookDelivery(ctx, created.ID, first)
+	assertDbErr(t, err, dberr.NotFound, "delivery", "")
+	due, err = db.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Empty(t, due, "deliveries wait while their webhook is disabled")
+
+	all.Enabled = true
+	all, err = db.UpdateWebhook(ctx, all)
+	assert.NoError(t, err)
+	assert.Zero(t, all.ConsecutiveFailures)
+	assert.Empty(t, all.DisabledReason)
+	due, err = db.ClaimWebhookDeliveries(ctx, 10, time.Minute)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, replay.ID, due[0].ID)
+	}
+	assert.NoError(t, db.RecordWebhookAttempt(ctx, models.WebhookAttempt{DeliveryID: replay.ID, StatusCode: 204}))
+	page, err := db.ReadWebhookDeliveries(ctx, all.ID, models.WebhookDeliveryQuery{Status: models.DeliverySucceeded})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.NotNil(t, page.Items[0].DeliveredAt)
+		assert.Equal(t, 204, page.Items[0].LastStatusCode)
+	}
+
+	assert.NoError(t, db.DeleteWebhook(ctx, all.ID))
+	assert.Empty(t, deliveries(all.ID), "deleting a webhook deletes its deliveries")
+	_, err = db.RebuildProjections(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, deliveries(created.ID), 1, "replaying events does not deliver them again")
+}
+
+func testIdempotencyKeys(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	key := models.IdempotencyKey{TenantID: "default", Subject: "alice", Key: "key-1", RequestHash: "hash-1"}
+	reserved, ok, err := db.ReserveIdempotencyKey(ctx, key, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, reserved.Completed())
+	assert.WithinDuration(t, reserved.CreatedAt.Add(time.Hour), reserved.ExpiresAt, timestampPrecision)
+
+	retry := key
+	retry.RequestHash = "hash-2"
+	stored, ok, err := db.ReserveIdempotencyKey(ctx, retry, time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, ok, "a key can only be reserved once")
+	assert.Equal(t, "hash-1", stored.RequestHash)
+	assert.False(t, stored.Completed())
+
+	reserved.StatusCode = 201
+	reserved.ResponseHeader = map[string][]string{"Content-Type": {"application/json"}}
+	reserved.ResponseBody = []byte(`{"id":1}`)
+	assert.NoError(t, db.CompleteIdempotencyKey(ctx, reserved))
+	assert.NoError(t, db.ReleaseIdempotencyKey(ctx, key), "completed keys are not released")
+	stored, ok, err = db.ReserveIdempotencyKey(ctx, key, time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 201, stored.StatusCode)
+	assert.Equal(t, reserved.ResponseHeader, stored.ResponseHeader)
+	assert.Equal(t, `{"id":1}`, string(stored.ResponseBody))
+
+	for _, other := range []models.IdempotencyKey{
+		{TenantID: "default", Subject: "bob", Key: "key-1", RequestHash: "hash-3"},
+		{TenantID: "acme", Subject: "alice", Key: "key-1", RequestHash: "hash-3"},
+	} {
+		stored, ok, err = db.ReserveIdempotencyKey(ctx, other, time.Hour)
+		assert.NoError(t, err)
+		assert.True(t, ok, "keys are per subject and tenant")
+		assert.Equal(t, "hash-3", stored.RequestHash)
+		assert.NoError(t, db.ReleaseIdempotencyKey(ctx, other))
+	}
+
+	key2 := models.IdempotencyKey{TenantID: "default", Subject: "alice", Key: "key-2", RequestHash: "hash"}
+	_, _, err = db.ReserveIdempotencyKey(ctx, key2, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, db.ReleaseIdempotencyKey(ctx, key2))
+	key2.RequestHash = "other"
+	_, ok, err = db.ReserveIdempotencyKey(ctx, key2, time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, ok, "released keys can be reserved again")
+
+	time.Sleep(5 * time.Millisecond)
+	key2.RequestHash = "third"
+	_, ok, err = db.ReserveIdempotencyKey(ctx, key2, time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, ok, "expired keys can be reserved again")
+	time.Sleep(5 * time.Millisecond)
+	n, err := db.PurgeIdempotencyKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "only expired keys are purged")
+	_, ok, err = db.ReserveIdempotencyKey(ctx, key, time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func testAPIKeys(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	etl, err := db.CreateAPIKey(ctx, models.APIKey{Name: "etl", Prefix: "tbk_etl", Hash: "hash-etl", Scopes: []string{"fund:read"}, CreatedBy: "alice"})
+	assert.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, etl.ID)
+	assert.False(t, etl.CreatedAt.IsZero())
+
+	_, err = db.CreateAPIKey(ctx, models.APIKey{Name: "copy", Prefix: "tbk_etl", Hash: "hash-etl", Scopes: []string{"fund:read"}, CreatedBy: "alice"})
+	assert.ErrorIs(t, err, dberr.Conflict, "hashes are unique")
+
+	read, err := db.ReadAPIKeyByID(ctx, etl.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, etl.Name, read.Name)
+	assert.Equal(t, "hash-etl", read.Hash)
+	assert.Equal(t, []string{"fund:read"}, read.Scopes)
+	assert.Nil(t, read.LastUsedAt)
+
+	used, err := db.UseAPIKey(ctx, "hash-etl")
+	assert.NoError(t, err)
+	assert.Equal(t, etl.ID, used.ID)
+	if assert.NotNil(t, used.LastUsedAt) {
+		read, err = db.ReadAPIKeyByID(ctx, etl.ID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, read.LastUsedAt) {
+			assert.WithinDuration(t, *used.LastUsedAt, *read.LastUsedAt, timestampPrecision)
+		}
+	}
+	_, err = db.UseAPIKey(ctx, "hash-unknown")
+	assert.ErrorIs(t, err, dberr.NotFound)
+
+	rotated, err := db.RotateAPIKey(ctx, etl.ID, "hash-etl-2", "tbk_etl2")
+	assert.NoError(t, err)
+	assert.Equal(t, "tbk_etl2", rotated.Prefix)
+	assert.NotNil(t, rotated.RotatedAt)
+	_, err = db.UseAPIKey(ctx, "hash-etl")
+	assert.ErrorIs(t, err, dberr.NotFound, "the old key stops working")
+	_, err = db.UseAPIKey(ctx, "hash-etl-2")
+	assert.NoError(t, err)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = db.CreateAPIKey(ctx, models.APIKey{Name: "expired", Prefix: "tbk_old", Hash: "hash-expired", Scopes: []string{"fund:read"}, CreatedBy: "alice", ExpiresAt: &past})
+	assert.NoError(t, err)
+	_, err = db.UseAPIKey(ctx, "hash-expired")
+	assert.ErrorIs(t, err, dberr.NotFound, "expired keys do not authenticate")
+
+	revoked, err := db.RevokeAPIKey(ctx, etl.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	_, err = db.UseAPIKey(ctx, "hash-etl-2")
+	assert.ErrorIs(t, err, dberr.NotFound, "revoked keys do not authenticate")
+	_, err = db.RevokeAPIKey(ctx, etl.ID)
+	assert.ErrorIs(t, err, dberr.NotFound)
+	_, err = db.RotateAPIKey(ctx, etl.ID, "hash-etl-3", "tbk_etl3")
+	assert.ErrorIs(t, err, dberr.NotFound)
+	_, err = db.RevokeAPIKey(ctx, uuid.New())
+	assert.ErrorIs(t, err, dberr.NotFound)
+
+	keys, err := db.ReadAPIKeys(ctx, models.APIKeyQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, keys.Items, 1, "revoked keys are omitted")
+	keys, err = db.ReadAPIKeys(ctx, models.APIKeyQuery{IncludeRevoked: true, PageQuery: models.PageQuery{Sort: "name"}})
+	assert.NoError(t, err)
+	if assert.Len(t, keys.Items, 2) {
+		assert.Equal(t, "etl", keys.Items[0].Name)
+		assert.Equal(t, "expired", keys.Items[1].Name)
+	}
+}
+
+func testTenants(t *testing.T, db database.Db) {
+	acme := access.NewContext(context.Background(), access.Scope{Tenant: "acme"})
+	globex := access.NewContext(context.Background(), access.Scope{Tenant: "globex"})
+
+	fund, err := db.CreateFund(acme, validFund("Fund A"))
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", fund.TenantID)
+	investor, err := db.CreateInvestor(acme, validInvestor("alex@example.com"))
+	assert.NoError(t, err)
+	_, err = db.CreateInvestment(acme, models.CreateInvestment{InvestorID: investor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(100), InvestmentDate: "2024-03-15"})
+	assert.NoError(t, err)
+	afterSetup := between()
+
+	for name, ctx := range map[string]context.Context{"other tenant": globex, "default tenant": context.Background()} {
+		funds, err := db.ReadFunds(ctx, models.FundQuery{})
+		assert.NoError(t, err)
+		assert.Empty(t, funds.Items, name)
+		_, err = db.ReadFundByID(ctx, fund.ID)
+		assert.ErrorIs(t, err, dberr.NotFound, name)
+		_, err = db.ReadInvestorByID(ctx, investor.ID)
+		assert.ErrorIs(t, err, dberr.NotFound, name)
+		investments, err := db.ReadInvestments(ctx, fund.ID, models.InvestmentQuery{})
+		assert.NoError(t, err)
+		assert.Empty(t, investments.Items, name)
+		results, err := db.Search(ctx, models.SearchQuery{Q: "Fund A"})
+		assert.NoError(t, err)
+		assert.Empty(t, results.Items, name)
+		funds, err = db.AsOf(afterSetup).ReadFunds(ctx, models.FundQuery{})
+		assert.NoError(t, err)
+		assert.Empty(t, funds.Items, name)
+		entries, err := db.ReadAuditLog(ctx, models.AuditQuery{})
+		assert.NoError(t, err)
+		assert.Empty(t, entries.Items, name)
+
+		_, err = db.UpdateFund(ctx, fund)
+		assert.ErrorIs(t, err, dberr.NotFound, name)
+		_, err = db.DeleteInvestor(ctx, investor.ID, models.DeleteResource{Reason: "test"})
+		assert.ErrorIs(t, err, dberr.NotFound, name)
+		_, err = db.RestoreFund(ctx, fund.ID)
+		assert.ErrorIs(t, err, dberr.NotFound, name)
+	}
+
+	entries, err := db.ReadAuditLog(acme, models.AuditQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, entries.Items, 3)
+	funds, err := db.AsOf(afterSetup).ReadFunds(acme, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, funds.Items, 1)
+
+	_, err = db.CreateInvestor(globex, validInvestor("alex@example.com"))
+	assert.NoError(t, err, "emails are unique per tenant")
+	_, err = db.CreateInvestor(acme, validInvestor("alex@example.com"))
+	assertDbErr(t, err, dberr.Conflict, "investor", "email")
+
+	globexInvestor, err := db.CreateInvestor(globex, validInvestor("sam@example.com"))
+	assert.NoError(t, err)
+	_, err = db.CreateInvestment(globex, models.CreateInvestment{InvestorID: globexInvestor.ID, FundID: fund.ID, AmountUsd: decimal.NewFromInt(100), InvestmentDate: "2024-03-15"})
+	assertDbErr(t, err, dberr.ForeignKeyViolation, "investment", "fund_id")
+
+	update := fund
+	update.Name = "Fund A II"
+	update.TenantID = "globex"
+	updated, err := db.UpdateFund(acme, update)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", updated.TenantID, "rows cannot move between tenants")
+
+	webhook, err := db.CreateWebhook(globex, models.CreateWebhook{URL: "https://globex.example.com/hook"})
+	assert.NoError(t, err)
+	_, err = db.ReadWebhookByID(acme, webhook.ID)
+	assert.ErrorIs(t, err, dberr.NotFound)
+	_, err = db.CreateFund(acme, validFund("Fund B"))
+	assert.NoError(t, err)
+	deliveries, err := db.ReadWebhookDeliveries(globex, webhook.ID, models.WebhookDeliveryQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries.Items, "webhooks only receive their tenant's events")
+	_, err = db.CreateFund(globex, validFund("Fund G"))
+	assert.NoError(t, err)
+	deliveries, err = db.ReadWebhookDeliveries(globex, webhook.ID, models.WebhookDeliveryQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, deliveries.Items, 1)
+	deliveries, err = db.ReadWebhookDeliveries(acme, webhook.ID, models.WebhookDeliveryQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, deliveries.Items)
+
+	key, err := db.CreateAPIKey(globex, models.APIKey{Name: "etl", Prefix: "tbk_g", Hash: "hash-g", Scopes: []string{"fund:read"}, CreatedBy: "ops"})
+	assert.NoError(t, err)
+	_, err = db.RevokeAPIKey(acme, key.ID)
+	assert.ErrorIs(t, err, dberr.NotFound)
+	used, err := db.UseAPIKey(context.Background(), "hash-g")
+	assert.NoError(t, err)
+	assert.Equal(t, "globex", used.TenantID, "keys are found in any tenant, and say which they act for")
+
+	_, err = db.RebuildProjections(context.Background())
+	assert.NoError(t, err)
+	rebuilt, err := db.ReadFundByID(acme, fund.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", rebuilt.TenantID)
+}
+
+func testFundStats(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	acme := access.NewContext(ctx, access.Scope{Tenant: "acme"})
+	del := models.DeleteResource{Reason: "test"}
+
+	open := mustCreateFund(t, db, validFund("Fund A"))
+	mustCreateFund(t, db, validFund("Fund E"))
+	closed := validFund("Fund B")
+	closed.Status = "Closed"
+	mustCreateFund(t, db, closed)
+	deleted := mustCreateFund(t, db, validFund("Fund C"))
+	_, err := db.DeleteFund(ctx, deleted.ID, del)
+	assert.NoError(t, err)
+	_, err = db.CreateFund(acme, validFund("Fund D"))
+	assert.NoError(t, err)
+
+	investor, err := db.CreateInvestor(ctx, validInvestor("alex@example.com"))
+	assert.NoError(t, err)
+	for _, amount := range []string{"100.50", "250.25", "1000"} {
+		investment, err := db.CreateInvestment(ctx, models.CreateInvestment{InvestorID: investor.ID, FundID: open.ID, AmountUsd: decimal.RequireFromString(amount), InvestmentDate: "2024-03-15"})
+		assert.NoError(t, err)
+		if amount == "1000" {
+			_, err = db.DeleteInvestment(ctx, open.ID, investment.ID, del)
+			assert.NoError(t, err)
+		}
+	}
+
+	stats, err := db.ReadFundStats(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 3)
+	fundraising, closedStats, acmeStats := stats[2], stats[1], stats[0]
+	assert.Equal(t, "Fundraising", fundraising.Status)
+	assert.Equal(t, models.DefaultTenant, fundraising.TenantID)
+	assert.Equal(t, 2, fundraising.Funds, "deleted funds are left out")
+	assert.True(t, decimal.RequireFromString("350.75").Equal(fundraising.CommittedUsd), fundraising.CommittedUsd.String())
+	assert.Equal(t, "Closed", closedStats.Status)
+	assert.Equal(t, 1, closedStats.Funds)
+	assert.True(t, closedStats.CommittedUsd.IsZero())
+	assert.Equal(t, "acme", acmeStats.TenantID, "every tenant is included")
+	assert.Equal(t, 1, acmeStats.Funds)
+}
+
+func testFundCommitments(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	acme := access.NewContext(ctx, access.Scope{Tenant: "acme"})
+
+	small := mustCreateFund(t, db, validFund("Fund A"))
+	large := mustCreateFund(t, db, validFund("Fund B"))
+	empty := mustCreateFund(t, db, validFund("Fund C"))
+	deleted := mustCreateFund(t, db, validFund("Fund D"))
+	acmeFund, err := db.CreateFund(acme, validFund("Fund E"))
+	assert.NoError(t, err)
+	investor := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	acmeInvestor, err := db.CreateInvestor(acme, validInvestor("alex@example.com"))
+	assert.NoError(t, err)
+	for _, inv := range []struct {
+		ctx      context.Context
+		investor uuid.UUID
+		fund     uuid.UUID
+		amount   string
+	}{
+		{ctx, investor.ID, small.ID, "100.50"},
+		{ctx, investor.ID, large.ID, "5000"},
+		{acme, acmeInvestor.ID, acmeFund.ID, "2500"},
+	} {
+		_, err := db.CreateInvestment(inv.ctx, models.CreateInvestment{InvestorID: inv.investor, FundID: inv.fund, AmountUsd: decimal.RequireFromString(inv.amount), InvestmentDate: "2024-03-15"})
+		assert.NoError(t, err)
+	}
+	_, err = db.DeleteFund(ctx, deleted.ID, models.DeleteResource{Reason: "test"})
+	assert.NoError(t, err)
+
+	commitments, err := db.ReadFundCommitments(ctx, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, commitments, 4, "deleted funds are left out") {
+		assert.Equal(t, large.ID, commitments[0].FundID)
+		assert.True(t, decimal.RequireFromString("5000").Equal(commitments[0].CommittedUsd), commitments[0].CommittedUsd.String())
+		assert.Equal(t, acmeFund.ID, commitments[1].FundID, "every tenant is included")
+		assert.Equal(t, "acme", commitments[1].TenantID)
+		assert.Equal(t, small.ID, commitments[2].FundID)
+		assert.Equal(t, empty.ID, commitments[3].FundID)
+		assert.True(t, commitments[3].CommittedUsd.IsZero())
+	}
+
+	commitments, err = db.ReadFundCommitments(ctx, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, commitments, 2) {
+		assert.Equal(t, large.ID, commitments[0].FundID)
+		assert.Equal(t, acmeFund.ID, commitments[1].FundID)
+	}
+}
+
+func testAccessScope(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	fundA := mustCreateFund(t, db, validFund("Fund A"))
+	fundB := mustCreateFund(t, db, validFund("Fund B"))
+	alex := mustCreateInvestor(t, db, validInvestor("alex@example.com"))
+	sam := mustCreateInvestor(t, db, models.CreateInvestor{Name: "Sam Jones", InvestorType: "Institution", Email: "sam@example.com"})
+	for _, inv := range []models.CreateInvestment{
+		{InvestorID: alex.ID, FundID: fundA.ID, AmountUsd: decimal.NewFromInt(100), InvestmentDate: "2024-03-15"},
+		{InvestorID: sam.ID, FundID: fundB.ID, AmountUsd: decimal.NewFromInt(200), InvestmentDate: "2024-03-15"},
+	} {
+		_, err := db.CreateInvestment(ctx, inv)
+		assert.NoError(t, err)
+	}
+	afterSetup := between()
+
+	fundIDs := func(r database.Reader, ctx context.Context) []uuid.UUID {
+		page, err := r.ReadFunds(ctx, models.FundQuery{})
+		assert.NoError(t, err)
+		var ids []uuid.UUID
+		for _, f := range page.Items {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+	investorIDs := func(ctx context.Context) []uuid.UUID {
+		page, err := db.ReadInvestors(ctx, models.InvestorQuery{})
+		assert.NoError(t, err)
+		var ids []uuid.UUID
+		for _, inv := range page.Items {
+			ids = append(ids, inv.ID)
+		}
+		return ids
+	}
+	assert.Len(t, fundIDs(db, ctx), 2, "an unrestricted scope sees everything")
+
+	manager := access.NewContext(ctx, access.Scope{Funds: []uuid.UUID{fundA.ID}})
+	assert.Equal(t, []uuid.UUID{fundA.ID}, fundIDs(db, manager))
+	assert.Equal(t, []uuid.UUID{fundA.ID}, fundIDs(db.AsOf(afterSetup), manager), "as-of reads are scoped too")
+	assert.Equal(t, []uuid.UUID{alex.ID}, investorIDs(manager), "a fund's investors are in scope")
+	_, err := db.ReadFundByID(manager, fundB.ID)
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+	_, err = db.ReadInvestorByID(manager, sam.ID)
+	assertDbErr(t, err, dberr.NotFound, "investor", "")
+	investments, err := db.ReadInvestments(manager, fundB.ID, models.InvestmentQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, investments.Items)
+	results, err := db.Search(manager, models.SearchQuery{Q: "Fund"})
+	assert.NoError(t, err)
+	if assert.Len(t, results.Items, 1) {
+		assert.Equal(t, fundA.ID, results.Items[0].Fund.ID)
+	}
+
+	_, err = db.UpdateFund(manager, fundB)
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+	_, err = db.DeleteFund(manager, fundB.ID, models.DeleteResource{Reason: "test"})
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+	_, err = db.UpdateInvestor(manager, alex)
+	assertDbErr(t, err, dberr.NotFound, "investor", "")
+	_, err = db.CreateInvestment(manager, models.CreateInvestment{InvestorID: sam.ID, FundID: fundB.ID, AmountUsd: decimal.NewFromInt(1), InvestmentDate: "2024-03-15"})
+	assertDbErr(t, err, dberr.ForeignKeyViolation, "investment", "fund_id")
+	investment, err := db.CreateInvestment(manager, models.CreateInvestment{InvestorID: sam.ID, FundID: fundA.ID, AmountUsd: decimal.NewFromInt(1), InvestmentDate: "2024-03-15"})
+	assert.NoError(t, err, "managers may take commitments from any investor into their funds")
+	_, err = db.DeleteInvestment(manager, fundA.ID, investment.ID, models.DeleteResource{Reason: "test"})
+	assert.NoError(t, err)
+	_, err = db.UpdateFund(manager, fundA)
+	assert.NoError(t, err)
+
+	lp := access.NewContext(ctx, access.Scope{Investors: []uuid.UUID{sam.ID}})
+	assert.ElementsMatch(t, []uuid.UUID{fundA.ID, fundB.ID}, fundIDs(db, lp), "funds an investor has invested in, even if withdrawn since, are in scope")
+	assert.Equal(t, []uuid.UUID{fundB.ID}, fundIDs(db.AsOf(afterSetup), lp))
+	assert.Equal(t, []uuid.UUID{sam.ID}, investorIDs(lp))
+	investments, err = db.ReadInvestments(lp, fundA.ID, models.InvestmentQuery{IncludeDeleted: true})
+	assert.NoError(t, err)
+	if assert.Len(t, investments.Items, 1, "only the investor's own investments are in scope") {
+		assert.Equal(t, sam.ID, investments.Items[0].InvestorID)
+	}
+	_, err = db.UpdateFund(lp, fundB)
+	assertDbErr(t, err, dberr.NotFound, "fund", "")
+
+	nobody := access.NewContext(ctx, access.Scope{Funds: []uuid.UUID{}})
+	assert.Empty(t, fundIDs(db, nobody))
+	assert.Empty(t, investorIDs(nobody))
+}
+
+func testPagination(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	for i, name := range []string{"Delta", "Alpha", "Echo", "Charlie", "Bravo"} {
+		cf := validFund(name)
+		cf.VintageYear = 2020 + i%2
+		mustCreateFund(t, db, cf)
+	}
+
+	query := models.FundQuery{PageQuery: models.PageQuery{Limit: 2, Sort: "-name"}}
+	var names []string
+	for pages := 0; pages < 5; pages++ {
+		page, err := db.ReadFunds(ctx, query)
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.LessOrEqual(t, len(page.Items), 2)
+		for _, f := range page.Items {
+			names = append(names, f.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"Echo", "Delta", "Charlie", "Bravo", "Alpha"}, names)
+
+	// Ties on the sort column are broken by id, so none are skipped.
+	query = models.FundQuery{PageQuery: models.PageQuery{Limit: 1, Sort: "vintage_year"}}
+	seen := make(map[uuid.UUID]bool)
+	for pages := 0; pages < 6; pages++ {
+		page, err := db.ReadFunds(ctx, query)
+		if !assert.NoError(t, err) {
+			return
+		}
+		for _, f := range page.Items {
+			assert.False(t, seen[f.ID], "fund %s returned twice", f.Name)
+			seen[f.ID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Len(t, seen, 5)
+
+	year := 2021
+	filtered, err := db.ReadFunds(ctx, models.FundQuery{VintageYearMin: &year})
+	assert.NoError(t, err)
+	assert.Len(t, filtered.Items, 2)
+}
+
+func testSearch(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	for _, name := range []string{"Growth", "Growth Equity II", "Titan Growth", "Evergreen Credit"} {
+		mustCreateFund(t, db, validFund(name))
+	}
+	mustCreateInvestor(t, db, models.CreateInvestor{Name: "Sam Jones", InvestorType: "Institution", Email: "sam@acmepension.com"})
+
+	page, err := db.Search(ctx, models.SearchQuery{Q: "growth"})
+	assert.NoError(t, err)
+	var names []string
+	for _, r := range page.Items {
+		names = append(names, r.Fund.Name)
+	}
+	assert.Equal(t, []string{"Growth", "Growth Equity II", "Titan Growth"}, names)
+
+	page, err = db.Search(ctx, models.SearchQuery{Q: "acmepension"})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, "investor", page.Items[0].Type)
+		assert.Equal(t, "Sam Jones", page.Items[0].Investor.Name)
+	}
+}
+
+func testTransactions(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	errRollback := errors.New("roll back")
+
+	err := db.WithTx(ctx, func(tx database.Db) error {
+		if _, err := tx.CreateFund(ctx, validFund("Rolled back")); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	err = db.WithTx(ctx, func(tx database.Db) error {
+		if _, err := tx.CreateFund(ctx, validFund("Committed")); err != nil {
+			return err
+		}
+		nested := tx.WithTx(ctx, func(tx database.Db) error {
+			if _, err := tx.CreateFund(ctx, validFund("Nested, rolled back")); err != nil {
+				return err
+			}
+			return errRollback
+		})
+		assert.ErrorIs(t, nested, errRollback)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	page, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, "Committed", page.Items[0].Name)
+	}
+}
+
+func testCancelledContext(t *testing.T, db database.Db) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := db.CreateFund(ctx, validFund("Fund I"))
+	assert.ErrorIs(t, err, context.Canceled)
+	_, err = db.ReadFunds(ctx, models.FundQuery{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	page, err := db.ReadFunds(context.Background(), models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+}
+
+func testConcurrentWriters(t *testing.T, db database.Db) {
+	ctx := context.Background()
+	const writers = 10
+
+	var wg sync.WaitGroup
+	errs := make([]error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := db.CreateFund(ctx, validFund(fmt.Sprintf("Fund %d", i))); err != nil {
+				errs[i] = err
+				return
+			}
+			_, errs[i] = db.CreateInvestor(ctx, validInvestor("same@example.com"))
+		}()
+	}
+	wg.Wait()
+
+	var created, conflicts int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, dberr.Conflict):
+			conflicts++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	assert.Equal(t, 1, created, "exactly one writer wins the email")
+	assert.Equal(t, writers-1, conflicts)
+
+	funds, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, funds.Items, writers)
+}
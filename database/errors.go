@@ -0,0 +1,150 @@
+package database
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/glebarez/go-sqlite"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// PostgreSQL SQLSTATE codes, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgInvalidDatetime     = "22007"
+	pgDatetimeOverflow    = "22008"
+	pgNotNullViolation    = "23502"
+	pgForeignKeyViolation = "23503"
+	pgUniqueViolation     = "23505"
+	pgCheckViolation      = "23514"
+)
+
+var tableEntities = map[string]string{
+	"funds":       "fund",
+	"investors":   "investor",
+	"investments": "investment",
+}
+
+// checkFields maps the named check constraints declared on the models to the
+// column they guard.
+var checkFields = map[string]string{
+	"vintage_year_range": "vintage_year",
+	"fund_status_chk":    "status",
+	"investor_type_chk":  "investor_type",
+	"amount_usd_nonneg":  "amount_usd",
+	// SQLite only, see migrations/sqlite/0001_init.up.sql.
+	"investment_date_valid": "investment_date",
+}
+
+// keyDetail extracts the column from details such as
+// `Key (email)=(a@b.com) already exists.` Keys that are unique per tenant
+// lead with tenant_id, which is skipped, as it is not what the caller chose.
+var keyDetail = regexp.MustCompile(`^Key \((?:tenant_id, )?([^)]+)\)=`)
+
+// translatePGError converts gorm and PostgreSQL errors into dberr errors.
+// entity is used when the error does not identify the table itself.
+func translatePGError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &dberr.Error{Kind: dberr.NotFound, Entity: entity, Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	if e, ok := tableEntities[pgErr.TableName]; ok {
+		entity = e
+	}
+	field := pgErr.ColumnName
+	if m := keyDetail.FindStringSubmatch(pgErr.Detail); m != nil {
+		field = m[1]
+	}
+
+	switch pgErr.Code {
+	case pgUniqueViolation:
+		return &dberr.Error{Kind: dberr.Conflict, Entity: entity, Field: field, Err: err}
+	case pgForeignKeyViolation:
+		return &dberr.Error{Kind: dberr.ForeignKeyViolation, Entity: entity, Field: field, Err: err}
+	case pgCheckViolation:
+		if f, ok := checkFields[pgErr.ConstraintName]; ok {
+			field = f
+		} else {
+			field = pgErr.ConstraintName
+		}
+		return &dberr.Error{Kind: dberr.CheckViolation, Entity: entity, Field: field, Err: err}
+	case pgNotNullViolation:
+		return &dberr.Error{Kind: dberr.CheckViolation, Entity: entity, Field: field, Err: err}
+	case pgInvalidDatetime, pgDatetimeOverflow:
+		// Raised while parsing the input, so PostgreSQL cannot name the column.
+		return &dberr.Error{Kind: dberr.CheckViolation, Entity: entity, Err: err}
+	default:
+		return err
+	}
+}
+
+// SQLite extended result codes, see https://www.sqlite.org/rescode.html
+const (
+	sqliteCheckViolation      = 275
+	sqliteForeignKeyViolation = 787
+	sqliteNotNullViolation    = 1299
+	sqliteUniqueViolation     = 2067
+	sqlitePrimaryKeyViolation = 1555
+)
+
+// sqliteColumn and sqliteCheck extract the failing column or constraint from
+// messages such as `UNIQUE constraint failed: investors.email` and
+// `CHECK constraint failed: fund_status_chk`. As in keyDetail, a leading
+// tenant_id is skipped.
+var (
+	sqliteColumn = regexp.MustCompile(`constraint failed: (\w+)\.(?:tenant_id, \w+\.)?(\w+)`)
+	sqliteCheck  = regexp.MustCompile(`CHECK constraint failed: (\w+)`)
+)
+
+// translateSQLiteError is the SQLite counterpart of translatePGError. SQLite
+// does not say which foreign key failed, so callers check references
+// themselves where the field matters.
+func translateSQLiteError(err error, entity string) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &dberr.Error{Kind: dberr.NotFound, Entity: entity, Err: err}
+	}
+
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+	var field string
+	if m := sqliteColumn.FindStringSubmatch(sqliteErr.Error()); m != nil {
+		if e, ok := tableEntities[m[1]]; ok {
+			entity = e
+		}
+		field = m[2]
+	}
+
+	switch sqliteErr.Code() {
+	case sqliteUniqueViolation, sqlitePrimaryKeyViolation:
+		return &dberr.Error{Kind: dberr.Conflict, Entity: entity, Field: field, Err: err}
+	case sqliteForeignKeyViolation:
+		return &dberr.Error{Kind: dberr.ForeignKeyViolation, Entity: entity, Err: err}
+	case sqliteCheckViolation:
+		if m := sqliteCheck.FindStringSubmatch(sqliteErr.Error()); m != nil {
+			if f, ok := checkFields[m[1]]; ok {
+				field = f
+			} else {
+				field = m[1]
+			}
+		}
+		return &dberr.Error{Kind: dberr.CheckViolation, Entity: entity, Field: field, Err: err}
+	case sqliteNotNullViolation:
+		return &dberr.Error{Kind: dberr.CheckViolation, Entity: entity, Field: field, Err: err}
+	default:
+		return err
+	}
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslatePGError(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		kind   dberr.Kind
+		entity string
+		field  string
+	}{
+		{
+			name:   "record not found",
+			err:    gorm.ErrRecordNotFound,
+			kind:   dberr.NotFound,
+			entity: "fund",
+		},
+		{
+			name: "unique email",
+			err: &pgconn.PgError{
+				Code:           pgUniqueViolation,
+				TableName:      "investors",
+				ConstraintName: "idx_investors_email",
+				Detail:         "Key (email)=(a@b.com) already exists.",
+			},
+			kind:   dberr.Conflict,
+			entity: "investor",
+			field:  "email",
+		},
+		{
+			name: "unique email per tenant",
+			err: &pgconn.PgError{
+				Code:           pgUniqueViolation,
+				TableName:      "investors",
+				ConstraintName: "idx_investors_email",
+				Detail:         "Key (tenant_id, email)=(acme, a@b.com) already exists.",
+			},
+			kind:   dberr.Conflict,
+			entity: "investor",
+			field:  "email",
+		},
+		{
+			name: "missing investor",
+			err: fmt.Errorf("wrapped: %w", &pgconn.PgError{
+				Code:      pgForeignKeyViolation,
+				TableName: "investments",
+				Detail:    `Key (investor_id)=(00000000-0000-0000-0000-000000000000) is not present in table "investors".`,
+			}),
+			kind:   dberr.ForeignKeyViolation,
+			entity: "investment",
+			field:  "investor_id",
+		},
+		{
+			name: "named check constraint",
+			err: &pgconn.PgError{
+				Code:           pgCheckViolation,
+				TableName:      "funds",
+				ConstraintName: "fund_status_chk",
+			},
+			kind:   dberr.CheckViolation,
+			entity: "fund",
+			field:  "status",
+		},
+		{
+			name: "not null",
+			err: &pgconn.PgError{
+				Code:       pgNotNullViolation,
+				TableName:  "funds",
+				ColumnName: "name",
+			},
+			kind:   dberr.CheckViolation,
+			entity: "fund",
+			field:  "name",
+		},
+		{
+			name: "impossible date",
+			err: &pgconn.PgError{
+				Code:    pgDatetimeOverflow,
+				Message: `date/time field value out of range: "2024-02-30"`,
+			},
+			kind:   dberr.CheckViolation,
+			entity: "fund",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := translatePGError(tc.err, "fund")
+			assert.ErrorIs(t, err, tc.kind)
+			e, ok := dberr.As(err)
+			assert.True(t, ok)
+			assert.Equal(t, tc.entity, e.Entity)
+			assert.Equal(t, tc.field, e.Field)
+		})
+	}
+}
+
+func TestTranslateError_PassesThroughOtherErrors(t *testing.T) {
+	errOther := errors.New("connection reset")
+	assert.Same(t, errOther, translatePGError(errOther, "fund"))
+	assert.NoError(t, translatePGError(nil, "fund"))
+
+	syntax := &pgconn.PgError{Code: "42601"}
+	assert.Same(t, syntax, translatePGError(syntax, "fund"))
+}
@@ -0,0 +1,162 @@
+// Package events defines the append-only stream of changes to funds,
+// investors and investments that is the system of record. The funds,
+// investors and investments tables, and their histories, are projections of
+// it: every write appends an event and applies it, and Replay can rebuild
+// them from scratch.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+)
+
+// Type names what happened. Every event carries the full state of its
+// entity afterwards, so that projections need not know how each change is
+// made.
+type Type string
+
+const (
+	FundCreated  Type = "FundCreated"
+	FundUpdated  Type = "FundUpdated"
+	FundDeleted  Type = "FundDeleted"
+	FundRestored Type = "FundRestored"
+
+	InvestorRegistered Type = "InvestorRegistered"
+	InvestorUpdated    Type = "InvestorUpdated"
+	InvestorDeleted    Type = "InvestorDeleted"
+	InvestorRestored   Type = "InvestorRestored"
+
+	CommitmentMade      Type = "CommitmentMade"
+	CommitmentWithdrawn Type = "CommitmentWithdrawn"
+	CommitmentRestored  Type = "CommitmentRestored"
+
+	// The Imported events snapshot rows that predate the event store; see
+	// migration 0007_events.
+	FundImported       Type = "FundImported"
+	InvestorImported   Type = "InvestorImported"
+	CommitmentImported Type = "CommitmentImported"
+)
+
+type typeInfo struct {
+	stream string
+	action string
+}
+
+var types = map[Type]typeInfo{
+	FundCreated:         {"fund", models.AuditCreate},
+	FundUpdated:         {"fund", models.AuditUpdate},
+	FundDeleted:         {"fund", models.AuditDelete},
+	FundRestored:        {"fund", models.AuditRestore},
+	FundImported:        {"fund", models.AuditCreate},
+	InvestorRegistered:  {"investor", models.AuditCreate},
+	InvestorUpdated:     {"investor", models.AuditUpdate},
+	InvestorDeleted:     {"investor", models.AuditDelete},
+	InvestorRestored:    {"investor", models.AuditRestore},
+	InvestorImported:    {"investor", models.AuditCreate},
+	CommitmentMade:      {"investment", models.AuditCreate},
+	CommitmentWithdrawn: {"investment", models.AuditDelete},
+	CommitmentRestored:  {"investment", models.AuditRestore},
+	CommitmentImported:  {"investment", models.AuditCreate},
+}
+
+// Stream returns the entity the event is about: "fund", "investor" or
+// "investment", as in the audit log.
+func (t Type) Stream() string {
+	return types[t].stream
+}
+
+// Action returns the audit log action the event is recorded as.
+func (t Type) Action() string {
+	return types[t].action
+}
+
+// Creates reports whether the event adds a row, rather than changing one.
+func (t Type) Creates() bool {
+	return t.Action() == models.AuditCreate
+}
+
+// Event is a change to one entity, identified by Stream and StreamID. Seq
+// orders every event in the store and is assigned on append.
+type Event struct {
+	Seq        int64           `json:"seq" gorm:"primaryKey;autoIncrement"`
+	Stream     string          `json:"stream" gorm:"not null"`
+	StreamID   uuid.UUID       `json:"stream_id" gorm:"type:uuid;not null"`
+	Type       Type            `json:"type" gorm:"not null"`
+	Data       json.RawMessage `json:"data" gorm:"not null;serializer:json"`
+	OccurredAt time.Time       `json:"occurred_at" gorm:"not null"`
+}
+
+func (Event) TableName() string {
+	return "events"
+}
+
+// New returns an event of type t about the entity with the given id, whose
+// state is now state.
+func New(t Type, id uuid.UUID, state any, at time.Time) (Event, error) {
+	if _, ok := types[t]; !ok {
+		return Event{}, fmt.Errorf("unknown event type %q", t)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{Stream: t.Stream(), StreamID: id, Type: t, Data: data, OccurredAt: at}, nil
+}
+
+// Decode returns the state carried by ev.
+func Decode[T any](ev Event) (T, error) {
+	var state T
+	if err := json.Unmarshal(ev.Data, &state); err != nil {
+		return state, fmt.Errorf("decoding %s event %d: %w", ev.Type, ev.Seq, err)
+	}
+	return state, nil
+}
+
+// Store is an append-only sequence of events.
+type Store interface {
+	// Append assigns each event the next Seq and stores it.
+	Append(context.Context, ...*Event) error
+	// Load returns up to limit events with a Seq greater than after, in
+	// order.
+	Load(ctx context.Context, after int64, limit int) ([]Event, error)
+}
+
+// Projection is state derived from events.
+type Projection interface {
+	// Reset discards the projection's state.
+	Reset(context.Context) error
+	Apply(context.Context, Event) error
+}
+
+const replayBatch = 500
+
+// Replay resets p and applies every event in store to it, in order. It
+// returns the number of events applied.
+func Replay(ctx context.Context, store Store, p Projection) (int, error) {
+	if err := p.Reset(ctx); err != nil {
+		return 0, err
+	}
+	var n int
+	var after int64
+	for {
+		batch, err := store.Load(ctx, after, replayBatch)
+		if err != nil {
+			return n, err
+		}
+		for _, ev := range batch {
+			if err := p.Apply(ctx, ev); err != nil {
+				return n, fmt.Errorf("applying %s event %d: %w", ev.Type, ev.Seq, err)
+			}
+			n++
+			after = ev.Seq
+		}
+		if len(batch) < replayBatch {
+			return n, nil
+		}
+	}
+}
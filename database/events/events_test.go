@@ -0,0 +1,83 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingProjection struct {
+	resets int
+	seqs   []int64
+}
+
+func (p *countingProjection) Reset(context.Context) error {
+	p.resets++
+	p.seqs = nil
+	return nil
+}
+
+func (p *countingProjection) Apply(_ context.Context, ev Event) error {
+	p.seqs = append(p.seqs, ev.Seq)
+	return nil
+}
+
+func TestReplay_AppliesEveryEventInOrder(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	total := 2*replayBatch + 1
+	for range total {
+		id := uuid.New()
+		ev, err := New(FundCreated, id, models.Fund{ID: id}, time.Now())
+		assert.NoError(t, err)
+		assert.NoError(t, store.Append(ctx, &ev))
+	}
+
+	p := &countingProjection{seqs: []int64{42}}
+	n, err := Replay(ctx, store, p)
+	assert.NoError(t, err)
+	assert.Equal(t, total, n)
+	assert.Equal(t, 1, p.resets)
+	if assert.Len(t, p.seqs, total) {
+		for i, seq := range p.seqs {
+			assert.Equal(t, int64(i+1), seq)
+		}
+	}
+}
+
+func TestMemoryStore_CloneIsIndependent(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	ev, _ := New(InvestorRegistered, uuid.New(), models.Investor{}, time.Now())
+	assert.NoError(t, store.Append(ctx, &ev))
+
+	clone := store.Clone()
+	next, _ := New(InvestorUpdated, ev.StreamID, models.Investor{}, time.Now())
+	assert.NoError(t, clone.Append(ctx, &next))
+	assert.Equal(t, int64(2), next.Seq)
+
+	original, _ := store.Load(ctx, 0, 10)
+	cloned, _ := clone.Load(ctx, 0, 10)
+	assert.Len(t, original, 1)
+	assert.Len(t, cloned, 2)
+}
+
+func TestNew_RejectsUnknownType(t *testing.T) {
+	_, err := New("FundRenamed", uuid.New(), models.Fund{}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestWebhookEventTypes_AreTheEmittedTypes(t *testing.T) {
+	var emitted []string
+	for typ := range types {
+		if !strings.HasSuffix(string(typ), "Imported") {
+			emitted = append(emitted, string(typ))
+		}
+	}
+	assert.ElementsMatch(t, emitted, models.WebhookEventTypes)
+}
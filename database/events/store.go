@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// MemoryStore is a Store held in memory, for MockDb.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events []Event
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (s *MemoryStore) Append(ctx context.Context, events ...*Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Appends go to a clipped slice, so that they never write to an array
+	// shared with a clone.
+	s.events = slices.Clip(s.events)
+	for _, ev := range events {
+		ev.Seq = int64(len(s.events) + 1)
+		s.events = append(s.events, *ev)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Load(ctx context.Context, after int64, limit int) ([]Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	// Seq is one more than the index.
+	start := min(int(max(after, 0)), len(s.events))
+	end := min(start+limit, len(s.events))
+	return slices.Clone(s.events[start:end]), nil
+}
+
+// Clone returns a copy of s that can be appended to independently.
+func (s *MemoryStore) Clone() *MemoryStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &MemoryStore{events: slices.Clip(s.events)}
+}
+
+// SQLStore is a Store in the events table. Pass it the transaction that
+// applies the events, so that they are committed together.
+type SQLStore struct {
+	db *gorm.DB
+}
+
+func NewSQLStore(db *gorm.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Append(ctx context.Context, events ...*Event) error {
+	for _, ev := range events {
+		if err := s.db.WithContext(ctx).Create(ev).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) Load(ctx context.Context, after int64, limit int) ([]Event, error) {
+	var events []Event
+	err := s.db.WithContext(ctx).Where("seq > ?", after).Order("seq").Limit(limit).Find(&events).Error
+	return events, err
+}
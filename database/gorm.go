@@ -0,0 +1,460 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/iuhmirza/titanbay-take-home/database/events"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("date", dateSerializer{})
+}
+
+// dateSerializer reads date columns into 'yyyy-mm-dd' strings. Without it,
+// PostgreSQL dates arrive as time.Time and database/sql formats them as
+// RFC 3339 timestamps.
+type dateSerializer struct{}
+
+func (dateSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	var date string
+	switch v := dbValue.(type) {
+	case nil:
+	case time.Time:
+		date = v.Format(time.DateOnly)
+	case string:
+		date = v
+	case []byte:
+		date = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a date", dbValue)
+	}
+	return field.Set(ctx, dst, date)
+}
+
+func (dateSerializer) Value(_ context.Context, _ *schema.Field, _ reflect.Value, fieldValue any) (any, error) {
+	return fieldValue, nil
+}
+
+// gormDb implements the Db methods that are portable across the SQL dialects
+// we support. PGDB and SQLiteDB embed it, supplying translate to map their
+// driver's errors to dberr and implementing Search, AsOf and WithTx
+// themselves. asOf is set in the read-only views returned by AsOf.
+type gormDb struct {
+	db        *gorm.DB
+	translate func(err error, entity string) error
+	asOf      *time.Time
+}
+
+func (g *gormDb) CreateFund(ctx context.Context, createFund models.CreateFund) (models.Fund, error) {
+	fund := models.Fund{
+		ID:            uuid.New(),
+		Name:          createFund.Name,
+		VintageYear:   createFund.VintageYear,
+		TargetSizeUsd: createFund.TargetSizeUsd,
+		Status:        createFund.Status,
+		Version:       1,
+		Tenancy:       models.Tenancy{TenantID: tenantOf(ctx)},
+	}
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		fund.CreatedAt = tx.NowFunc()
+		return emit(ctx, tx, events.FundCreated, fund.ID, nil, fund)
+	})
+	return fund, g.translate(err, "fund")
+}
+
+func (g *gormDb) ReadFunds(ctx context.Context, query models.FundQuery) (models.Page[models.Fund], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Fund]{}, err
+	}
+	tx := g.from(ctx, "funds")
+	if query.Status != "" {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	if query.VintageYearMin != nil {
+		tx = tx.Where("vintage_year >= ?", *query.VintageYearMin)
+	}
+	if query.VintageYearMax != nil {
+		tx = tx.Where("vintage_year <= ?", *query.VintageYearMax)
+	}
+	if !query.IncludeDeleted {
+		tx = tx.Where("deleted_at IS NULL")
+	}
+	return paginate[models.Fund](tx, query.PageQuery)
+}
+
+func (g *gormDb) ReadFundByID(ctx context.Context, id uuid.UUID) (models.Fund, error) {
+	var fund models.Fund
+	return fund, g.translate(g.from(ctx, "funds").First(&fund, "id = ?", id).Error, "fund")
+}
+
+func (g *gormDb) UpdateFund(ctx context.Context, fund models.Fund) (models.Fund, error) {
+	if err := checkWriteScope(ctx, "fund", fund.ID); err != nil {
+		return models.Fund{}, err
+	}
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.Fund
+		if err := lockLive(inTenant(ctx, tx), &existing, fund.ID); err != nil {
+			return err
+		}
+		if fund.Version != 0 && fund.Version != existing.Version {
+			return &dberr.Error{Kind: dberr.VersionMismatch, Entity: "fund"}
+		}
+		if fund.CreatedAt.IsZero() {
+			fund.CreatedAt = existing.CreatedAt
+		}
+		fund.Version = existing.Version + 1
+		fund.Tenancy = existing.Tenancy
+		fund.Deletion = existing.Deletion
+		return emit(ctx, tx, events.FundUpdated, fund.ID, existing, fund)
+	})
+	if err != nil {
+		return models.Fund{}, g.translate(err, "fund")
+	}
+	return fund, nil
+}
+
+func (g *gormDb) DeleteFund(ctx context.Context, id uuid.UUID, del models.DeleteResource) (models.Fund, error) {
+	if err := checkWriteScope(ctx, "fund", id); err != nil {
+		return models.Fund{}, err
+	}
+	var fund models.Fund
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockLive(inTenant(ctx, tx), &fund, id); err != nil {
+			return err
+		}
+		if err := noLiveInvestments(tx, "fund_id", id, "fund"); err != nil {
+			return err
+		}
+		before := fund
+		fund.Deletion = newDeletion(ctx, tx, del)
+		fund.Version++
+		return emit(ctx, tx, events.FundDeleted, id, before, fund)
+	})
+	if err != nil {
+		return models.Fund{}, g.translate(err, "fund")
+	}
+	return fund, nil
+}
+
+func (g *gormDb) RestoreFund(ctx context.Context, id uuid.UUID) (models.Fund, error) {
+	if err := checkWriteScope(ctx, "fund", id); err != nil {
+		return models.Fund{}, err
+	}
+	var fund models.Fund
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := inTenant(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&fund, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if !fund.Deleted() {
+			return nil
+		}
+		before := fund
+		fund.Deletion = models.Deletion{}
+		fund.Version++
+		return emit(ctx, tx, events.FundRestored, id, before, fund)
+	})
+	if err != nil {
+		return models.Fund{}, g.translate(err, "fund")
+	}
+	return fund, nil
+}
+
+func (g *gormDb) CreateInvestor(ctx context.Context, createInvestor models.CreateInvestor) (models.Investor, error) {
+	investor := models.Investor{
+		ID:           uuid.New(),
+		Name:         createInvestor.Name,
+		InvestorType: createInvestor.InvestorType,
+		Email:        createInvestor.Email,
+		Version:      1,
+		Tenancy:      models.Tenancy{TenantID: tenantOf(ctx)},
+	}
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		investor.CreatedAt = tx.NowFunc()
+		return emit(ctx, tx, events.InvestorRegistered, investor.ID, nil, investor)
+	})
+	return investor, g.translate(err, "investor")
+}
+
+func (g *gormDb) ReadInvestors(ctx context.Context, query models.InvestorQuery) (models.Page[models.Investor], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Investor]{}, err
+	}
+	tx := g.from(ctx, "investors")
+	if query.InvestorType != "" {
+		tx = tx.Where("investor_type = ?", query.InvestorType)
+	}
+	if !query.IncludeDeleted {
+		tx = tx.Where("deleted_at IS NULL")
+	}
+	return paginate[models.Investor](tx, query.PageQuery)
+}
+
+func (g *gormDb) ReadInvestorByID(ctx context.Context, id uuid.UUID) (models.Investor, error) {
+	var investor models.Investor
+	return investor, g.translate(g.from(ctx, "investors").First(&investor, "id = ?", id).Error, "investor")
+}
+
+func (g *gormDb) UpdateInvestor(ctx context.Context, investor models.Investor) (models.Investor, error) {
+	if err := checkWriteScope(ctx, "investor", investor.ID); err != nil {
+		return models.Investor{}, err
+	}
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.Investor
+		if err := lockLive(inTenant(ctx, tx), &existing, investor.ID); err != nil {
+			return err
+		}
+		if investor.Version != 0 && investor.Version != existing.Version {
+			return &dberr.Error{Kind: dberr.VersionMismatch, Entity: "investor"}
+		}
+		if investor.CreatedAt.IsZero() {
+			investor.CreatedAt = existing.CreatedAt
+		}
+		investor.Version = existing.Version + 1
+		investor.Tenancy = existing.Tenancy
+		investor.Deletion = existing.Deletion
+		return emit(ctx, tx, events.InvestorUpdated, investor.ID, existing, investor)
+	})
+	if err != nil {
+		return models.Investor{}, g.translate(err, "investor")
+	}
+	return investor, nil
+}
+
+func (g *gormDb) DeleteInvestor(ctx context.Context, id uuid.UUID, del models.DeleteResource) (models.Investor, error) {
+	if err := checkWriteScope(ctx, "investor", id); err != nil {
+		return models.Investor{}, err
+	}
+	var investor models.Investor
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockLive(inTenant(ctx, tx), &investor, id); err != nil {
+			return err
+		}
+		if err := noLiveInvestments(tx, "investor_id", id, "investor"); err != nil {
+			return err
+		}
+		before := investor
+		investor.Deletion = newDeletion(ctx, tx, del)
+		investor.Version++
+		return emit(ctx, tx, events.InvestorDeleted, id, before, investor)
+	})
+	if err != nil {
+		return models.Investor{}, g.translate(err, "investor")
+	}
+	return investor, nil
+}
+
+// RestoreInvestor fails with dberr.Conflict if a live investor has taken
+// the email address in the meantime.
+func (g *gormDb) RestoreInvestor(ctx context.Context, id uuid.UUID) (models.Investor, error) {
+	if err := checkWriteScope(ctx, "investor", id); err != nil {
+		return models.Investor{}, err
+	}
+	var investor models.Investor
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := inTenant(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&investor, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if !investor.Deleted() {
+			return nil
+		}
+		before := investor
+		investor.Deletion = models.Deletion{}
+		investor.Version++
+		return emit(ctx, tx, events.InvestorRestored, id, before, investor)
+	})
+	if err != nil {
+		return models.Investor{}, g.translate(err, "investor")
+	}
+	return investor, nil
+}
+
+// CreateInvestment checks the referenced rows up front, rather than relying
+// on the foreign keys alone, because not every driver reports which
+// constraint failed and the foreign keys cannot tell deleted rows apart.
+func (g *gormDb) CreateInvestment(ctx context.Context, createInvestment models.CreateInvestment) (models.Investment, error) {
+	investment := models.Investment{
+		ID:             uuid.New(),
+		InvestorID:     createInvestment.InvestorID,
+		AmountUsd:      createInvestment.AmountUsd,
+		InvestmentDate: createInvestment.InvestmentDate,
+		FundID:         createInvestment.FundID,
+		Tenancy:        models.Tenancy{TenantID: tenantOf(ctx)},
+	}
+
+	if err := checkInvestmentScope(ctx, investment.FundID, investment.InvestorID); err != nil {
+		return models.Investment{}, err
+	}
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := mustExist(inTenant(ctx, tx), &models.Investor{}, investment.InvestorID, "investor_id"); err != nil {
+			return err
+		}
+		if err := mustExist(inTenant(ctx, tx), &models.Fund{}, investment.FundID, "fund_id"); err != nil {
+			return err
+		}
+		investment.CreatedAt = tx.NowFunc()
+		return emit(ctx, tx, events.CommitmentMade, investment.ID, nil, investment)
+	})
+	return investment, g.translate(err, "investment")
+}
+
+// mustExist checks that an investment's field references a live row of
+// model, in the tenant tx is limited to. The row is share-locked, so that
+// it cannot be deleted until the transaction ends; DeleteFund and
+// DeleteInvestor lock it for update before looking for live investments.
+func mustExist(tx *gorm.DB, model any, id uuid.UUID, field string) error {
+	var ids []uuid.UUID
+	err := tx.Model(model).Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = ? AND deleted_at IS NULL", id).Limit(1).Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return &dberr.Error{Kind: dberr.ForeignKeyViolation, Entity: "investment", Field: field}
+	}
+	return nil
+}
+
+// lockLive reads the live row with the given id into dst and locks it for
+// update. Deleted rows, and those outside the tenant tx is limited to, are
+// reported as not found.
+func lockLive(tx *gorm.DB, dst any, id uuid.UUID) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(dst, "id = ? AND deleted_at IS NULL", id).Error
+}
+
+// noLiveInvestments fails with dberr.InUse if live investments reference id
+// through column.
+func noLiveInvestments(tx *gorm.DB, column string, id uuid.UUID, entity string) error {
+	var count int64
+	if err := tx.Model(&models.Investment{}).Where(column+" = ? AND deleted_at IS NULL", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return &dberr.Error{Kind: dberr.InUse, Entity: entity}
+	}
+	return nil
+}
+
+// newDeletion records the deletion of a row, now, by the actor of ctx.
+func newDeletion(ctx context.Context, tx *gorm.DB, del models.DeleteResource) models.Deletion {
+	now := tx.NowFunc()
+	return models.Deletion{DeletedAt: &now, DeletedBy: actorOf(ctx), DeleteReason: del.Reason}
+}
+
+func (g *gormDb) ReadInvestments(ctx context.Context, fundID uuid.UUID, query models.InvestmentQuery) (models.Page[models.Investment], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Investment]{}, err
+	}
+	tx := g.from(ctx, "investments").Where("fund_id = ?", fundID)
+	if query.InvestorID != nil {
+		tx = tx.Where("investor_id = ?", *query.InvestorID)
+	}
+	if query.InvestmentDateFrom != "" {
+		tx = tx.Where("investment_date >= ?", query.InvestmentDateFrom)
+	}
+	if query.InvestmentDateTo != "" {
+		tx = tx.Where("investment_date <= ?", query.InvestmentDateTo)
+	}
+	if query.AmountUsdMin != nil {
+		tx = tx.Where("amount_usd >= ?", *query.AmountUsdMin)
+	}
+	if query.AmountUsdMax != nil {
+		tx = tx.Where("amount_usd <= ?", *query.AmountUsdMax)
+	}
+	if !query.IncludeDeleted {
+		tx = tx.Where("deleted_at IS NULL")
+	}
+	return paginate[models.Investment](tx, query.PageQuery)
+}
+
+func (g *gormDb) DeleteInvestment(ctx context.Context, fundID, id uuid.UUID, del models.DeleteResource) (models.Investment, error) {
+	var investment models.Investment
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := inTenant(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&investment, "id = ? AND fund_id = ? AND deleted_at IS NULL", id, fundID).Error
+		if err != nil {
+			return err
+		}
+		if !access.FromContext(ctx).HasInvestment(investment.FundID, investment.InvestorID) {
+			return gorm.ErrRecordNotFound
+		}
+		before := investment
+		investment.Deletion = newDeletion(ctx, tx, del)
+		return emit(ctx, tx, events.CommitmentWithdrawn, id, before, investment)
+	})
+	if err != nil {
+		return models.Investment{}, g.translate(err, "investment")
+	}
+	return investment, nil
+}
+
+func (g *gormDb) RestoreInvestment(ctx context.Context, fundID, id uuid.UUID) (models.Investment, error) {
+	var investment models.Investment
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := inTenant(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&investment, "id = ? AND fund_id = ?", id, fundID).Error
+		if err != nil {
+			return err
+		}
+		if !access.FromContext(ctx).HasInvestment(investment.FundID, investment.InvestorID) {
+			return gorm.ErrRecordNotFound
+		}
+		if !investment.Deleted() {
+			return nil
+		}
+		if err := mustExist(inTenant(ctx, tx), &models.Investor{}, investment.InvestorID, "investor_id"); err != nil {
+			return err
+		}
+		if err := mustExist(inTenant(ctx, tx), &models.Fund{}, investment.FundID, "fund_id"); err != nil {
+			return err
+		}
+		before := investment
+		investment.Deletion = models.Deletion{}
+		return emit(ctx, tx, events.CommitmentRestored, id, before, investment)
+	})
+	if err != nil {
+		return models.Investment{}, g.translate(err, "investment")
+	}
+	return investment, nil
+}
+
+func (g *gormDb) ReadAuditLog(ctx context.Context, query models.AuditQuery) (models.Page[models.AuditEntry], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.AuditEntry]{}, err
+	}
+	tx := inTenant(ctx, g.db.WithContext(ctx))
+	if query.Entity != "" {
+		tx = tx.Where("entity = ?", query.Entity)
+	}
+	if query.EntityID != nil {
+		tx = tx.Where("entity_id = ?", *query.EntityID)
+	}
+	return paginate[models.AuditEntry](tx, query.PageQuery)
+}
+
+func (g *gormDb) RebuildProjections(ctx context.Context) (int, error) {
+	var n int
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		n, err = events.Replay(ctx, events.NewSQLStore(tx), sqlProjection{tx})
+		return err
+	})
+	return n, err
+}
+
+// withTx runs fn against a Db bound to a new transaction (or savepoint, when
+// g is already in one). wrap builds the dialect's Db around the transaction.
+func (g *gormDb) withTx(ctx context.Context, wrap func(gormDb) Db, fn func(Db) error) error {
+	return g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(wrap(gormDb{db: tx, translate: g.translate}))
+	})
+}
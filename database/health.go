@@ -0,0 +1,54 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// gormOf returns the connection behind db, or nil for MockDb.
+func gormOf(db Db) *gorm.DB {
+	switch db := db.(type) {
+	case *PGDB:
+		return db.db
+	case *SQLiteDB:
+		return db.db
+	}
+	return nil
+}
+
+// Ping checks that db's database can be reached. MockDb always can.
+func Ping(ctx context.Context, db Db) error {
+	g := gormOf(db)
+	if g == nil {
+		return ctx.Err()
+	}
+	sqlDB, err := g.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckSchema returns the schema version of db's database, failing unless
+// every embedded migration has been applied, as ConnectToDB requires at
+// startup. MockDb has no schema, and reports version 0.
+func CheckSchema(ctx context.Context, db Db) (int, error) {
+	g := gormOf(db)
+	if g == nil {
+		return 0, ctx.Err()
+	}
+	migrator, err := NewMigrator(g)
+	if err != nil {
+		return 0, err
+	}
+	current, err := migrator.Current(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if current < migrator.Latest() {
+		return current, fmt.Errorf("schema is at version %d but %d is required", current, migrator.Latest())
+	}
+	return current, nil
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+	mock := NewMockDb()
+	assert.NoError(t, Ping(ctx, mock))
+	version, err := CheckSchema(ctx, mock)
+	assert.NoError(t, err)
+	assert.Zero(t, version)
+
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	assert.NoError(t, Ping(ctx, db))
+	migrator, err := NewMigrator(db.db)
+	assert.NoError(t, err)
+	version, err = CheckSchema(ctx, db)
+	assert.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), version)
+
+	assert.NoError(t, migrator.Down(ctx))
+	version, err = CheckSchema(ctx, db)
+	assert.Error(t, err)
+	assert.Equal(t, migrator.Latest()-1, version)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Error(t, Ping(cancelled, db))
+	assert.Error(t, Ping(cancelled, mock))
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// addVersion closes the current version of the row with the given id in the
+// history table of model's table, and appends a copy of the row as it now
+// is, valid from at. The projection calls it after every write, so that the
+// current versions always match the live table (see migration 0006_history).
+func addVersion(tx *gorm.DB, model any, id uuid.UUID, at time.Time) error {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	table, columns := stmt.Schema.Table, strings.Join(stmt.Schema.DBNames, ", ")
+	err := tx.Table(table+"_history").Where("id = ? AND valid_to IS NULL", id).Update("valid_to", at).Error
+	if err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO "+table+"_history (valid_from, "+columns+") SELECT ?, "+columns+
+		" FROM "+table+" WHERE id = ?", at, id).Error
+}
+
+// from starts a query on table or, in a view returned by AsOf, on the
+// versions in its history table that were current at that instant. The
+// versions are aliased to the table name, so callers need not care which.
+// Either way, only the rows that the access.Scope in ctx allows are seen.
+func (g *gormDb) from(ctx context.Context, table string) *gorm.DB {
+	tx := g.db.WithContext(ctx)
+	if g.asOf != nil {
+		versions := tx.Session(&gorm.Session{NewDB: true}).Table(table+"_history").
+			Where("valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", *g.asOf, *g.asOf)
+		tx = tx.Table("(?) AS "+table, versions)
+	} else {
+		tx = tx.Table(table)
+	}
+	return g.scoped(ctx, tx, table)
+}
+
+// asOfView returns a copy of g that reads the state at t.
+func (g *gormDb) asOfView(t time.Time) gormDb {
+	t = t.UTC()
+	return gormDb{db: g.db, translate: g.translate, asOf: &t}
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyKeyIs selects key's row by its primary key: its tenant,
+// subject and key.
+func idempotencyKeyIs(key models.IdempotencyKey) clause.Expr {
+	return gorm.Expr("tenant_id = ? AND subject = ? AND idempotency_key = ?", key.TenantID, key.Subject, key.Key)
+}
+
+// ReserveIdempotencyKey relies on the primary key to pick a winner among
+// concurrent requests, on any replica: the losers' inserts do nothing, and
+// they read the winner's row instead.
+func (g *gormDb) ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl time.Duration) (models.IdempotencyKey, bool, error) {
+	var reserved bool
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		err := tx.Where(idempotencyKeyIs(key)).Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{}).Error
+		if err != nil {
+			return err
+		}
+		key = newIdempotencyKey(key, now, ttl)
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&key)
+		if res.Error != nil {
+			return res.Error
+		}
+		if reserved = res.RowsAffected == 1; reserved {
+			return nil
+		}
+		err = tx.Where(idempotencyKeyIs(key)).First(&key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The winner released the key in the meantime. Report it as
+			// still in progress, so that the client retries.
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return models.IdempotencyKey{}, false, g.translate(err, "idempotency key")
+	}
+	return key, reserved, nil
+}
+
+func (g *gormDb) CompleteIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	err := g.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where(idempotencyKeyIs(key)).
+		Select("status_code", "response_header", "response_body").
+		Updates(&key).Error
+	return g.translate(err, "idempotency key")
+}
+
+func (g *gormDb) ReleaseIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	err := g.db.WithContext(ctx).Where(idempotencyKeyIs(key)).Where("status_code = 0").Delete(&models.IdempotencyKey{}).Error
+	return g.translate(err, "idempotency key")
+}
+
+func (g *gormDb) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	res := g.db.WithContext(ctx).Where("expires_at <= ?", g.db.NowFunc()).Delete(&models.IdempotencyKey{})
+	return int(res.RowsAffected), g.translate(res.Error, "idempotency key")
+}
+
+// newIdempotencyKey returns key reserved at now, for ttl. MockDb uses it too.
+func newIdempotencyKey(key models.IdempotencyKey, now time.Time, ttl time.Duration) models.IdempotencyKey {
+	return models.IdempotencyKey{
+		TenantID:    key.TenantID,
+		Subject:     key.Subject,
+		Key:         key.Key,
+		RequestHash: key.RequestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+	}
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/metrics"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// queryStartKey is where instrumentQueries keeps a statement's start time.
+const queryStartKey = "metrics:start"
+
+// Instrument reports db's query durations and connection pool stats to m.
+// MockDb has neither, and is left alone.
+func Instrument(db Db, m *metrics.Metrics) error {
+	g := gormOf(db)
+	if g == nil {
+		return nil
+	}
+	if err := instrumentQueries(g, m); err != nil {
+		return err
+	}
+	sqlDB, err := g.DB()
+	if err != nil {
+		return err
+	}
+	return m.Register(collectors.NewDBStatsCollector(sqlDB, g.Dialector.Name()))
+}
+
+// instrumentQueries times every statement GORM runs, with callbacks around
+// each of its processors.
+func instrumentQueries(db *gorm.DB, m *metrics.Metrics) error {
+	start := func(tx *gorm.DB) {
+		tx.InstanceSet(queryStartKey, time.Now())
+	}
+	observe := func(operation string) func(*gorm.DB) {
+		return func(tx *gorm.DB) {
+			if began, ok := tx.InstanceGet(queryStartKey); ok {
+				m.ObserveQuery(operation, tx.Statement.Table, time.Since(began.(time.Time)))
+			}
+		}
+	}
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("metrics:before_create", start),
+		cb.Create().After("*").Register("metrics:after_create", observe("create")),
+		cb.Query().Before("*").Register("metrics:before_query", start),
+		cb.Query().After("*").Register("metrics:after_query", observe("query")),
+		cb.Update().Before("*").Register("metrics:before_update", start),
+		cb.Update().After("*").Register("metrics:after_update", observe("update")),
+		cb.Delete().Before("*").Register("metrics:before_delete", start),
+		cb.Delete().After("*").Register("metrics:after_delete", observe("delete")),
+		cb.Row().Before("*").Register("metrics:before_row", start),
+		cb.Row().After("*").Register("metrics:after_row", observe("row")),
+		cb.Raw().Before("*").Register("metrics:before_raw", start),
+		cb.Raw().After("*").Register("metrics:after_raw", observe("raw")),
+	)
+}
@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/metrics"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	m := metrics.New()
+	assert.NoError(t, Instrument(db, m))
+	assert.NoError(t, Instrument(NewMockDb(), metrics.New()))
+
+	_, err = db.CreateFund(ctx, models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+	_, err = db.ReadFundStats(ctx)
+	assert.NoError(t, err)
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `titanbay_db_query_duration_seconds_count{operation="create",table="funds"}`)
+	assert.Contains(t, string(body), `titanbay_db_query_duration_seconds_count{operation="row",table="funds"} 1`)
+	assert.Contains(t, string(body), `go_sql_open_connections{db_name="sqlite"}`)
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is an arbitrary key for pg_advisory_xact_lock, serialising
+// migrators started concurrently by several replicas.
+const migrationLockID = 7_391_604_218
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql pairs from fsys.
+// Versions must start at 1 and have no gaps, and every migration needs both
+// directions.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected file %q in migrations", entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for version := 1; version <= len(byVersion); version++ {
+		migration, ok := byVersion[version]
+		if !ok {
+			return nil, fmt.Errorf("migration %d is missing", version)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	return migrations, nil
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+// NewMigrator returns a Migrator for the migrations embedded in the binary
+// for db's dialect. Each dialect has its own directory under migrations/,
+// with the same versions and names so that schema_migrations means the same
+// thing on every backend.
+func NewMigrator(db *gorm.DB) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := dialectMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+func dialectMigrations(dialect string) ([]Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations/"+dialect)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := LoadMigrations(sub)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for %s", dialect)
+	}
+	return migrations, nil
+}
+
+// Latest is the version the embedded migrations bring the schema to.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+func (m *Migrator) ensureVersionTable(ctx context.Context) error {
+	timestamp := "timestamptz"
+	if m.dialect == "sqlite" {
+		timestamp = "datetime"
+	}
+	return m.db.WithContext(ctx).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at ` + timestamp + ` NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`).Error
+}
+
+// Current returns the highest applied version, or 0 for an empty database.
+func (m *Migrator) Current(ctx context.Context) (int, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return 0, err
+	}
+	var version int
+	err := m.db.WithContext(ctx).Raw(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version).Error
+	return version, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureVersionTable(ctx); err != nil {
+		return nil, err
+	}
+	var rows []struct {
+		Version   int
+		AppliedAt time.Time
+	}
+	if err := m.db.WithContext(ctx).Raw(`SELECT version, applied_at FROM schema_migrations`).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i].Migration = migration
+		if at, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	current, err := m.Current(ctx)
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		return nil
+	}
+	return m.To(ctx, current-1)
+}
+
+// To migrates up or down until the schema is at version. Each step runs in
+// its own transaction together with its schema_migrations bookkeeping.
+func (m *Migrator) To(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("version must be between 0 and %d", m.Latest())
+	}
+	current, err := m.Current(ctx)
+	if err != nil {
+		return err
+	}
+	if current > m.Latest() {
+		return fmt.Errorf("database is at version %d, which is newer than this binary's %d", current, m.Latest())
+	}
+
+	for _, step := range plan(m.migrations, current, version) {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// SQLite serialises writers itself, so only PostgreSQL needs the lock.
+			if m.dialect == "postgres" {
+				if err := tx.Exec(`SELECT pg_advisory_xact_lock(?)`, migrationLockID).Error; err != nil {
+					return err
+				}
+			}
+			// Another replica may have run this step while we waited for the lock.
+			var applied bool
+			if err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = ?)`, step.Version).Scan(&applied).Error; err != nil {
+				return err
+			}
+			if applied == step.up {
+				return nil
+			}
+
+			if step.up {
+				if err := tx.Exec(step.Up).Error; err != nil {
+					return err
+				}
+				return tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, step.Version, step.Name).Error
+			}
+			if err := tx.Exec(step.Down).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, step.Version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s failed: %w", step.Version, step.Name, err)
+		}
+	}
+	return nil
+}
+
+type migrationStep struct {
+	Migration
+	up bool
+}
+
+// plan lists the migrations to run, in order, to go from version current to
+// version target.
+func plan(migrations []Migration, current, target int) []migrationStep {
+	var steps []migrationStep
+	for v := current + 1; v <= target; v++ {
+		steps = append(steps, migrationStep{Migration: migrations[v-1], up: true})
+	}
+	for v := current; v > target; v-- {
+		steps = append(steps, migrationStep{Migration: migrations[v-1], up: false})
+	}
+	return steps
+}
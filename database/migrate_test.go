@@ -0,0 +1,73 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectMigrations_LoadsEmbeddedMigrations(t *testing.T) {
+	postgres, err := dialectMigrations("postgres")
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(postgres), 2)
+	for i, migration := range postgres {
+		assert.Equal(t, i+1, migration.Version)
+		assert.NotEmpty(t, migration.Up)
+		assert.NotEmpty(t, migration.Down)
+	}
+
+	sqlite, err := dialectMigrations("sqlite")
+	assert.NoError(t, err)
+	assert.Len(t, sqlite, len(postgres))
+	for i, migration := range sqlite {
+		assert.Equal(t, postgres[i].Name, migration.Name)
+	}
+
+	_, err = dialectMigrations("mysql")
+	assert.Error(t, err)
+}
+
+func TestLoadMigrations_Errors(t *testing.T) {
+	file := &fstest.MapFile{Data: []byte("SELECT 1;")}
+	cases := map[string]fstest.MapFS{
+		"gap": {
+			"0001_a.up.sql": file, "0001_a.down.sql": file,
+			"0003_c.up.sql": file, "0003_c.down.sql": file,
+		},
+		"missing down": {
+			"0001_a.up.sql": file,
+		},
+		"conflicting names": {
+			"0001_a.up.sql": file, "0001_b.down.sql": file,
+		},
+		"stray file": {
+			"0001_a.up.sql": file, "0001_a.down.sql": file, "README.md": file,
+		},
+	}
+	for name, fsys := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadMigrations(fsys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	migrations := []Migration{{Version: 1}, {Version: 2}, {Version: 3}}
+	versions := func(steps []migrationStep) (out []int) {
+		for _, s := range steps {
+			if !s.up {
+				out = append(out, -s.Version)
+			} else {
+				out = append(out, s.Version)
+			}
+		}
+		return out
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, versions(plan(migrations, 0, 3)))
+	assert.Equal(t, []int{2}, versions(plan(migrations, 1, 2)))
+	assert.Equal(t, []int{-3, -2}, versions(plan(migrations, 3, 1)))
+	assert.Empty(t, plan(migrations, 2, 2))
+}
@@ -0,0 +1,3 @@
+DROP TABLE investments;
+DROP TABLE investors;
+DROP TABLE funds;
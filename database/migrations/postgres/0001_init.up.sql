@@ -0,0 +1,39 @@
+-- Baseline schema, equivalent to what GORM's AutoMigrate produced before
+-- versioned migrations were introduced. IF NOT EXISTS lets databases created
+-- by AutoMigrate adopt the migration history without changes.
+CREATE TABLE IF NOT EXISTS funds (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name            text NOT NULL,
+    vintage_year    bigint NOT NULL
+        CONSTRAINT vintage_year_range CHECK (vintage_year >= 1900 AND vintage_year <= 2100),
+    target_size_usd numeric(20,2) NOT NULL DEFAULT 0,
+    status          text NOT NULL
+        CONSTRAINT fund_status_chk CHECK (status IN ('Fundraising','Investing','Closed')),
+    created_at      timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS investors (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          text NOT NULL,
+    investor_type text NOT NULL
+        CONSTRAINT investor_type_chk CHECK (investor_type IN ('Individual','Institution','Family Office')),
+    email         varchar(320) NOT NULL,
+    created_at    timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_investors_email ON investors (email);
+
+CREATE TABLE IF NOT EXISTS investments (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    investor_id     uuid NOT NULL
+        CONSTRAINT fk_investors_investments REFERENCES investors (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    fund_id         uuid NOT NULL
+        CONSTRAINT fk_funds_investments REFERENCES funds (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    amount_usd      numeric(20,2) NOT NULL
+        CONSTRAINT amount_usd_nonneg CHECK (amount_usd >= 0),
+    created_at      timestamptz,
+    investment_date date NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_investments_investor_id ON investments (investor_id);
+CREATE INDEX IF NOT EXISTS idx_investments_fund_id ON investments (fund_id);
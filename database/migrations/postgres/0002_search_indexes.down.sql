@@ -0,0 +1,6 @@
+-- pg_trgm is left installed as other database objects may depend on it.
+DROP INDEX idx_investors_email_trgm;
+DROP INDEX idx_investors_name_tsv;
+DROP INDEX idx_investors_name_trgm;
+DROP INDEX idx_funds_name_tsv;
+DROP INDEX idx_funds_name_trgm;
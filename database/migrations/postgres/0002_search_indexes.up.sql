@@ -0,0 +1,7 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_funds_name_trgm ON funds USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_funds_name_tsv ON funds USING gin (to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS idx_investors_name_trgm ON investors USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_investors_name_tsv ON investors USING gin (to_tsvector('simple', name));
+CREATE INDEX IF NOT EXISTS idx_investors_email_trgm ON investors USING gin (email gin_trgm_ops);
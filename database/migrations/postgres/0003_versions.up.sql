@@ -0,0 +1,4 @@
+-- Row versions for optimistic concurrency: every update increments version,
+-- which is exposed to clients as the ETag.
+ALTER TABLE funds ADD COLUMN version bigint NOT NULL DEFAULT 1;
+ALTER TABLE investors ADD COLUMN version bigint NOT NULL DEFAULT 1;
@@ -0,0 +1,7 @@
+-- Fails if a deleted investor shares its email with another investor.
+DROP INDEX idx_investors_email;
+CREATE UNIQUE INDEX idx_investors_email ON investors (email);
+
+ALTER TABLE investments DROP COLUMN deleted_at, DROP COLUMN deleted_by, DROP COLUMN delete_reason;
+ALTER TABLE investors DROP COLUMN deleted_at, DROP COLUMN deleted_by, DROP COLUMN delete_reason;
+ALTER TABLE funds DROP COLUMN deleted_at, DROP COLUMN deleted_by, DROP COLUMN delete_reason;
@@ -0,0 +1,18 @@
+-- Soft deletion: rows with deleted_at set are hidden from lists and search but
+-- kept, along with who deleted them and why, so that they can be restored.
+ALTER TABLE funds
+    ADD COLUMN deleted_at    timestamptz,
+    ADD COLUMN deleted_by    text NOT NULL DEFAULT '',
+    ADD COLUMN delete_reason text NOT NULL DEFAULT '';
+ALTER TABLE investors
+    ADD COLUMN deleted_at    timestamptz,
+    ADD COLUMN deleted_by    text NOT NULL DEFAULT '',
+    ADD COLUMN delete_reason text NOT NULL DEFAULT '';
+ALTER TABLE investments
+    ADD COLUMN deleted_at    timestamptz,
+    ADD COLUMN deleted_by    text NOT NULL DEFAULT '',
+    ADD COLUMN delete_reason text NOT NULL DEFAULT '';
+
+-- A deleted investor no longer holds on to its email address.
+DROP INDEX idx_investors_email;
+CREATE UNIQUE INDEX idx_investors_email ON investors (email) WHERE deleted_at IS NULL;
@@ -0,0 +1,27 @@
+-- Every create, update, delete and restore appends a row here, in the same
+-- transaction as the change. changes holds the before and after value of
+-- each field that changed. Rows can be added but never updated or deleted.
+CREATE TABLE audit_log (
+    id         bigserial PRIMARY KEY,
+    entity     text NOT NULL
+        CONSTRAINT audit_entity_chk CHECK (entity IN ('fund','investor','investment')),
+    entity_id  uuid NOT NULL,
+    action     text NOT NULL
+        CONSTRAINT audit_action_chk CHECK (action IN ('create','update','delete','restore')),
+    actor      text NOT NULL,
+    request_id text NOT NULL DEFAULT '',
+    changes    jsonb NOT NULL,
+    created_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log (entity, entity_id, created_at, id);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at, id);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END
+$$;
+
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
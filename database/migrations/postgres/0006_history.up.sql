@@ -0,0 +1,76 @@
+-- System-versioned history: every write to funds, investors or investments
+-- closes the row's current version, by setting valid_to, and appends a copy
+-- of the new row, valid from the same instant. The live tables are thus
+-- always equal to the versions whose valid_to is NULL, and as-of queries
+-- read the versions for which valid_from <= t < valid_to.
+--
+-- Columns added to a live table must be added to its history table too.
+CREATE TABLE funds_history (
+    version_id      bigserial PRIMARY KEY,
+    valid_from      timestamptz NOT NULL,
+    valid_to        timestamptz,
+    id              uuid NOT NULL,
+    name            text NOT NULL,
+    vintage_year    bigint NOT NULL,
+    target_size_usd numeric(20,2) NOT NULL,
+    status          text NOT NULL,
+    created_at      timestamptz,
+    version         bigint NOT NULL,
+    deleted_at      timestamptz,
+    deleted_by      text NOT NULL,
+    delete_reason   text NOT NULL
+);
+
+CREATE TABLE investors_history (
+    version_id    bigserial PRIMARY KEY,
+    valid_from    timestamptz NOT NULL,
+    valid_to      timestamptz,
+    id            uuid NOT NULL,
+    name          text NOT NULL,
+    investor_type text NOT NULL,
+    email         varchar(320) NOT NULL,
+    created_at    timestamptz,
+    version       bigint NOT NULL,
+    deleted_at    timestamptz,
+    deleted_by    text NOT NULL,
+    delete_reason text NOT NULL
+);
+
+CREATE TABLE investments_history (
+    version_id      bigserial PRIMARY KEY,
+    valid_from      timestamptz NOT NULL,
+    valid_to        timestamptz,
+    id              uuid NOT NULL,
+    investor_id     uuid NOT NULL,
+    fund_id         uuid NOT NULL,
+    amount_usd      numeric(20,2) NOT NULL,
+    created_at      timestamptz,
+    investment_date date NOT NULL,
+    deleted_at      timestamptz,
+    deleted_by      text NOT NULL,
+    delete_reason   text NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_funds_history_current ON funds_history (id) WHERE valid_to IS NULL;
+CREATE INDEX idx_funds_history_id ON funds_history (id, valid_from);
+CREATE INDEX idx_funds_history_valid ON funds_history (valid_from, valid_to);
+CREATE UNIQUE INDEX idx_investors_history_current ON investors_history (id) WHERE valid_to IS NULL;
+CREATE INDEX idx_investors_history_id ON investors_history (id, valid_from);
+CREATE INDEX idx_investors_history_valid ON investors_history (valid_from, valid_to);
+CREATE UNIQUE INDEX idx_investments_history_current ON investments_history (id) WHERE valid_to IS NULL;
+CREATE INDEX idx_investments_history_fund_id ON investments_history (fund_id, valid_from);
+CREATE INDEX idx_investments_history_valid ON investments_history (valid_from, valid_to);
+
+-- Earlier changes were not versioned, so existing rows are taken to have
+-- looked as they do now since they were created.
+INSERT INTO funds_history (valid_from, id, name, vintage_year, target_size_usd, status, created_at, version, deleted_at, deleted_by, delete_reason)
+SELECT COALESCE(created_at, now()), id, name, vintage_year, target_size_usd, status, created_at, version, deleted_at, deleted_by, delete_reason
+FROM funds;
+
+INSERT INTO investors_history (valid_from, id, name, investor_type, email, created_at, version, deleted_at, deleted_by, delete_reason)
+SELECT COALESCE(created_at, now()), id, name, investor_type, email, created_at, version, deleted_at, deleted_by, delete_reason
+FROM investors;
+
+INSERT INTO investments_history (valid_from, id, investor_id, fund_id, amount_usd, created_at, investment_date, deleted_at, deleted_by, delete_reason)
+SELECT COALESCE(created_at, now()), id, investor_id, fund_id, amount_usd, created_at, investment_date, deleted_at, deleted_by, delete_reason
+FROM investments;
@@ -0,0 +1,38 @@
+-- The event store: every change to a fund, investor or investment, in the
+-- order it was made. The live and history tables are projections of it
+-- that `go run . replay` can rebuild. Like audit_log, it is append-only.
+CREATE TABLE events (
+    seq         bigserial PRIMARY KEY,
+    stream      text NOT NULL
+        CONSTRAINT events_stream_chk CHECK (stream IN ('fund','investor','investment')),
+    stream_id   uuid NOT NULL,
+    type        text NOT NULL,
+    data        jsonb NOT NULL,
+    occurred_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_events_stream ON events (stream_id, seq);
+
+CREATE FUNCTION events_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'events is append-only';
+END
+$$;
+
+CREATE TRIGGER events_append_only BEFORE UPDATE OR DELETE ON events
+    FOR EACH ROW EXECUTE FUNCTION events_append_only();
+
+-- Rows written before the event store existed are imported as snapshots of
+-- their current state, parents first, so that replaying them satisfies the
+-- foreign keys.
+INSERT INTO events (stream, stream_id, type, data, occurred_at)
+SELECT 'fund', id, 'FundImported', to_jsonb(f), COALESCE(created_at, now())
+FROM funds f ORDER BY created_at, id;
+
+INSERT INTO events (stream, stream_id, type, data, occurred_at)
+SELECT 'investor', id, 'InvestorImported', to_jsonb(i), COALESCE(created_at, now())
+FROM investors i ORDER BY created_at, id;
+
+INSERT INTO events (stream, stream_id, type, data, occurred_at)
+SELECT 'investment', id, 'CommitmentImported', to_jsonb(i), COALESCE(created_at, now())
+FROM investments i ORDER BY created_at, id;
@@ -0,0 +1,17 @@
+-- Transactional outbox: every event is queued here in the transaction that
+-- appends it, and the relay in package outbox publishes it afterwards.
+-- Published messages are kept for a while and then deleted by the relay.
+CREATE TABLE outbox (
+    id              bigserial PRIMARY KEY,
+    topic           text NOT NULL,
+    partition_key   text NOT NULL,
+    payload         jsonb NOT NULL,
+    created_at      timestamptz NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL,
+    published_at    timestamptz,
+    last_error      text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (next_attempt_at, id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published ON outbox (published_at) WHERE published_at IS NOT NULL;
@@ -0,0 +1,34 @@
+-- Webhook subscriptions and their delivery log. Every write queues a
+-- delivery for each enabled webhook subscribed to its event, in the same
+-- transaction, and the dispatcher in package webhooks sends them.
+CREATE TABLE webhooks (
+    id                   uuid PRIMARY KEY,
+    url                  text NOT NULL,
+    event_types          jsonb NOT NULL,
+    secret               text NOT NULL,
+    enabled              boolean NOT NULL,
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    disabled_reason      text NOT NULL DEFAULT '',
+    created_at           timestamptz NOT NULL,
+    version              integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE webhook_deliveries (
+    id               bigserial PRIMARY KEY,
+    webhook_id       uuid NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_seq        bigint NOT NULL,
+    event_type       text NOT NULL,
+    payload          jsonb NOT NULL,
+    status           text NOT NULL,
+    attempts         integer NOT NULL DEFAULT 0,
+    next_attempt_at  timestamptz,
+    last_status_code integer NOT NULL DEFAULT 0,
+    last_error       text NOT NULL DEFAULT '',
+    replay_of        bigint REFERENCES webhook_deliveries (id) ON DELETE SET NULL,
+    created_at       timestamptz NOT NULL,
+    delivered_at     timestamptz,
+    CONSTRAINT webhook_deliveries_status_chk CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at, id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at, id) WHERE status = 'pending';
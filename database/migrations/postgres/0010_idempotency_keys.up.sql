@@ -0,0 +1,14 @@
+-- Responses to POSTs made with an Idempotency-Key header, replayed when the
+-- request is retried. status_code is 0 while the first request is still in
+-- progress. Rows are deleted once they expire.
+CREATE TABLE idempotency_keys (
+    idempotency_key text PRIMARY KEY,
+    request_hash    text NOT NULL,
+    status_code     integer NOT NULL DEFAULT 0,
+    response_header jsonb,
+    response_body   bytea,
+    created_at      timestamptz NOT NULL,
+    expires_at      timestamptz NOT NULL
+);
+
+CREATE INDEX idx_idempotency_keys_expires ON idempotency_keys (expires_at);
@@ -0,0 +1,18 @@
+-- Keys that machine clients authenticate with. Only a SHA-256 hash of each
+-- key is stored; prefix is its first few characters, so that a key can be
+-- recognised. Revoked keys are kept for the record.
+CREATE TABLE api_keys (
+    id           uuid PRIMARY KEY,
+    name         text NOT NULL,
+    prefix       text NOT NULL,
+    hash         text NOT NULL,
+    scopes       jsonb NOT NULL,
+    created_by   text NOT NULL,
+    created_at   timestamptz NOT NULL,
+    expires_at   timestamptz,
+    last_used_at timestamptz,
+    rotated_at   timestamptz,
+    revoked_at   timestamptz
+);
+
+CREATE UNIQUE INDEX idx_api_keys_hash ON api_keys (hash);
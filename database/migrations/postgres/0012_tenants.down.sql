@@ -0,0 +1,20 @@
+-- Fails if live investors of different tenants share an email address.
+DROP INDEX idx_investors_email;
+CREATE UNIQUE INDEX idx_investors_email ON investors (email) WHERE deleted_at IS NULL;
+
+DROP INDEX idx_api_keys_tenant;
+DROP INDEX idx_webhooks_tenant;
+DROP INDEX idx_audit_log_tenant;
+DROP INDEX idx_funds_tenant;
+DROP INDEX idx_investors_tenant;
+DROP INDEX idx_investments_tenant;
+
+ALTER TABLE api_keys DROP COLUMN tenant_id;
+ALTER TABLE webhooks DROP COLUMN tenant_id;
+ALTER TABLE audit_log DROP COLUMN tenant_id;
+ALTER TABLE investments_history DROP COLUMN tenant_id;
+ALTER TABLE investors_history DROP COLUMN tenant_id;
+ALTER TABLE funds_history DROP COLUMN tenant_id;
+ALTER TABLE investments DROP COLUMN tenant_id;
+ALTER TABLE investors DROP COLUMN tenant_id;
+ALTER TABLE funds DROP COLUMN tenant_id;
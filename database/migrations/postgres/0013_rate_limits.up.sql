@@ -0,0 +1,10 @@
+-- Token buckets shared by every replica, one per client and limit. A bucket
+-- is full again at full_at, after which its row can be deleted.
+CREATE TABLE rate_limits (
+    bucket_key text PRIMARY KEY,
+    tokens     double precision NOT NULL,
+    updated_at timestamptz NOT NULL,
+    full_at    timestamptz NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_full ON rate_limits (full_at);
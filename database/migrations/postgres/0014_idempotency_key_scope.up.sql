@@ -0,0 +1,20 @@
+-- Idempotency keys belong to the caller that sent them, in the tenant it
+-- acted for, so that callers choosing the same key do not collide. Stored
+-- keys are dropped: they only cache responses for retries, and a retry
+-- after the migration runs as a new request.
+DROP TABLE idempotency_keys;
+
+CREATE TABLE idempotency_keys (
+    tenant_id       text NOT NULL,
+    subject         text NOT NULL,
+    idempotency_key text NOT NULL,
+    request_hash    text NOT NULL,
+    status_code     integer NOT NULL DEFAULT 0,
+    response_header jsonb,
+    response_body   bytea,
+    created_at      timestamptz NOT NULL,
+    expires_at      timestamptz NOT NULL,
+    PRIMARY KEY (tenant_id, subject, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires ON idempotency_keys (expires_at);
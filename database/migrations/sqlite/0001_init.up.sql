@@ -0,0 +1,42 @@
+-- SQLite equivalent of postgres/0001_init. UUIDs are stored as text and are
+-- always generated by the application. investment_date is text rather than
+-- date so that it reads back as 'yyyy-mm-dd', and is checked to be a real
+-- calendar date instead; the '+0 days' modifier makes date() normalise
+-- impossible days such as 2024-02-30 rather than echo them back.
+CREATE TABLE funds (
+    id              text PRIMARY KEY,
+    name            text NOT NULL,
+    vintage_year    integer NOT NULL
+        CONSTRAINT vintage_year_range CHECK (vintage_year >= 1900 AND vintage_year <= 2100),
+    target_size_usd numeric NOT NULL DEFAULT 0,
+    status          text NOT NULL
+        CONSTRAINT fund_status_chk CHECK (status IN ('Fundraising','Investing','Closed')),
+    created_at      datetime
+);
+
+CREATE TABLE investors (
+    id            text PRIMARY KEY,
+    name          text NOT NULL,
+    investor_type text NOT NULL
+        CONSTRAINT investor_type_chk CHECK (investor_type IN ('Individual','Institution','Family Office')),
+    email         varchar(320) NOT NULL,
+    created_at    datetime
+);
+
+CREATE UNIQUE INDEX idx_investors_email ON investors (email);
+
+CREATE TABLE investments (
+    id              text PRIMARY KEY,
+    investor_id     text NOT NULL
+        CONSTRAINT fk_investors_investments REFERENCES investors (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    fund_id         text NOT NULL
+        CONSTRAINT fk_funds_investments REFERENCES funds (id) ON UPDATE CASCADE ON DELETE RESTRICT,
+    amount_usd      numeric NOT NULL
+        CONSTRAINT amount_usd_nonneg CHECK (amount_usd >= 0),
+    created_at      datetime,
+    investment_date text NOT NULL
+        CONSTRAINT investment_date_valid CHECK (investment_date = date(investment_date, '+0 days'))
+);
+
+CREATE INDEX idx_investments_investor_id ON investments (investor_id);
+CREATE INDEX idx_investments_fund_id ON investments (fund_id);
@@ -0,0 +1 @@
+-- Intentionally empty, see 0002_search_indexes.up.sql.
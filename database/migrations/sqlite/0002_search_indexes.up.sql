@@ -0,0 +1,3 @@
+-- Intentionally empty. SQLite has no trigram or full-text indexes matching
+-- the PostgreSQL ones, so SQLiteDB.Search scores rows in Go instead. The
+-- version exists to keep schema_migrations aligned across dialects.
@@ -0,0 +1,2 @@
+ALTER TABLE investors DROP COLUMN version;
+ALTER TABLE funds DROP COLUMN version;
@@ -0,0 +1,13 @@
+-- Fails if a deleted investor shares its email with another investor.
+DROP INDEX idx_investors_email;
+CREATE UNIQUE INDEX idx_investors_email ON investors (email);
+
+ALTER TABLE investments DROP COLUMN delete_reason;
+ALTER TABLE investments DROP COLUMN deleted_by;
+ALTER TABLE investments DROP COLUMN deleted_at;
+ALTER TABLE investors DROP COLUMN delete_reason;
+ALTER TABLE investors DROP COLUMN deleted_by;
+ALTER TABLE investors DROP COLUMN deleted_at;
+ALTER TABLE funds DROP COLUMN delete_reason;
+ALTER TABLE funds DROP COLUMN deleted_by;
+ALTER TABLE funds DROP COLUMN deleted_at;
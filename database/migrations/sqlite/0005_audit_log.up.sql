@@ -0,0 +1,27 @@
+-- SQLite equivalent of postgres/0005_audit_log, with changes stored as JSON
+-- text.
+CREATE TABLE audit_log (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    entity     text NOT NULL
+        CONSTRAINT audit_entity_chk CHECK (entity IN ('fund','investor','investment')),
+    entity_id  text NOT NULL,
+    action     text NOT NULL
+        CONSTRAINT audit_action_chk CHECK (action IN ('create','update','delete','restore')),
+    actor      text NOT NULL,
+    request_id text NOT NULL DEFAULT '',
+    changes    text NOT NULL,
+    created_at datetime NOT NULL
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log (entity, entity_id, created_at, id);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at, id);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
@@ -0,0 +1,3 @@
+DROP TABLE investments_history;
+DROP TABLE investors_history;
+DROP TABLE funds_history;
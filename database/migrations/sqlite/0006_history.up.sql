@@ -0,0 +1,69 @@
+-- SQLite equivalent of postgres/0006_history, which describes how the
+-- history tables are maintained.
+CREATE TABLE funds_history (
+    version_id      integer PRIMARY KEY AUTOINCREMENT,
+    valid_from      datetime NOT NULL,
+    valid_to        datetime,
+    id              text NOT NULL,
+    name            text NOT NULL,
+    vintage_year    integer NOT NULL,
+    target_size_usd numeric NOT NULL,
+    status          text NOT NULL,
+    created_at      datetime,
+    version         integer NOT NULL,
+    deleted_at      datetime,
+    deleted_by      text NOT NULL,
+    delete_reason   text NOT NULL
+);
+
+CREATE TABLE investors_history (
+    version_id    integer PRIMARY KEY AUTOINCREMENT,
+    valid_from    datetime NOT NULL,
+    valid_to      datetime,
+    id            text NOT NULL,
+    name          text NOT NULL,
+    investor_type text NOT NULL,
+    email         varchar(320) NOT NULL,
+    created_at    datetime,
+    version       integer NOT NULL,
+    deleted_at    datetime,
+    deleted_by    text NOT NULL,
+    delete_reason text NOT NULL
+);
+
+CREATE TABLE investments_history (
+    version_id      integer PRIMARY KEY AUTOINCREMENT,
+    valid_from      datetime NOT NULL,
+    valid_to        datetime,
+    id              text NOT NULL,
+    investor_id     text NOT NULL,
+    fund_id         text NOT NULL,
+    amount_usd      numeric NOT NULL,
+    created_at      datetime,
+    investment_date text NOT NULL,
+    deleted_at      datetime,
+    deleted_by      text NOT NULL,
+    delete_reason   text NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_funds_history_current ON funds_history (id) WHERE valid_to IS NULL;
+CREATE INDEX idx_funds_history_id ON funds_history (id, valid_from);
+CREATE INDEX idx_funds_history_valid ON funds_history (valid_from, valid_to);
+CREATE UNIQUE INDEX idx_investors_history_current ON investors_history (id) WHERE valid_to IS NULL;
+CREATE INDEX idx_investors_history_id ON investors_history (id, valid_from);
+CREATE INDEX idx_investors_history_valid ON investors_history (valid_from, valid_to);
+CREATE UNIQUE INDEX idx_investments_history_current ON investments_history (id) WHERE valid_to IS NULL;
+CREATE INDEX idx_investments_history_fund_id ON investments_history (fund_id, valid_from);
+CREATE INDEX idx_investments_history_valid ON investments_history (valid_from, valid_to);
+
+INSERT INTO funds_history (valid_from, id, name, vintage_year, target_size_usd, status, created_at, version, deleted_at, deleted_by, delete_reason)
+SELECT COALESCE(created_at, CURRENT_TIMESTAMP), id, name, vintage_year, target_size_usd, status, created_at, version, deleted_at, deleted_by, delete_reason
+FROM funds;
+
+INSERT INTO investors_history (valid_from, id, name, investor_type, email, created_at, version, deleted_at, deleted_by, delete_reason)
+SELECT COALESCE(created_at, CURRENT_TIMESTAMP), id, name, investor_type, email, created_at, version, deleted_at, deleted_by, delete_reason
+FROM investors;
+
+INSERT INTO investments_history (valid_from, id, investor_id, fund_id, amount_usd, created_at, investment_date, deleted_at, deleted_by, delete_reason)
+SELECT COALESCE(created_at, CURRENT_TIMESTAMP), id, investor_id, fund_id, amount_usd, created_at, investment_date, deleted_at, deleted_by, delete_reason
+FROM investments;
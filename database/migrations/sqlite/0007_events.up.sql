@@ -0,0 +1,48 @@
+-- SQLite equivalent of postgres/0007_events, with data stored as JSON text.
+-- Timestamps are stored as '2006-01-02 15:04:05.999999999-07:00', which
+-- becomes RFC 3339 once the space is replaced.
+CREATE TABLE events (
+    seq         integer PRIMARY KEY AUTOINCREMENT,
+    stream      text NOT NULL
+        CONSTRAINT events_stream_chk CHECK (stream IN ('fund','investor','investment')),
+    stream_id   text NOT NULL,
+    type        text NOT NULL,
+    data        text NOT NULL,
+    occurred_at datetime NOT NULL
+);
+
+CREATE INDEX idx_events_stream ON events (stream_id, seq);
+
+CREATE TRIGGER events_no_update BEFORE UPDATE ON events
+BEGIN
+    SELECT RAISE(ABORT, 'events is append-only');
+END;
+
+CREATE TRIGGER events_no_delete BEFORE DELETE ON events
+BEGIN
+    SELECT RAISE(ABORT, 'events is append-only');
+END;
+
+INSERT INTO events (stream, stream_id, type, data, occurred_at)
+SELECT 'fund', id, 'FundImported', json_object(
+    'id', id, 'name', name, 'vintage_year', vintage_year, 'target_size_usd', target_size_usd,
+    'status', status, 'created_at', replace(created_at, ' ', 'T'), 'version', version,
+    'deleted_at', replace(deleted_at, ' ', 'T'), 'deleted_by', deleted_by, 'delete_reason', delete_reason
+), COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM funds ORDER BY created_at, id;
+
+INSERT INTO events (stream, stream_id, type, data, occurred_at)
+SELECT 'investor', id, 'InvestorImported', json_object(
+    'id', id, 'name', name, 'investor_type', investor_type, 'email', email,
+    'created_at', replace(created_at, ' ', 'T'), 'version', version,
+    'deleted_at', replace(deleted_at, ' ', 'T'), 'deleted_by', deleted_by, 'delete_reason', delete_reason
+), COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM investors ORDER BY created_at, id;
+
+INSERT INTO events (stream, stream_id, type, data, occurred_at)
+SELECT 'investment', id, 'CommitmentImported', json_object(
+    'id', id, 'investor_id', investor_id, 'fund_id', fund_id, 'amount_usd', amount_usd,
+    'created_at', replace(created_at, ' ', 'T'), 'investment_date', investment_date,
+    'deleted_at', replace(deleted_at, ' ', 'T'), 'deleted_by', deleted_by, 'delete_reason', delete_reason
+), COALESCE(created_at, CURRENT_TIMESTAMP)
+FROM investments ORDER BY created_at, id;
@@ -0,0 +1,16 @@
+-- SQLite equivalent of postgres/0008_outbox, with payloads stored as JSON
+-- text.
+CREATE TABLE outbox (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    topic           text NOT NULL,
+    partition_key   text NOT NULL,
+    payload         text NOT NULL,
+    created_at      datetime NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at datetime NOT NULL,
+    published_at    datetime,
+    last_error      text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (next_attempt_at, id) WHERE published_at IS NULL;
+CREATE INDEX idx_outbox_published ON outbox (published_at) WHERE published_at IS NOT NULL;
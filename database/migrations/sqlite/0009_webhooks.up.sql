@@ -0,0 +1,33 @@
+-- SQLite equivalent of postgres/0009_webhooks, with event types and
+-- payloads stored as JSON text.
+CREATE TABLE webhooks (
+    id                   text PRIMARY KEY,
+    url                  text NOT NULL,
+    event_types          text NOT NULL,
+    secret               text NOT NULL,
+    enabled              boolean NOT NULL,
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    disabled_reason      text NOT NULL DEFAULT '',
+    created_at           datetime NOT NULL,
+    version              integer NOT NULL DEFAULT 1
+);
+
+CREATE TABLE webhook_deliveries (
+    id               integer PRIMARY KEY AUTOINCREMENT,
+    webhook_id       text NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_seq        bigint NOT NULL,
+    event_type       text NOT NULL,
+    payload          text NOT NULL,
+    status           text NOT NULL,
+    attempts         integer NOT NULL DEFAULT 0,
+    next_attempt_at  datetime,
+    last_status_code integer NOT NULL DEFAULT 0,
+    last_error       text NOT NULL DEFAULT '',
+    replay_of        integer REFERENCES webhook_deliveries (id) ON DELETE SET NULL,
+    created_at       datetime NOT NULL,
+    delivered_at     datetime,
+    CONSTRAINT webhook_deliveries_status_chk CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries (webhook_id, created_at, id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at, id) WHERE status = 'pending';
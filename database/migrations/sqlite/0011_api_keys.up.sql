@@ -0,0 +1,16 @@
+-- SQLite equivalent of postgres/0011_api_keys.
+CREATE TABLE api_keys (
+    id           text PRIMARY KEY,
+    name         text NOT NULL,
+    prefix       text NOT NULL,
+    hash         text NOT NULL,
+    scopes       text NOT NULL,
+    created_by   text NOT NULL,
+    created_at   datetime NOT NULL,
+    expires_at   datetime,
+    last_used_at datetime,
+    rotated_at   datetime,
+    revoked_at   datetime
+);
+
+CREATE UNIQUE INDEX idx_api_keys_hash ON api_keys (hash);
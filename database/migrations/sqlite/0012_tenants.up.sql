@@ -0,0 +1,20 @@
+-- SQLite equivalent of postgres/0012_tenants.
+ALTER TABLE funds ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE investors ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE investments ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE funds_history ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE investors_history ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE investments_history ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE audit_log ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN tenant_id text NOT NULL DEFAULT 'default';
+
+CREATE INDEX idx_funds_tenant ON funds (tenant_id);
+CREATE INDEX idx_investors_tenant ON investors (tenant_id);
+CREATE INDEX idx_investments_tenant ON investments (tenant_id);
+CREATE INDEX idx_audit_log_tenant ON audit_log (tenant_id);
+CREATE INDEX idx_webhooks_tenant ON webhooks (tenant_id);
+CREATE INDEX idx_api_keys_tenant ON api_keys (tenant_id);
+
+DROP INDEX idx_investors_email;
+CREATE UNIQUE INDEX idx_investors_email ON investors (tenant_id, email) WHERE deleted_at IS NULL;
@@ -0,0 +1,9 @@
+-- SQLite equivalent of postgres/0013_rate_limits.
+CREATE TABLE rate_limits (
+    bucket_key text PRIMARY KEY,
+    tokens     real NOT NULL,
+    updated_at datetime NOT NULL,
+    full_at    datetime NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_full ON rate_limits (full_at);
@@ -0,0 +1,17 @@
+-- SQLite equivalent of postgres/0014_idempotency_key_scope.
+DROP TABLE idempotency_keys;
+
+CREATE TABLE idempotency_keys (
+    tenant_id       text NOT NULL,
+    subject         text NOT NULL,
+    idempotency_key text NOT NULL,
+    request_hash    text NOT NULL,
+    status_code     integer NOT NULL DEFAULT 0,
+    response_header text,
+    response_body   blob,
+    created_at      datetime NOT NULL,
+    expires_at      datetime NOT NULL,
+    PRIMARY KEY (tenant_id, subject, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires ON idempotency_keys (expires_at);
@@ -1,45 +1,74 @@
 package database
 
 import (
-	"errors"
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/iuhmirza/titanbay-take-home/database/events"
 	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/shopspring/decimal"
 )
 
-var (
-	errFundNotFound     = errors.New("fund not found")
-	errInvestorNotFound = errors.New("investor not found")
-)
-
+// MockDb is an in-memory Db. It enforces the same constraints as the SQL
+// schema, and no more: validating requests is the handlers' job.
+// investorEmailIndex only holds live investors, like the partial unique
+// index it mirrors. The history maps hold every version of each row, oldest
+// first, like the history tables. As in the SQL backends, the maps are a
+// projection of eventStore, which writes append to. Webhook deliveries are
+// numbered from lastDeliveryID, like an identity column.
 type MockDb struct {
 	funds       map[uuid.UUID]models.Fund
 	investors   map[uuid.UUID]models.Investor
 	investments map[uuid.UUID]models.Investment
 	investorEmailIndex map[string]uuid.UUID
+	auditLog           []models.AuditEntry
+	fundHistory        map[uuid.UUID][]mockVersion[models.Fund]
+	investorHistory    map[uuid.UUID][]mockVersion[models.Investor]
+	investmentHistory  map[uuid.UUID][]mockVersion[models.Investment]
+	eventStore         *events.MemoryStore
+	webhooks           map[uuid.UUID]models.Webhook
+	webhookDeliveries  map[int]models.WebhookDelivery
+	lastDeliveryID     int
+	idempotencyKeys    map[idempotencyKeyID]models.IdempotencyKey
+	apiKeys            map[uuid.UUID]models.APIKey
 	mu                 sync.RWMutex
 }
 
+type mockVersion[T any] struct {
+	validFrom time.Time
+	row       T
+}
+
 func NewMockDb() *MockDb {
 	return &MockDb{
 		funds:              make(map[uuid.UUID]models.Fund),
 		investors:          make(map[uuid.UUID]models.Investor),
 		investments:        make(map[uuid.UUID]models.Investment),
 		investorEmailIndex: make(map[string]uuid.UUID),
+		fundHistory:        make(map[uuid.UUID][]mockVersion[models.Fund]),
+		investorHistory:    make(map[uuid.UUID][]mockVersion[models.Investor]),
+		investmentHistory:  make(map[uuid.UUID][]mockVersion[models.Investment]),
+		eventStore:         events.NewMemoryStore(),
+		webhooks:           make(map[uuid.UUID]models.Webhook),
+		webhookDeliveries:  make(map[int]models.WebhookDelivery),
+		idempotencyKeys:    make(map[idempotencyKeyID]models.IdempotencyKey),
+		apiKeys:            make(map[uuid.UUID]models.APIKey),
 	}
 }
 
-func (db *MockDb) CreateFund(createFund models.CreateFund) (models.Fund, error) {
-	// Simulate DB constraints by validating DTO
-	if err := createFund.Validate(); err != nil {
+func (db *MockDb) CreateFund(ctx context.Context, createFund models.CreateFund) (models.Fund, error) {
+	if err := ctx.Err(); err != nil {
 		return models.Fund{}, err
 	}
 
-	db.mu.Lock()
-	defer db.mu.Unlock()
-
 	id := uuid.New()
 	now := time.Now().UTC()
 
@@ -50,61 +79,166 @@ func (db *MockDb) CreateFund(createFund models.CreateFund) (models.Fund, error)
 		TargetSizeUsd: createFund.TargetSizeUsd,
 		Status:        createFund.Status,
 		CreatedAt:     now,
+		Version:       1,
+		Tenancy:       models.Tenancy{TenantID: tenantOf(ctx)},
 	}
+	if err := checkFund(fund); err != nil {
+		return models.Fund{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
-	db.funds[id] = fund
+	if err := db.emit(ctx, events.FundCreated, id, nil, fund); err != nil {
+		return models.Fund{}, err
+	}
 	return fund, nil
 }
 
-func (db *MockDb) ReadFunds() ([]models.Fund, error) {
+func (db *MockDb) ReadFunds(ctx context.Context, query models.FundQuery) (models.Page[models.Fund], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.Fund]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Fund]{}, err
+	}
+
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
+	scope := access.FromContext(ctx)
 	funds := make([]models.Fund, 0, len(db.funds))
 	for _, f := range db.funds {
+		if !db.fundVisible(scope, f.ID) {
+			continue
+		}
+		if query.Status != "" && f.Status != query.Status {
+			continue
+		}
+		if query.VintageYearMin != nil && f.VintageYear < *query.VintageYearMin {
+			continue
+		}
+		if query.VintageYearMax != nil && f.VintageYear > *query.VintageYearMax {
+			continue
+		}
+		if f.Deleted() && !query.IncludeDeleted {
+			continue
+		}
 		funds = append(funds, f)
 	}
-	return funds, nil
+	return paginateSlice(funds, query.PageQuery)
 }
 
-func (db *MockDb) ReadFundByID(id uuid.UUID) (models.Fund, error) {
+func (db *MockDb) ReadFundByID(ctx context.Context, id uuid.UUID) (models.Fund, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Fund{}, err
+	}
+
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
 	fund, ok := db.funds[id]
-	if !ok {
-		return models.Fund{}, errFundNotFound
+	if !ok || !db.fundVisible(access.FromContext(ctx), id) {
+		return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
 	}
 	return fund, nil
 }
 
-func (db *MockDb) UpdateFund(fund models.Fund) (models.Fund, error) {
+func (db *MockDb) UpdateFund(ctx context.Context, fund models.Fund) (models.Fund, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Fund{}, err
+	}
+	if err := checkWriteScope(ctx, "fund", fund.ID); err != nil {
+		return models.Fund{}, err
+	}
+
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
 	existing, ok := db.funds[fund.ID]
-	if !ok {
-		return models.Fund{}, errFundNotFound
+	if !ok || existing.Deleted() || existing.TenantID != tenantOf(ctx) {
+		return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
 	}
 
+	if fund.Version != 0 && fund.Version != existing.Version {
+		return models.Fund{}, &dberr.Error{Kind: dberr.VersionMismatch, Entity: "fund"}
+	}
+	if err := checkFund(fund); err != nil {
+		return models.Fund{}, err
+	}
 	if fund.CreatedAt.IsZero() {
 		fund.CreatedAt = existing.CreatedAt
 	}
+	fund.Version = existing.Version + 1
+	fund.Tenancy = existing.Tenancy
+	fund.Deletion = existing.Deletion
 
-	db.funds[fund.ID] = fund
+	if err := db.emit(ctx, events.FundUpdated, fund.ID, existing, fund); err != nil {
+		return models.Fund{}, err
+	}
 	return fund, nil
 }
 
-func (db *MockDb) CreateInvestor(createInvestor models.CreateInvestor) (models.Investor, error) {
-	if err := createInvestor.Validate(); err != nil {
-		return models.Investor{}, err
+func (db *MockDb) DeleteFund(ctx context.Context, id uuid.UUID, del models.DeleteResource) (models.Fund, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Fund{}, err
+	}
+	if err := checkWriteScope(ctx, "fund", id); err != nil {
+		return models.Fund{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	fund, ok := db.funds[id]
+	if !ok || fund.Deleted() || fund.TenantID != tenantOf(ctx) {
+		return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
+	}
+	if db.hasLiveInvestments(func(inv models.Investment) bool { return inv.FundID == id }) {
+		return models.Fund{}, &dberr.Error{Kind: dberr.InUse, Entity: "fund"}
+	}
+	before := fund
+	fund.Deletion = newMockDeletion(ctx, del)
+	fund.Version++
+
+	if err := db.emit(ctx, events.FundDeleted, id, before, fund); err != nil {
+		return models.Fund{}, err
+	}
+	return fund, nil
+}
+
+func (db *MockDb) RestoreFund(ctx context.Context, id uuid.UUID) (models.Fund, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Fund{}, err
+	}
+	if err := checkWriteScope(ctx, "fund", id); err != nil {
+		return models.Fund{}, err
 	}
 
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	if _, taken := db.investorEmailIndex[createInvestor.Email]; taken {
-		return models.Investor{}, errors.New("email already exists")
+	fund, ok := db.funds[id]
+	if !ok || fund.TenantID != tenantOf(ctx) {
+		return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
+	}
+	if !fund.Deleted() {
+		return fund, nil
+	}
+	before := fund
+	fund.Deletion = models.Deletion{}
+	fund.Version++
+
+	if err := db.emit(ctx, events.FundRestored, id, before, fund); err != nil {
+		return models.Fund{}, err
+	}
+	return fund, nil
+}
+
+func (db *MockDb) CreateInvestor(ctx context.Context, createInvestor models.CreateInvestor) (models.Investor, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investor{}, err
 	}
 
 	id := uuid.New()
@@ -116,38 +250,187 @@ func (db *MockDb) CreateInvestor(createInvestor models.CreateInvestor) (models.I
 		InvestorType: createInvestor.InvestorType,
 		Email:        createInvestor.Email,
 		CreatedAt:    now,
+		Version:      1,
+		Tenancy:      models.Tenancy{TenantID: tenantOf(ctx)},
 	}
+	if !slices.Contains(investorTypes, investor.InvestorType) {
+		return models.Investor{}, &dberr.Error{Kind: dberr.CheckViolation, Entity: "investor", Field: "investor_type"}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
 
-	db.investors[id] = investor
-	db.investorEmailIndex[investor.Email] = id
+	if _, taken := db.investorEmailIndex[emailKey(investor)]; taken {
+		return models.Investor{}, &dberr.Error{Kind: dberr.Conflict, Entity: "investor", Field: "email"}
+	}
 
+	if err := db.emit(ctx, events.InvestorRegistered, id, nil, investor); err != nil {
+		return models.Investor{}, err
+	}
 	return investor, nil
 }
 
-func (db *MockDb) ReadInvestors() ([]models.Investor, error) {
+func (db *MockDb) ReadInvestors(ctx context.Context, query models.InvestorQuery) (models.Page[models.Investor], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.Investor]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Investor]{}, err
+	}
+
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
+	scope := access.FromContext(ctx)
 	investors := make([]models.Investor, 0, len(db.investors))
 	for _, inv := range db.investors {
+		if !db.investorVisible(scope, inv.ID) {
+			continue
+		}
+		if query.InvestorType != "" && inv.InvestorType != query.InvestorType {
+			continue
+		}
+		if inv.Deleted() && !query.IncludeDeleted {
+			continue
+		}
 		investors = append(investors, inv)
 	}
-	return investors, nil
+	return paginateSlice(investors, query.PageQuery)
 }
 
-func (db *MockDb) CreateInvestment(createInvestment models.CreateInvestment) (models.Investment, error) {
-	if err := createInvestment.Validate(); err != nil {
-		return models.Investment{}, err
+func (db *MockDb) ReadInvestorByID(ctx context.Context, id uuid.UUID) (models.Investor, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investor{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	investor, ok := db.investors[id]
+	if !ok || !db.investorVisible(access.FromContext(ctx), id) {
+		return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+	}
+	return investor, nil
+}
+
+func (db *MockDb) UpdateInvestor(ctx context.Context, investor models.Investor) (models.Investor, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investor{}, err
+	}
+	if err := checkWriteScope(ctx, "investor", investor.ID); err != nil {
+		return models.Investor{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.investors[investor.ID]
+	if !ok || existing.Deleted() || existing.TenantID != tenantOf(ctx) {
+		return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+	}
+	if investor.Version != 0 && investor.Version != existing.Version {
+		return models.Investor{}, &dberr.Error{Kind: dberr.VersionMismatch, Entity: "investor"}
+	}
+	if !slices.Contains(investorTypes, investor.InvestorType) {
+		return models.Investor{}, &dberr.Error{Kind: dberr.CheckViolation, Entity: "investor", Field: "investor_type"}
+	}
+	if id, taken := db.investorEmailIndex[emailKey(models.Investor{Email: investor.Email, Tenancy: existing.Tenancy})]; taken && id != investor.ID {
+		return models.Investor{}, &dberr.Error{Kind: dberr.Conflict, Entity: "investor", Field: "email"}
+	}
+	if investor.CreatedAt.IsZero() {
+		investor.CreatedAt = existing.CreatedAt
+	}
+	investor.Version = existing.Version + 1
+	investor.Tenancy = existing.Tenancy
+	investor.Deletion = existing.Deletion
+
+	if err := db.emit(ctx, events.InvestorUpdated, investor.ID, existing, investor); err != nil {
+		return models.Investor{}, err
+	}
+	return investor, nil
+}
+
+func (db *MockDb) DeleteInvestor(ctx context.Context, id uuid.UUID, del models.DeleteResource) (models.Investor, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investor{}, err
+	}
+	if err := checkWriteScope(ctx, "investor", id); err != nil {
+		return models.Investor{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	investor, ok := db.investors[id]
+	if !ok || investor.Deleted() || investor.TenantID != tenantOf(ctx) {
+		return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+	}
+	if db.hasLiveInvestments(func(inv models.Investment) bool { return inv.InvestorID == id }) {
+		return models.Investor{}, &dberr.Error{Kind: dberr.InUse, Entity: "investor"}
+	}
+	before := investor
+	investor.Deletion = newMockDeletion(ctx, del)
+	investor.Version++
+
+	if err := db.emit(ctx, events.InvestorDeleted, id, before, investor); err != nil {
+		return models.Investor{}, err
+	}
+	return investor, nil
+}
+
+func (db *MockDb) RestoreInvestor(ctx context.Context, id uuid.UUID) (models.Investor, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investor{}, err
+	}
+	if err := checkWriteScope(ctx, "investor", id); err != nil {
+		return models.Investor{}, err
 	}
 
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	if _, ok := db.investors[createInvestment.InvestorID]; !ok {
-		return models.Investment{}, errInvestorNotFound
+	investor, ok := db.investors[id]
+	if !ok || investor.TenantID != tenantOf(ctx) {
+		return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+	}
+	if !investor.Deleted() {
+		return investor, nil
+	}
+	if _, taken := db.investorEmailIndex[emailKey(investor)]; taken {
+		return models.Investor{}, &dberr.Error{Kind: dberr.Conflict, Entity: "investor", Field: "email"}
+	}
+	before := investor
+	investor.Deletion = models.Deletion{}
+	investor.Version++
+
+	if err := db.emit(ctx, events.InvestorRestored, id, before, investor); err != nil {
+		return models.Investor{}, err
+	}
+	return investor, nil
+}
+
+func (db *MockDb) CreateInvestment(ctx context.Context, createInvestment models.CreateInvestment) (models.Investment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investment{}, err
+	}
+
+	if createInvestment.AmountUsd.IsNegative() {
+		return models.Investment{}, &dberr.Error{Kind: dberr.CheckViolation, Entity: "investment", Field: "amount_usd"}
+	}
+	if _, err := time.Parse(time.DateOnly, createInvestment.InvestmentDate); err != nil {
+		return models.Investment{}, &dberr.Error{Kind: dberr.CheckViolation, Entity: "investment", Field: "investment_date"}
+	}
+
+	if err := checkInvestmentScope(ctx, createInvestment.FundID, createInvestment.InvestorID); err != nil {
+		return models.Investment{}, err
 	}
-	if _, ok := db.funds[createInvestment.FundID]; !ok {
-		return models.Investment{}, errFundNotFound
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.checkReferences(tenantOf(ctx), createInvestment.InvestorID, createInvestment.FundID); err != nil {
+		return models.Investment{}, err
 	}
 
 	id := uuid.New()
@@ -160,21 +443,914 @@ func (db *MockDb) CreateInvestment(createInvestment models.CreateInvestment) (mo
 		AmountUsd:      createInvestment.AmountUsd,
 		InvestmentDate: createInvestment.InvestmentDate,
 		CreatedAt:      now,
+		Tenancy:        models.Tenancy{TenantID: tenantOf(ctx)},
 	}
 
-	db.investments[id] = investment
+	if err := db.emit(ctx, events.CommitmentMade, id, nil, investment); err != nil {
+		return models.Investment{}, err
+	}
 	return investment, nil
 }
 
-func (db *MockDb) ReadInvestments(fundID uuid.UUID) ([]models.Investment, error) {
+func (db *MockDb) ReadInvestments(ctx context.Context, fundID uuid.UUID, query models.InvestmentQuery) (models.Page[models.Investment], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.Investment]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Investment]{}, err
+	}
+
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
+	scope := access.FromContext(ctx)
 	investments := make([]models.Investment, 0)
 	for _, inv := range db.investments {
-		if inv.FundID == fundID {
-			investments = append(investments, inv)
+		if inv.FundID != fundID || inv.TenantID != scope.TenantID() || !scope.HasInvestment(inv.FundID, inv.InvestorID) {
+			continue
+		}
+		if query.InvestorID != nil && inv.InvestorID != *query.InvestorID {
+			continue
+		}
+		if query.InvestmentDateFrom != "" && inv.InvestmentDate < query.InvestmentDateFrom {
+			continue
+		}
+		if query.InvestmentDateTo != "" && inv.InvestmentDate > query.InvestmentDateTo {
+			continue
+		}
+		if query.AmountUsdMin != nil && inv.AmountUsd.LessThan(*query.AmountUsdMin) {
+			continue
+		}
+		if query.AmountUsdMax != nil && inv.AmountUsd.GreaterThan(*query.AmountUsdMax) {
+			continue
+		}
+		if inv.Deleted() && !query.IncludeDeleted {
+			continue
+		}
+		investments = append(investments, inv)
+	}
+	return paginateSlice(investments, query.PageQuery)
+}
+
+func (db *MockDb) DeleteInvestment(ctx context.Context, fundID, id uuid.UUID, del models.DeleteResource) (models.Investment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investment{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	investment, ok := db.investments[id]
+	if !ok || investment.FundID != fundID || investment.Deleted() || investment.TenantID != tenantOf(ctx) ||
+		!access.FromContext(ctx).HasInvestment(investment.FundID, investment.InvestorID) {
+		return models.Investment{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investment"}
+	}
+	before := investment
+	investment.Deletion = newMockDeletion(ctx, del)
+
+	if err := db.emit(ctx, events.CommitmentWithdrawn, id, before, investment); err != nil {
+		return models.Investment{}, err
+	}
+	return investment, nil
+}
+
+func (db *MockDb) RestoreInvestment(ctx context.Context, fundID, id uuid.UUID) (models.Investment, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Investment{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	investment, ok := db.investments[id]
+	if !ok || investment.FundID != fundID || investment.TenantID != tenantOf(ctx) ||
+		!access.FromContext(ctx).HasInvestment(investment.FundID, investment.InvestorID) {
+		return models.Investment{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investment"}
+	}
+	if !investment.Deleted() {
+		return investment, nil
+	}
+	if err := db.checkReferences(investment.TenantID, investment.InvestorID, investment.FundID); err != nil {
+		return models.Investment{}, err
+	}
+	before := investment
+	investment.Deletion = models.Deletion{}
+
+	if err := db.emit(ctx, events.CommitmentRestored, id, before, investment); err != nil {
+		return models.Investment{}, err
+	}
+	return investment, nil
+}
+
+func (db *MockDb) Search(ctx context.Context, query models.SearchQuery) (models.Page[models.SearchResult], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.SearchResult]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.SearchResult]{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	scope := access.FromContext(ctx)
+	results := make([]models.SearchResult, 0)
+	for _, f := range db.funds {
+		if f.Deleted() || !db.fundVisible(scope, f.ID) {
+			continue
+		}
+		if score := scoreText(f.Name, query.Q, true); score > 0 {
+			results = append(results, models.SearchResult{Type: "fund", Score: score, Fund: &f})
+		}
+	}
+	for _, inv := range db.investors {
+		if inv.Deleted() || !db.investorVisible(scope, inv.ID) {
+			continue
+		}
+		score := max(scoreText(inv.Name, query.Q, true), scoreText(inv.Email, query.Q, false))
+		if score > 0 {
+			results = append(results, models.SearchResult{Type: "investor", Score: score, Investor: &inv})
+		}
+	}
+	return models.Page[models.SearchResult]{Items: rankResults(results, query.PageSize())}, nil
+}
+
+func (db *MockDb) ReadAuditLog(ctx context.Context, query models.AuditQuery) (models.Page[models.AuditEntry], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.AuditEntry]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.AuditEntry]{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	entries := make([]models.AuditEntry, 0)
+	for _, entry := range db.auditLog {
+		if entry.TenantID != tenant {
+			continue
+		}
+		if query.Entity != "" && entry.Entity != query.Entity {
+			continue
+		}
+		if query.EntityID != nil && entry.EntityID != *query.EntityID {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return paginateSlice(entries, query.PageQuery)
+}
+
+// WithTx runs fn against a copy of the store and swaps it in on success.
+// Transactions hold the write lock for their whole duration, so they are
+// serializable and fn must only use the Db it is given.
+func (db *MockDb) WithTx(ctx context.Context, fn func(Db) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tx := &MockDb{
+		funds:              maps.Clone(db.funds),
+		investors:          maps.Clone(db.investors),
+		investments:        maps.Clone(db.investments),
+		investorEmailIndex: maps.Clone(db.investorEmailIndex),
+		auditLog:           slices.Clip(db.auditLog),
+		fundHistory:        maps.Clone(db.fundHistory),
+		investorHistory:    maps.Clone(db.investorHistory),
+		investmentHistory:  maps.Clone(db.investmentHistory),
+		eventStore:         db.eventStore.Clone(),
+		webhooks:           maps.Clone(db.webhooks),
+		webhookDeliveries:  maps.Clone(db.webhookDeliveries),
+		lastDeliveryID:     db.lastDeliveryID,
+		idempotencyKeys:    maps.Clone(db.idempotencyKeys),
+		apiKeys:            maps.Clone(db.apiKeys),
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.funds = tx.funds
+	db.investors = tx.investors
+	db.investments = tx.investments
+	db.investorEmailIndex = tx.investorEmailIndex
+	db.auditLog = tx.auditLog
+	db.fundHistory = tx.fundHistory
+	db.investorHistory = tx.investorHistory
+	db.investmentHistory = tx.investmentHistory
+	db.eventStore = tx.eventStore
+	db.webhooks = tx.webhooks
+	db.webhookDeliveries = tx.webhookDeliveries
+	db.lastDeliveryID = tx.lastDeliveryID
+	db.idempotencyKeys = tx.idempotencyKeys
+	db.apiKeys = tx.apiKeys
+	return nil
+}
+
+// AsOf returns a snapshot of the rows as they were at t, built from their
+// histories.
+func (db *MockDb) AsOf(t time.Time) Reader {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	view := NewMockDb()
+	snapshot(view.funds, db.fundHistory, t)
+	snapshot(view.investors, db.investorHistory, t)
+	snapshot(view.investments, db.investmentHistory, t)
+	return view
+}
+
+// snapshot adds to rows the version of each row in history that was current
+// at t, if any.
+func snapshot[T any](rows map[uuid.UUID]T, history map[uuid.UUID][]mockVersion[T], t time.Time) {
+	for id, versions := range history {
+		for i := len(versions) - 1; i >= 0; i-- {
+			if !versions[i].validFrom.After(t) {
+				rows[id] = versions[i].row
+				break
+			}
+		}
+	}
+}
+
+var (
+	fundStatuses  = []string{"Fundraising", "Investing", "Closed"}
+	investorTypes = []string{"Individual", "Institution", "Family Office"}
+)
+
+// checkFund mirrors the check constraints on the funds table.
+func checkFund(fund models.Fund) error {
+	if fund.VintageYear < 1900 || fund.VintageYear > 2100 {
+		return &dberr.Error{Kind: dberr.CheckViolation, Entity: "fund", Field: "vintage_year"}
+	}
+	if !slices.Contains(fundStatuses, fund.Status) {
+		return &dberr.Error{Kind: dberr.CheckViolation, Entity: "fund", Field: "status"}
+	}
+	return nil
+}
+
+// checkReferences mirrors the investment foreign keys, which must reference
+// live rows, in tenant. Callers hold the lock.
+func (db *MockDb) checkReferences(tenant string, investorID, fundID uuid.UUID) error {
+	if investor, ok := db.investors[investorID]; !ok || investor.Deleted() || investor.TenantID != tenant {
+		return &dberr.Error{Kind: dberr.ForeignKeyViolation, Entity: "investment", Field: "investor_id"}
+	}
+	if fund, ok := db.funds[fundID]; !ok || fund.Deleted() || fund.TenantID != tenant {
+		return &dberr.Error{Kind: dberr.ForeignKeyViolation, Entity: "investment", Field: "fund_id"}
+	}
+	return nil
+}
+
+// fundVisible mirrors gormDb.scoped for funds: a fund is visible if it is in
+// scope's tenant, scope names it, and it has an investment that scope allows
+// when scope limits investors. Callers hold the lock. investorVisible is its
+// counterpart.
+func (db *MockDb) fundVisible(scope access.Scope, id uuid.UUID) bool {
+	if db.funds[id].TenantID != scope.TenantID() || !scope.HasFund(id) {
+		return false
+	}
+	return scope.Investors == nil || db.hasInvestment(func(inv models.Investment) bool {
+		return inv.FundID == id && scope.HasInvestment(inv.FundID, inv.InvestorID)
+	})
+}
+
+func (db *MockDb) investorVisible(scope access.Scope, id uuid.UUID) bool {
+	if db.investors[id].TenantID != scope.TenantID() || !scope.HasInvestor(id) {
+		return false
+	}
+	return scope.Funds == nil || db.hasInvestment(func(inv models.Investment) bool {
+		return inv.InvestorID == id && scope.HasInvestment(inv.FundID, inv.InvestorID)
+	})
+}
+
+// hasInvestment reports whether any investment, live or deleted, matches.
+// Callers hold the lock.
+func (db *MockDb) hasInvestment(match func(models.Investment) bool) bool {
+	for _, inv := range db.investments {
+		if match(inv) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasLiveInvestments reports whether any live investment matches. Callers
+// hold the lock.
+func (db *MockDb) hasLiveInvestments(match func(models.Investment) bool) bool {
+	for _, inv := range db.investments {
+		if !inv.Deleted() && match(inv) {
+			return true
+		}
+	}
+	return false
+}
+
+func newMockDeletion(ctx context.Context, del models.DeleteResource) models.Deletion {
+	now := time.Now().UTC()
+	return models.Deletion{DeletedAt: &now, DeletedBy: actorOf(ctx), DeleteReason: del.Reason}
+}
+
+// emit mirrors the SQL backends' emit: it appends an event to the store,
+// queues its webhook deliveries, applies it to the maps and appends to the
+// audit log, numbering entries in the order they are written. Callers hold
+// the lock.
+func (db *MockDb) emit(ctx context.Context, t events.Type, id uuid.UUID, before, after any) error {
+	now := time.Now().UTC()
+	ev, err := events.New(t, id, after, now)
+	if err != nil {
+		return err
+	}
+	entry, err := newAuditEntry(ctx, t.Stream(), id, t.Action(), before, after)
+	if err != nil {
+		return err
+	}
+	if err := db.eventStore.Append(ctx, &ev); err != nil {
+		return err
+	}
+	if err := db.queueWebhookDeliveries(tenantOf(ctx), ev); err != nil {
+		return err
+	}
+	if err := (mockProjection{db}).Apply(ctx, ev); err != nil {
+		return err
+	}
+	entry.ID = len(db.auditLog) + 1
+	entry.CreatedAt = now
+	db.auditLog = append(db.auditLog, entry)
+	return nil
+}
+
+func (db *MockDb) RebuildProjections(ctx context.Context) (int, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return events.Replay(ctx, db.eventStore, mockProjection{db})
+}
+
+// mockProjection maintains MockDb's maps, like sqlProjection does the
+// tables. Constraints are checked before events are emitted, so Apply
+// cannot fail on a valid stream. Callers hold the lock.
+type mockProjection struct {
+	db *MockDb
+}
+
+func (p mockProjection) Reset(context.Context) error {
+	clear(p.db.funds)
+	clear(p.db.investors)
+	clear(p.db.investments)
+	clear(p.db.investorEmailIndex)
+	clear(p.db.fundHistory)
+	clear(p.db.investorHistory)
+	clear(p.db.investmentHistory)
+	return nil
+}
+
+func (p mockProjection) Apply(_ context.Context, ev events.Event) error {
+	switch ev.Stream {
+	case "fund":
+		return projectMock(ev, p.db.funds, p.db.fundHistory, nil)
+	case "investor":
+		return projectMock(ev, p.db.investors, p.db.investorHistory, p.indexEmail)
+	case "investment":
+		return projectMock(ev, p.db.investments, p.db.investmentHistory, nil)
+	}
+	return fmt.Errorf("unknown event stream %q", ev.Stream)
+}
+
+// indexEmail moves an investor's entry in the email index from its old row
+// to its new one.
+func (p mockProjection) indexEmail(old *models.Investor, row models.Investor) {
+	if old != nil && p.db.investorEmailIndex[emailKey(*old)] == old.ID {
+		delete(p.db.investorEmailIndex, emailKey(*old))
+	}
+	if !row.Deleted() {
+		p.db.investorEmailIndex[emailKey(row)] = row.ID
+	}
+}
+
+// emailKey is the key of investor in the email index. Emails are unique per
+// tenant, like idx_investors_email.
+func emailKey(investor models.Investor) string {
+	return investor.TenantID + "\x00" + investor.Email
+}
+
+// projectMock stores the state carried by ev in rows and appends it to
+// history. Appends go to clipped slices, so that they never write to an
+// array shared with an uncommitted transaction. index, if not nil, is
+// called with the row being replaced, if any, to maintain an index.
+func projectMock[T any](ev events.Event, rows map[uuid.UUID]T, history map[uuid.UUID][]mockVersion[T], index func(old *T, row T)) error {
+	row, err := events.Decode[T](ev)
+	if err != nil {
+		return err
+	}
+	ensureTenant(&row)
+	if index != nil {
+		if old, ok := rows[ev.StreamID]; ok {
+			index(&old, row)
+		} else {
+			index(nil, row)
+		}
+	}
+	rows[ev.StreamID] = row
+	history[ev.StreamID] = append(slices.Clip(history[ev.StreamID]), mockVersion[T]{ev.OccurredAt, row})
+	return nil
+}
+
+func (db *MockDb) CreateWebhook(ctx context.Context, create models.CreateWebhook) (models.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook := newWebhook(create, tenantOf(ctx))
+	webhook.CreatedAt = time.Now().UTC()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+func (db *MockDb) ReadWebhooks(ctx context.Context, query models.WebhookQuery) (models.Page[models.Webhook], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.Webhook]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Webhook]{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	webhooks := make([]models.Webhook, 0, len(db.webhooks))
+	for _, w := range db.webhooks {
+		if w.TenantID != tenant {
+			continue
+		}
+		if query.Enabled != nil && w.Enabled != *query.Enabled {
+			continue
+		}
+		webhooks = append(webhooks, w)
+	}
+	return paginateSlice(webhooks, query.PageQuery)
+}
+
+func (db *MockDb) ReadWebhookByID(ctx context.Context, id uuid.UUID) (models.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Webhook{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	webhook, ok := db.webhooks[id]
+	if !ok || webhook.TenantID != tenantOf(ctx) {
+		return models.Webhook{}, &dberr.Error{Kind: dberr.NotFound, Entity: "webhook"}
+	}
+	return webhook, nil
+}
+
+func (db *MockDb) UpdateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Webhook{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	existing, ok := db.webhooks[webhook.ID]
+	if !ok || existing.TenantID != tenantOf(ctx) {
+		return models.Webhook{}, &dberr.Error{Kind: dberr.NotFound, Entity: "webhook"}
+	}
+	if webhook.Version != 0 && webhook.Version != existing.Version {
+		return models.Webhook{}, &dberr.Error{Kind: dberr.VersionMismatch, Entity: "webhook"}
+	}
+	webhook = updateWebhook(existing, webhook)
+	db.webhooks[webhook.ID] = webhook
+	return webhook, nil
+}
+
+// DeleteWebhook cascades to the webhook's deliveries, and clears ReplayOf
+// where it named one of them, like the foreign keys do.
+func (db *MockDb) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if webhook, ok := db.webhooks[id]; !ok || webhook.TenantID != tenantOf(ctx) {
+		return &dberr.Error{Kind: dberr.NotFound, Entity: "webhook"}
+	}
+	delete(db.webhooks, id)
+	maps.DeleteFunc(db.webhookDeliveries, func(_ int, d models.WebhookDelivery) bool {
+		return d.WebhookID == id
+	})
+	for deliveryID, d := range db.webhookDeliveries {
+		if d.ReplayOf != nil {
+			if _, ok := db.webhookDeliveries[*d.ReplayOf]; !ok {
+				d.ReplayOf = nil
+				db.webhookDeliveries[deliveryID] = d
+			}
+		}
+	}
+	return nil
+}
+
+func (db *MockDb) ReadWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, query models.WebhookDeliveryQuery) (models.Page[models.WebhookDelivery], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.WebhookDelivery]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.WebhookDelivery]{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	if db.webhooks[webhookID].TenantID != tenantOf(ctx) {
+		return paginateSlice(deliveries, query.PageQuery)
+	}
+	for _, d := range db.webhookDeliveries {
+		if d.WebhookID != webhookID {
+			continue
+		}
+		if query.Status != "" && d.Status != query.Status {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+	return paginateSlice(deliveries, query.PageQuery)
+}
+
+func (db *MockDb) ReplayWebhookDelivery(ctx context.Context, webhookID uuid.UUID, deliveryID int) (models.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	original, ok := db.webhookDeliveries[deliveryID]
+	if !ok || original.WebhookID != webhookID || db.webhooks[webhookID].TenantID != tenantOf(ctx) {
+		return models.WebhookDelivery{}, &dberr.Error{Kind: dberr.NotFound, Entity: "delivery"}
+	}
+	replay := replayDelivery(original, time.Now().UTC())
+	db.addDelivery(&replay)
+	return replay, nil
+}
+
+// ClaimWebhookDeliveries holds the write lock, so claims never overlap.
+func (db *MockDb) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now().UTC()
+	var due []models.DueDelivery
+	for _, d := range db.webhookDeliveries {
+		webhook := db.webhooks[d.WebhookID]
+		if d.Status != models.DeliveryPending || d.NextAttemptAt.After(now) || !webhook.Enabled {
+			continue
+		}
+		due = append(due, models.DueDelivery{WebhookDelivery: d, URL: webhook.URL, Secret: webhook.Secret})
+	}
+	slices.SortFunc(due, func(a, b models.DueDelivery) int {
+		if c := a.NextAttemptAt.Compare(*b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	due = due[:min(len(due), limit)]
+	leased := now.Add(lease)
+	for _, d := range due {
+		d.NextAttemptAt = &leased
+		db.webhookDeliveries[d.ID] = d.WebhookDelivery
+	}
+	return due, nil
+}
+
+func (db *MockDb) RecordWebhookAttempt(ctx context.Context, attempt models.WebhookAttempt) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delivery, ok := db.webhookDeliveries[attempt.DeliveryID]
+	if !ok {
+		return &dberr.Error{Kind: dberr.NotFound, Entity: "delivery"}
+	}
+	delivery = recordAttempt(delivery, attempt, time.Now().UTC())
+	db.webhookDeliveries[delivery.ID] = delivery
+
+	webhook := db.webhooks[delivery.WebhookID]
+	if attempt.Error == "" {
+		webhook.ConsecutiveFailures = 0
+	} else {
+		webhook.ConsecutiveFailures++
+		if attempt.DisableAfter > 0 && webhook.Enabled && webhook.ConsecutiveFailures >= attempt.DisableAfter {
+			webhook.Enabled = false
+			webhook.DisabledReason = disabledReason(attempt.DisableAfter)
+			webhook.Version++
+		}
+	}
+	db.webhooks[webhook.ID] = webhook
+	return nil
+}
+
+// queueWebhookDeliveries mirrors the SQL backends' function of the same
+// name, for the webhooks of tenant. Callers hold the lock.
+func (db *MockDb) queueWebhookDeliveries(tenant string, ev events.Event) error {
+	var webhooks []models.Webhook
+	for _, w := range db.webhooks {
+		if w.TenantID == tenant {
+			webhooks = append(webhooks, w)
+		}
+	}
+	deliveries, err := newDeliveries(webhooks, ev)
+	if err != nil {
+		return err
+	}
+	for i := range deliveries {
+		db.addDelivery(&deliveries[i])
+	}
+	return nil
+}
+
+// addDelivery numbers d and stores it. Callers hold the lock.
+func (db *MockDb) addDelivery(d *models.WebhookDelivery) {
+	db.lastDeliveryID++
+	d.ID = db.lastDeliveryID
+	db.webhookDeliveries[d.ID] = *d
+}
+
+// idempotencyKeyID is the primary key of an idempotency key.
+type idempotencyKeyID struct{ tenant, subject, key string }
+
+func idOfIdempotencyKey(key models.IdempotencyKey) idempotencyKeyID {
+	return idempotencyKeyID{key.TenantID, key.Subject, key.Key}
+}
+
+func (db *MockDb) ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl time.Duration) (models.IdempotencyKey, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return models.IdempotencyKey{}, false, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now().UTC()
+	if stored, ok := db.idempotencyKeys[idOfIdempotencyKey(key)]; ok && stored.ExpiresAt.After(now) {
+		return stored, false, nil
+	}
+	key = newIdempotencyKey(key, now, ttl)
+	db.idempotencyKeys[idOfIdempotencyKey(key)] = key
+	return key, true, nil
+}
+
+func (db *MockDb) CompleteIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if stored, ok := db.idempotencyKeys[idOfIdempotencyKey(key)]; ok {
+		stored.StatusCode = key.StatusCode
+		stored.ResponseHeader = key.ResponseHeader
+		stored.ResponseBody = key.ResponseBody
+		db.idempotencyKeys[idOfIdempotencyKey(key)] = stored
+	}
+	return nil
+}
+
+func (db *MockDb) ReleaseIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if stored, ok := db.idempotencyKeys[idOfIdempotencyKey(key)]; ok && !stored.Completed() {
+		delete(db.idempotencyKeys, idOfIdempotencyKey(key))
+	}
+	return nil
+}
+
+func (db *MockDb) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now().UTC()
+	n := len(db.idempotencyKeys)
+	maps.DeleteFunc(db.idempotencyKeys, func(_ idempotencyKeyID, k models.IdempotencyKey) bool {
+		return !k.ExpiresAt.After(now)
+	})
+	return n - len(db.idempotencyKeys), nil
+}
+
+func (db *MockDb) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	key = newAPIKey(key, tenantOf(ctx), time.Now().UTC())
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	for _, k := range db.apiKeys {
+		if k.Hash == key.Hash {
+			return models.APIKey{}, &dberr.Error{Kind: dberr.Conflict, Entity: "api key", Field: "hash"}
+		}
+	}
+	db.apiKeys[key.ID] = key
+	return key, nil
+}
+
+func (db *MockDb) ReadAPIKeys(ctx context.Context, query models.APIKeyQuery) (models.Page[models.APIKey], error) {
+	if err := ctx.Err(); err != nil {
+		return models.Page[models.APIKey]{}, err
+	}
+
+	if err := query.Validate(); err != nil {
+		return models.Page[models.APIKey]{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	keys := make([]models.APIKey, 0, len(db.apiKeys))
+	for _, k := range db.apiKeys {
+		if k.TenantID != tenant || k.RevokedAt != nil && !query.IncludeRevoked {
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return paginateSlice(keys, query.PageQuery)
+}
+
+func (db *MockDb) ReadAPIKeyByID(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	key, ok := db.apiKeys[id]
+	if !ok || key.TenantID != tenantOf(ctx) {
+		return models.APIKey{}, &dberr.Error{Kind: dberr.NotFound, Entity: "api key"}
+	}
+	return key, nil
+}
+
+func (db *MockDb) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key, ok := db.apiKeys[id]
+	if !ok || key.RevokedAt != nil || key.TenantID != tenantOf(ctx) {
+		return models.APIKey{}, &dberr.Error{Kind: dberr.NotFound, Entity: "api key"}
+	}
+	for _, k := range db.apiKeys {
+		if k.Hash == hash {
+			return models.APIKey{}, &dberr.Error{Kind: dberr.Conflict, Entity: "api key", Field: "hash"}
+		}
+	}
+	key = rotateAPIKey(key, hash, prefix, time.Now().UTC())
+	db.apiKeys[id] = key
+	return key, nil
+}
+
+func (db *MockDb) RevokeAPIKey(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key, ok := db.apiKeys[id]
+	if !ok || key.RevokedAt != nil || key.TenantID != tenantOf(ctx) {
+		return models.APIKey{}, &dberr.Error{Kind: dberr.NotFound, Entity: "api key"}
+	}
+	now := time.Now().UTC()
+	key.RevokedAt = &now
+	db.apiKeys[id] = key
+	return key, nil
+}
+
+func (db *MockDb) UseAPIKey(ctx context.Context, hash string) (models.APIKey, error) {
+	if err := ctx.Err(); err != nil {
+		return models.APIKey{}, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now().UTC()
+	for id, key := range db.apiKeys {
+		if key.Hash != hash || !key.Active(now) {
+			continue
+		}
+		if !usedRecently(key, now) {
+			key.LastUsedAt = &now
+			db.apiKeys[id] = key
+		}
+		return key, nil
+	}
+	return models.APIKey{}, &dberr.Error{Kind: dberr.NotFound, Entity: "api key"}
+}
+
+func (db *MockDb) ReadFundStats(ctx context.Context) ([]models.FundStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	committed := make(map[uuid.UUID]decimal.Decimal)
+	for _, investment := range db.investments {
+		if !investment.Deleted() {
+			committed[investment.FundID] = committed[investment.FundID].Add(investment.AmountUsd)
+		}
+	}
+	type key struct{ tenant, status string }
+	byKey := make(map[key]models.FundStats)
+	for _, fund := range db.funds {
+		if !fund.Deleted() {
+			k := key{fund.TenantID, fund.Status}
+			s := byKey[k]
+			s.TenantID, s.Status = fund.TenantID, fund.Status
+			s.Funds++
+			s.CommittedUsd = s.CommittedUsd.Add(committed[fund.ID])
+			byKey[k] = s
+		}
+	}
+	stats := make([]models.FundStats, 0, len(byKey))
+	for _, s := range byKey {
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b models.FundStats) int {
+		return cmp.Or(cmp.Compare(a.TenantID, b.TenantID), cmp.Compare(a.Status, b.Status))
+	})
+	return stats, nil
+}
+
+func (db *MockDb) ReadFundCommitments(ctx context.Context, limit int) ([]models.FundCommitment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	committed := make(map[uuid.UUID]decimal.Decimal)
+	for _, investment := range db.investments {
+		if !investment.Deleted() {
+			committed[investment.FundID] = committed[investment.FundID].Add(investment.AmountUsd)
+		}
+	}
+	commitments := make([]models.FundCommitment, 0)
+	for _, fund := range db.funds {
+		if !fund.Deleted() {
+			commitments = append(commitments, models.FundCommitment{TenantID: fund.TenantID, FundID: fund.ID, CommittedUsd: committed[fund.ID]})
 		}
 	}
-	return investments, nil
+	slices.SortFunc(commitments, func(a, b models.FundCommitment) int {
+		return cmp.Or(b.CommittedUsd.Cmp(a.CommittedUsd), cmp.Compare(a.FundID.String(), b.FundID.String()))
+	})
+	return commitments[:min(limit, len(commitments))], nil
 }
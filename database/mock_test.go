@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+var fundI = models.CreateFund{
+	Name:          "Fund I",
+	VintageYear:   2020,
+	TargetSizeUsd: decimal.NewFromInt(1_000_000),
+	Status:        "Fundraising",
+}
+
+func TestMockDb_WithTxCommits(t *testing.T) {
+	ctx := context.Background()
+	db := NewMockDb()
+
+	err := db.WithTx(ctx, func(tx Db) error {
+		_, err := tx.CreateFund(ctx, fundI)
+		return err
+	})
+	assert.NoError(t, err)
+
+	page, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+}
+
+func TestMockDb_WithTxRollsBackOnError(t *testing.T) {
+	ctx := context.Background()
+	db := NewMockDb()
+	errBoom := errors.New("boom")
+
+	err := db.WithTx(ctx, func(tx Db) error {
+		if _, err := tx.CreateFund(ctx, fundI); err != nil {
+			return err
+		}
+		return errBoom
+	})
+	assert.ErrorIs(t, err, errBoom)
+
+	page, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+}
+
+func TestMockDb_NestedWithTxRollsBackInnerOnly(t *testing.T) {
+	ctx := context.Background()
+	db := NewMockDb()
+	errBoom := errors.New("boom")
+
+	err := db.WithTx(ctx, func(tx Db) error {
+		if _, err := tx.CreateFund(ctx, fundI); err != nil {
+			return err
+		}
+		inner := tx.WithTx(ctx, func(tx Db) error {
+			_, _ = tx.CreateFund(ctx, fundI)
+			return errBoom
+		})
+		assert.ErrorIs(t, inner, errBoom)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	page, err := db.ReadFunds(ctx, models.FundQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+}
+
+func TestMockDb_CancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	db := NewMockDb()
+
+	_, err := db.CreateFund(ctx, fundI)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	err = db.WithTx(ctx, func(Db) error { return nil })
+	assert.ErrorIs(t, err, context.Canceled)
+}
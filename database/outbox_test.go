@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database/events"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/iuhmirza/titanbay-take-home/outbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutboxRelay_RetriesUntilPublished(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	fund, err := db.CreateFund(ctx, models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+	errRollback := errors.New("roll back")
+	err = db.WithTx(ctx, func(tx Db) error {
+		if _, err := tx.CreateFund(ctx, models.CreateFund{Name: "Fund II", VintageYear: 2020, Status: "Closed"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+
+	failures := 1
+	publisher := &outbox.MemoryPublisher{Fail: func(outbox.Message) error {
+		if failures > 0 {
+			failures--
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}}
+	relay := NewOutboxRelay(db, publisher)
+	relay.MinBackoff = 20 * time.Millisecond
+
+	n, err := relay.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "rolled back writes are not queued")
+	var queued outbox.Message
+	assert.NoError(t, db.db.First(&queued).Error)
+	assert.Equal(t, 1, queued.Attempts)
+	assert.Equal(t, "broker unavailable", queued.LastError)
+	assert.Nil(t, queued.PublishedAt)
+
+	n, err = relay.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, n, "failed messages wait for their backoff")
+
+	assert.Eventually(t, func() bool {
+		n, err := relay.RunOnce(ctx)
+		return err == nil && n == 1
+	}, time.Second, 5*time.Millisecond, "failed messages are retried after their backoff")
+	published := publisher.Messages()
+	if assert.Len(t, published, 1) {
+		assert.Equal(t, "funds", published[0].Topic)
+		assert.Equal(t, fund.ID.String(), published[0].Key)
+		var ev events.Event
+		assert.NoError(t, json.Unmarshal(published[0].Payload, &ev))
+		assert.Equal(t, events.FundCreated, ev.Type)
+		assert.Equal(t, fund.ID, ev.StreamID)
+	}
+
+	n, err = relay.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, n, "published messages are not sent again")
+}
+
+func TestOutboxRelay_PublishesOutsideTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	_, err = db.CreateFund(ctx, models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+
+	// SQLite has a single connection, so this write would wait for the
+	// relay's transaction if it were still open.
+	publisher := &outbox.MemoryPublisher{Fail: func(outbox.Message) error {
+		writeCtx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err := db.CreateFund(writeCtx, models.CreateFund{Name: "Fund II", VintageYear: 2020, Status: "Closed"})
+		return err
+	}}
+	n, err := NewOutboxRelay(db, publisher).RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Len(t, publisher.Messages(), 1)
+}
+
+// blockingPublisher waits for its context to end.
+type blockingPublisher struct{}
+
+func (blockingPublisher) Publish(ctx context.Context, _ outbox.Message) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestOutboxRelay_PublishTimeout(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	_, err = db.CreateFund(ctx, models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+
+	relay := NewOutboxRelay(db, blockingPublisher{})
+	relay.PublishTimeout = 10 * time.Millisecond
+	n, err := relay.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	var queued outbox.Message
+	assert.NoError(t, db.db.First(&queued).Error)
+	assert.Equal(t, context.DeadlineExceeded.Error(), queued.LastError)
+	assert.Nil(t, queued.PublishedAt)
+}
+
+func TestOutboxRelay_RetriesAfterLease(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	for _, name := range []string{"Fund I", "Fund II"} {
+		_, err = db.CreateFund(ctx, models.CreateFund{Name: name, VintageYear: 2020, Status: "Closed"})
+		assert.NoError(t, err)
+	}
+
+	// The relay stops after publishing the first message of its batch,
+	// leaving the second claimed.
+	stopCtx, stop := context.WithCancel(ctx)
+	publisher := &outbox.MemoryPublisher{Fail: func(outbox.Message) error {
+		stop()
+		return nil
+	}}
+	relay := NewOutboxRelay(db, publisher)
+	relay.Lease = 50 * time.Millisecond
+	n, err := relay.RunOnce(stopCtx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Len(t, publisher.Messages(), 1)
+
+	publisher.Fail = nil
+	n, err = relay.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, n, "claimed messages are left to their relay until the lease ends")
+	assert.Eventually(t, func() bool {
+		n, err := relay.RunOnce(ctx)
+		return err == nil && n == 1
+	}, time.Second, 5*time.Millisecond)
+	assert.Len(t, publisher.Messages(), 2)
+}
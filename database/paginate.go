@@ -0,0 +1,94 @@
+package database
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// paginate applies keyset pagination to an already filtered query. Callers
+// must have validated the query, which checks the sort column against the
+// model's whitelist, so it is safe to interpolate.
+func paginate[T models.Sortable](tx *gorm.DB, query models.PageQuery) (models.Page[T], error) {
+	field, desc := query.Order()
+	direction, comparison := "ASC", ">"
+	if desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	after, err := models.After[T](query)
+	if err != nil {
+		return models.Page[T]{}, err
+	}
+	if after != nil {
+		tx = tx.Where(fmt.Sprintf("(%s, id) %s (?, ?)", field, comparison), (*after).SortValue(field), (*after).SortValue("id"))
+	}
+
+	rows := make([]T, 0, query.PageSize()+1)
+	err = tx.Order(fmt.Sprintf("%s %s, id %s", field, direction, direction)).
+		Limit(query.PageSize() + 1).
+		Find(&rows).Error
+	if err != nil {
+		return models.Page[T]{}, err
+	}
+	return models.NewPage(rows, query)
+}
+
+// paginateSlice gives MockDb the same ordering and cursor semantics as
+// paginate does for PGDB.
+func paginateSlice[T models.Sortable](rows []T, query models.PageQuery) (models.Page[T], error) {
+	field, desc := query.Order()
+	compare := func(a, b models.Sortable) int {
+		c := compareSortValues(a.SortValue(field), b.SortValue(field))
+		if c == 0 {
+			c = compareSortValues(a.SortValue("id"), b.SortValue("id"))
+		}
+		if desc {
+			return -c
+		}
+		return c
+	}
+	slices.SortFunc(rows, func(a, b T) int { return compare(a, b) })
+
+	after, err := models.After[T](query)
+	if err != nil {
+		return models.Page[T]{}, err
+	}
+	if after != nil {
+		start, _ := slices.BinarySearchFunc(rows, *after, func(row, after T) int {
+			if compare(row, after) <= 0 {
+				return -1
+			}
+			return 1
+		})
+		rows = rows[start:]
+	}
+
+	return models.NewPage(rows[:min(len(rows), query.PageSize()+1)], query)
+}
+
+func compareSortValues(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case string:
+		return strings.Compare(a, b.(string))
+	case decimal.Decimal:
+		return a.Cmp(b.(decimal.Decimal))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case uuid.UUID:
+		b := b.(uuid.UUID)
+		return bytes.Compare(a[:], b[:])
+	default:
+		panic(fmt.Sprintf("unsupported sort value type %T", a))
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/events"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/iuhmirza/titanbay-take-home/outbox"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// emit appends an event of type t, carrying the state after of the entity
+// with the given id, queues it for publishing and for delivery to webhooks,
+// applies it to the tables and records the change from before in the audit
+// log. Writes go through emit only, so that the tables never hold anything
+// that replaying the event store would not rebuild.
+func emit(ctx context.Context, tx *gorm.DB, t events.Type, id uuid.UUID, before, after any) error {
+	ev, err := events.New(t, id, after, tx.NowFunc())
+	if err != nil {
+		return err
+	}
+	if err := events.NewSQLStore(tx).Append(ctx, &ev); err != nil {
+		return err
+	}
+	msg, err := outbox.NewMessage(ev)
+	if err != nil {
+		return err
+	}
+	if err := tx.WithContext(ctx).Create(msg).Error; err != nil {
+		return err
+	}
+	if err := queueWebhookDeliveries(ctx, tx, ev); err != nil {
+		return err
+	}
+	if err := (sqlProjection{tx}).Apply(ctx, ev); err != nil {
+		return err
+	}
+	return record(ctx, tx, t.Stream(), id, t.Action(), before, after)
+}
+
+// sqlProjection maintains the funds, investors and investments tables and
+// their histories. The audit log is not a projection: it records who made
+// each change, which events do not, and it cannot be deleted.
+type sqlProjection struct {
+	tx *gorm.DB
+}
+
+// projectedTables lists the tables that Reset empties, children first.
+var projectedTables = []string{
+	"investments_history", "investors_history", "funds_history",
+	"investments", "investors", "funds",
+}
+
+func (p sqlProjection) Reset(ctx context.Context) error {
+	for _, table := range projectedTables {
+		if err := p.tx.WithContext(ctx).Exec("DELETE FROM " + table).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p sqlProjection) Apply(ctx context.Context, ev events.Event) error {
+	switch ev.Stream {
+	case "fund":
+		return project[models.Fund](ctx, p.tx, ev)
+	case "investor":
+		return project[models.Investor](ctx, p.tx, ev)
+	case "investment":
+		return project[models.Investment](ctx, p.tx, ev)
+	}
+	return fmt.Errorf("unknown event stream %q", ev.Stream)
+}
+
+// project writes the state carried by ev to T's table, inserting it for
+// events that create a row so that constraint violations are reported as
+// they would be for any insert, and adds it to the table's history.
+func project[T any](ctx context.Context, tx *gorm.DB, ev events.Event) error {
+	row, err := events.Decode[T](ev)
+	if err != nil {
+		return err
+	}
+	ensureTenant(&row)
+	write := tx.WithContext(ctx).Omit(clause.Associations)
+	if ev.Type.Creates() {
+		err = write.Create(&row).Error
+	} else {
+		err = write.Save(&row).Error
+	}
+	if err != nil {
+		return err
+	}
+	return addVersion(tx.WithContext(ctx), row, ev.StreamID, ev.OccurredAt)
+}
+
+// NewOutboxRelay returns a relay that publishes db's outbox to p, or nil if
+// db has no outbox. MockDb does not: its events are only kept in memory.
+func NewOutboxRelay(db Db, p outbox.Publisher) *outbox.Relay {
+	switch db := db.(type) {
+	case *PGDB:
+		return outbox.NewRelay(db.db, p)
+	case *SQLiteDB:
+		return outbox.NewRelay(db.db, p)
+	}
+	return nil
+}
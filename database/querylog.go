@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const defaultSlowQuery = 200 * time.Millisecond
+
+// queryLogger logs GORM's queries with slog, so that they carry the
+// attributes of the request they were made for. At logger.Error it logs
+// failed queries; at logger.Warn, queries slower than slow as well; at
+// logger.Info, every query. Queries are logged with placeholders rather
+// than their values, which include webhook secrets, API key hashes,
+// investors' emails and stored responses.
+type queryLogger struct {
+	level logger.LogLevel
+	slow  time.Duration
+}
+
+// newQueryLogger reads the level from DB_LOG_LEVEL, one of silent, error,
+// warn (the default) or info, and the slow query threshold from
+// DB_SLOW_QUERY, a Go duration such as "500ms", or 0 to turn it off.
+func newQueryLogger() (queryLogger, error) {
+	l := queryLogger{level: logger.Warn, slow: defaultSlowQuery}
+	switch level := os.Getenv("DB_LOG_LEVEL"); strings.ToLower(level) {
+	case "silent":
+		l.level = logger.Silent
+	case "error":
+		l.level = logger.Error
+	case "", "warn":
+	case "info":
+		l.level = logger.Info
+	default:
+		return l, fmt.Errorf("unknown DB_LOG_LEVEL %q, want silent, error, warn or info", level)
+	}
+	if value := os.Getenv("DB_SLOW_QUERY"); value != "" {
+		slow, err := time.ParseDuration(value)
+		if err != nil || slow < 0 {
+			return l, fmt.Errorf("DB_SLOW_QUERY must be a duration such as 200ms, got %q", value)
+		}
+		l.slow = slow
+	}
+	return l, nil
+}
+
+func (l queryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	l.level = level
+	return l
+}
+
+func (l queryLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l queryLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l queryLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+// ParamsFilter drops a query's values before GORM writes them into the SQL
+// that Trace logs.
+func (l queryLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	return sql, nil
+}
+
+// Trace logs a query once it has run. Missing rows are not failures: they
+// are how lookups report 404s.
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	attrs := func() []any {
+		sql, rows := fc()
+		return []any{"sql", sql, "rows", rows, "duration_ms", float64(elapsed.Microseconds()) / 1000}
+	}
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		slog.ErrorContext(ctx, "Query failed", append(attrs(), "error", err)...)
+	case l.slow > 0 && elapsed > l.slow && l.level >= logger.Warn:
+		slog.WarnContext(ctx, "Slow query", append(attrs(), "threshold_ms", l.slow.Milliseconds())...)
+	case l.level >= logger.Info:
+		slog.InfoContext(ctx, "Query", attrs()...)
+	}
+}
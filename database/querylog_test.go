@@ -0,0 +1,58 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/logging"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+func TestQueryLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logging.New(&buf, slog.LevelDebug))
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	ctx := logging.NewContext(context.Background(), slog.String("request_id", "req-1"))
+
+	db.db.Logger = queryLogger{level: logger.Warn, slow: time.Hour}
+	_, err = db.ReadFundByID(ctx, uuid.New())
+	assert.Error(t, err)
+	assert.Empty(t, buf.String(), "missing rows are not logged as failures")
+
+	db.db.Logger = queryLogger{level: logger.Warn, slow: time.Nanosecond}
+	_, err = db.ReadFundByID(ctx, uuid.New())
+	assert.Error(t, err)
+	assert.Contains(t, buf.String(), `"msg":"Slow query"`)
+	assert.Contains(t, buf.String(), `"request_id":"req-1"`)
+	assert.Contains(t, buf.String(), `"sql":"SELECT`)
+
+	buf.Reset()
+	db.db.Logger = queryLogger{level: logger.Info}
+	_, err = db.CreateInvestor(ctx, models.CreateInvestor{Name: "Alex Smith", InvestorType: "Individual", Email: "alex@example.com"})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "INSERT INTO")
+	assert.NotContains(t, buf.String(), "alex@example.com", "values are not logged")
+
+	buf.Reset()
+	db.db.Logger = queryLogger{level: logger.Error, slow: time.Nanosecond}
+	assert.Error(t, db.db.WithContext(ctx).Exec("SELECT * FROM nowhere").Error)
+	assert.Contains(t, buf.String(), `"msg":"Query failed"`)
+	assert.NotContains(t, buf.String(), "Slow query")
+
+	t.Setenv("DB_LOG_LEVEL", "loud")
+	_, err = newQueryLogger()
+	assert.Error(t, err)
+	t.Setenv("DB_LOG_LEVEL", "info")
+	t.Setenv("DB_SLOW_QUERY", "1s")
+	l, err := newQueryLogger()
+	assert.NoError(t, err)
+	assert.Equal(t, queryLogger{level: logger.Info, slow: time.Second}, l)
+}
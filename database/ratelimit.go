@@ -0,0 +1,12 @@
+package database
+
+import "github.com/iuhmirza/titanbay-take-home/ratelimit"
+
+// NewRateLimitStore returns a rate limit store that keeps its buckets in db,
+// or nil if db cannot. MockDb cannot; use ratelimit.NewMemoryStore instead.
+func NewRateLimitStore(db Db) *ratelimit.SQLStore {
+	if g := gormOf(db); g != nil {
+		return ratelimit.NewSQLStore(g)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	assert.Nil(t, NewRateLimitStore(NewMockDb()))
+	store := NewRateLimitStore(db)
+	limit := ratelimit.Limit{Requests: 2, Per: time.Hour}
+
+	for i := 1; i >= 0; i-- {
+		res, err := store.Take(ctx, "sub:alice *", limit)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+	res, err := store.Take(ctx, "sub:alice *", limit)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.InDelta(t, 30*time.Minute, res.RetryAfter, float64(time.Second))
+	res, err = store.Take(ctx, "sub:bob *", limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+
+	n, err := store.Purge(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, n, "buckets are kept until they refill")
+	res, err = store.Take(ctx, "sub:carol *", ratelimit.Limit{Requests: 1, Per: time.Nanosecond})
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	time.Sleep(time.Millisecond)
+	n, err = store.Purge(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
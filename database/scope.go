@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"gorm.io/gorm"
+)
+
+// scoped limits a query on table to the rows that the access.Scope in ctx
+// allows: those of its tenant and, within that, the funds and investors it
+// names. Funds and investors are seen through the investments that the
+// scope allows, as those link the two.
+func (g *gormDb) scoped(ctx context.Context, tx *gorm.DB, table string) *gorm.DB {
+	s := access.FromContext(ctx)
+	tx = tx.Where(table+".tenant_id = ?", s.TenantID())
+	if s.Unrestricted() {
+		return tx
+	}
+	switch table {
+	case "funds":
+		if s.Funds != nil {
+			tx = tx.Where("id IN ?", s.Funds)
+		}
+		if s.Investors != nil {
+			tx = tx.Where("id IN (?)", g.from(ctx, "investments").Select("fund_id"))
+		}
+	case "investors":
+		if s.Investors != nil {
+			tx = tx.Where("id IN ?", s.Investors)
+		}
+		if s.Funds != nil {
+			tx = tx.Where("id IN (?)", g.from(ctx, "investments").Select("investor_id"))
+		}
+	case "investments":
+		if s.Funds != nil {
+			tx = tx.Where("fund_id IN ?", s.Funds)
+		}
+		if s.Investors != nil {
+			tx = tx.Where("investor_id IN ?", s.Investors)
+		}
+	}
+	return tx
+}
+
+// tenantOf returns the tenant that ctx confines reads and writes to.
+func tenantOf(ctx context.Context) string {
+	return access.FromContext(ctx).TenantID()
+}
+
+// inTenant limits a query to the rows of the tenant in ctx. The tables that
+// other tenants' rows share are always read through it, or through scoped.
+func inTenant(ctx context.Context, tx *gorm.DB) *gorm.DB {
+	return tx.Where("tenant_id = ?", tenantOf(ctx))
+}
+
+// ensureTenant gives row, a pointer to a model, the default tenant if it
+// names none.
+func ensureTenant(row any) {
+	if t, ok := row.(interface{ EnsureTenant() }); ok {
+		t.EnsureTenant()
+	}
+}
+
+// checkWriteScope reports a write to a fund or investor that the
+// access.Scope in ctx does not allow as not found, so that callers cannot
+// tell whether it exists.
+func checkWriteScope(ctx context.Context, entity string, id uuid.UUID) error {
+	s := access.FromContext(ctx)
+	allowed := s.CanWriteFund(id)
+	if entity == "investor" {
+		allowed = s.CanWriteInvestor(id)
+	}
+	if !allowed {
+		return &dberr.Error{Kind: dberr.NotFound, Entity: entity}
+	}
+	return nil
+}
+
+// checkInvestmentScope reports an investment that the access.Scope in ctx
+// does not allow as referencing a missing investor or fund, as it would be
+// to the caller.
+func checkInvestmentScope(ctx context.Context, fundID, investorID uuid.UUID) error {
+	s := access.FromContext(ctx)
+	if !s.HasInvestor(investorID) {
+		return &dberr.Error{Kind: dberr.ForeignKeyViolation, Entity: "investment", Field: "investor_id"}
+	}
+	if !s.HasFund(fundID) {
+		return &dberr.Error{Kind: dberr.ForeignKeyViolation, Entity: "investment", Field: "fund_id"}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package database
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/iuhmirza/titanbay-take-home/models"
+)
+
+// Match tiers shared by PGDB's SQL and MockDb's scorer. Anything that is not
+// an exact, prefix, word-prefix or substring match falls back to trigram
+// similarity, which must reach pg_trgm's default threshold to be returned and
+// is weighted so that fuzzy matches always rank below the other tiers. The
+// supporting indexes are created by migration 0002_search_indexes.
+const (
+	scoreExact       = 1.0
+	scorePrefix      = 0.9
+	scoreWordPrefix  = 0.75
+	scoreSubstring   = 0.6
+	scoreFuzzyWeight = 0.5
+	trigramThreshold = 0.3
+)
+
+// scoreSQL mirrors scoreText for a single column. words enables the
+// full-text word-prefix tier, which is only used for names.
+func scoreSQL(column string, words bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CASE WHEN lower(%s) = lower(@q) THEN %v", column, scoreExact)
+	fmt.Fprintf(&b, " WHEN %s ILIKE @prefix THEN %v", column, scorePrefix)
+	if words {
+		fmt.Fprintf(&b, " WHEN to_tsvector('simple', %s) @@ to_tsquery('simple', @tsquery) THEN %v", column, scoreWordPrefix)
+	}
+	fmt.Fprintf(&b, " WHEN %s ILIKE @like THEN %v", column, scoreSubstring)
+	fmt.Fprintf(&b, " WHEN similarity(%s, @q) >= %v THEN similarity(%[1]s, @q)::float8 * %[3]v", column, trigramThreshold, scoreFuzzyWeight)
+	b.WriteString(" ELSE 0 END")
+	return b.String()
+}
+
+// matchSQL is the index-backed predicate selecting candidate rows for column.
+func matchSQL(column string, words bool) string {
+	predicate := "(" + column + " % @q OR " + column + " ILIKE @like"
+	if words {
+		predicate += " OR to_tsvector('simple', " + column + ") @@ to_tsquery('simple', @tsquery)"
+	}
+	return predicate + ")"
+}
+
+func searchArgs(query models.SearchQuery) map[string]any {
+	q := strings.TrimSpace(query.Q)
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(q)
+	terms := searchWords(q)
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	return map[string]any{
+		"q":       q,
+		"prefix":  escaped + "%",
+		"like":    "%" + escaped + "%",
+		"tsquery": strings.Join(terms, " & "),
+		"limit":   query.PageSize(),
+	}
+}
+
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// scoreText ranks field against q using the same tiers as scoreSQL.
+func scoreText(field, q string, words bool) float64 {
+	field, q = strings.ToLower(field), strings.ToLower(strings.TrimSpace(q))
+	switch {
+	case field == q:
+		return scoreExact
+	case strings.HasPrefix(field, q):
+		return scorePrefix
+	case words && wordPrefixMatch(field, q):
+		return scoreWordPrefix
+	case strings.Contains(field, q):
+		return scoreSubstring
+	}
+	if s := trigramSimilarity(field, q); s >= trigramThreshold {
+		return s * scoreFuzzyWeight
+	}
+	return 0
+}
+
+func wordPrefixMatch(field, q string) bool {
+	terms := searchWords(q)
+	if len(terms) == 0 {
+		return false
+	}
+	words := searchWords(field)
+	for _, term := range terms {
+		if !slices.ContainsFunc(words, func(w string) bool { return strings.HasPrefix(w, term) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// trigramSimilarity implements pg_trgm's similarity(): each word is padded
+// with two leading spaces and one trailing space before being split into
+// trigrams, and the score is the Jaccard index of the two trigram sets.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range searchWords(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+// rankResults orders matches by descending score, breaking ties by name and
+// id so that both backends return identical pages, and applies the limit.
+func rankResults(results []models.SearchResult, limit int) []models.SearchResult {
+	name := func(r models.SearchResult) string {
+		if r.Fund != nil {
+			return r.Fund.Name
+		}
+		return r.Investor.Name
+	}
+	id := func(r models.SearchResult) string {
+		if r.Fund != nil {
+			return r.Fund.ID.String()
+		}
+		return r.Investor.ID.String()
+	}
+	slices.SortFunc(results, func(a, b models.SearchResult) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			strings.Compare(name(a), name(b)),
+			strings.Compare(id(a), id(b)),
+		)
+	})
+	return results[:min(len(results), limit)]
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"gorm.io/gorm"
+)
+
+// SQLiteDB is an embedded alternative to PGDB for demos and integration
+// tests. It enforces the same check, unique and foreign key constraints,
+// but scores search results in Go rather than with pg_trgm.
+type SQLiteDB struct {
+	gormDb
+}
+
+func NewSQLiteDB(db *gorm.DB) *SQLiteDB {
+	return &SQLiteDB{gormDb{db: db, translate: translateSQLiteError}}
+}
+
+// OpenSQLite opens the SQLite database at path, which may be ":memory:", with
+// foreign keys enforced. The pool is limited to one connection, so that an
+// in-memory database is shared by every caller and writers never contend for
+// SQLite's lock.
+func OpenSQLite(path string) (*gorm.DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		// Timestamps are compared as text, so they must share a time zone.
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	return db, nil
+}
+
+// NewMemorySQLiteDB returns an empty, fully migrated in-memory database.
+func NewMemorySQLiteDB() (*SQLiteDB, error) {
+	db, err := OpenSQLite(":memory:")
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		return nil, err
+	}
+	return NewSQLiteDB(db), nil
+}
+
+func (sqlitedb *SQLiteDB) Search(ctx context.Context, query models.SearchQuery) (models.Page[models.SearchResult], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.SearchResult]{}, err
+	}
+
+	var funds []models.Fund
+	if err := sqlitedb.from(ctx, "funds").Where("deleted_at IS NULL").Find(&funds).Error; err != nil {
+		return models.Page[models.SearchResult]{}, err
+	}
+	var investors []models.Investor
+	if err := sqlitedb.from(ctx, "investors").Where("deleted_at IS NULL").Find(&investors).Error; err != nil {
+		return models.Page[models.SearchResult]{}, err
+	}
+
+	results := make([]models.SearchResult, 0)
+	for _, f := range funds {
+		if score := scoreText(f.Name, query.Q, true); score > 0 {
+			results = append(results, models.SearchResult{Type: "fund", Score: score, Fund: &f})
+		}
+	}
+	for _, inv := range investors {
+		score := max(scoreText(inv.Name, query.Q, true), scoreText(inv.Email, query.Q, false))
+		if score > 0 {
+			results = append(results, models.SearchResult{Type: "investor", Score: score, Investor: &inv})
+		}
+	}
+	return models.Page[models.SearchResult]{Items: rankResults(results, query.PageSize())}, nil
+}
+
+func (sqlitedb *SQLiteDB) AsOf(t time.Time) Reader {
+	return &SQLiteDB{sqlitedb.asOfView(t)}
+}
+
+func (sqlitedb *SQLiteDB) WithTx(ctx context.Context, fn func(Db) error) error {
+	return sqlitedb.withTx(ctx, func(g gormDb) Db { return &SQLiteDB{g} }, fn)
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLiteDB_MigratesDownAndUp(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite(":memory:")
+	assert.NoError(t, err)
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+
+	assert.NoError(t, migrator.Up(ctx))
+	assert.NoError(t, migrator.To(ctx, 0))
+	current, err := migrator.Current(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, current)
+
+	assert.NoError(t, migrator.Up(ctx))
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range statuses {
+		assert.NotNil(t, s.AppliedAt)
+	}
+}
+
+func TestSQLiteDB_AuditLogIsAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewMemorySQLiteDB()
+	assert.NoError(t, err)
+	_, err = db.CreateFund(ctx, models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+
+	assert.ErrorContains(t, db.db.Exec(`UPDATE audit_log SET actor = 'mallory'`).Error, "append-only")
+	assert.ErrorContains(t, db.db.Exec(`DELETE FROM audit_log`).Error, "append-only")
+}
+
+func TestSQLiteDB_ImportsRowsThatPredateEvents(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenSQLite(":memory:")
+	assert.NoError(t, err)
+	migrator, err := NewMigrator(db)
+	assert.NoError(t, err)
+	assert.NoError(t, migrator.To(ctx, 6))
+
+	deletedAt := time.Date(2024, 2, 3, 4, 5, 6, 789, time.UTC)
+	fund := models.Fund{
+		ID:            uuid.New(),
+		Name:          "Fund I",
+		VintageYear:   2020,
+		TargetSizeUsd: decimal.RequireFromString("1234567.89"),
+		Status:        "Closed",
+		CreatedAt:     time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		Version:       3,
+		Deletion:      models.Deletion{DeletedAt: &deletedAt, DeletedBy: "ops@example.com", DeleteReason: "duplicate"},
+	}
+	assert.NoError(t, db.Omit("tenant_id").Create(&fund).Error, "tenants came later")
+	assert.NoError(t, migrator.Up(ctx))
+
+	sqlitedb := NewSQLiteDB(db)
+	n, err := sqlitedb.RebuildProjections(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	got, err := sqlitedb.ReadFundByID(ctx, fund.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "1234567.89", got.TargetSizeUsd.StringFixed(2))
+	got.TargetSizeUsd = fund.TargetSizeUsd
+	fund.TenantID = models.DefaultTenant
+	assert.Equal(t, fund, got)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"context"
+
+	"github.com/iuhmirza/titanbay-take-home/models"
+)
+
+func (g *gormDb) ReadFundStats(ctx context.Context) ([]models.FundStats, error) {
+	stats := make([]models.FundStats, 0)
+	err := g.db.WithContext(ctx).Table("funds").
+		Select("funds.tenant_id, funds.status, COUNT(DISTINCT funds.id) AS funds, COALESCE(SUM(investments.amount_usd), 0) AS committed_usd").
+		Joins("LEFT JOIN investments ON investments.fund_id = funds.id AND investments.deleted_at IS NULL").
+		Where("funds.deleted_at IS NULL").
+		Group("funds.tenant_id, funds.status").
+		Order("funds.tenant_id, funds.status").
+		Scan(&stats).Error
+	return stats, g.translate(err, "fund")
+}
+
+func (g *gormDb) ReadFundCommitments(ctx context.Context, limit int) ([]models.FundCommitment, error) {
+	commitments := make([]models.FundCommitment, 0)
+	err := g.db.WithContext(ctx).Table("funds").
+		Select("funds.tenant_id, funds.id AS fund_id, COALESCE(SUM(investments.amount_usd), 0) AS committed_usd").
+		Joins("LEFT JOIN investments ON investments.fund_id = funds.id AND investments.deleted_at IS NULL").
+		Where("funds.deleted_at IS NULL").
+		Group("funds.tenant_id, funds.id").
+		Order("committed_usd DESC, funds.id").
+		Limit(limit).
+		Scan(&commitments).Error
+	return commitments, g.translate(err, "fund")
+}
@@ -0,0 +1,311 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the instrumentation scope of Traced's spans.
+const tracerName = "github.com/iuhmirza/titanbay-take-home/database"
+
+// Traced returns db with a span around every method call, named after the
+// method, such as "Db.CreateFund", and a child of the span in the call's
+// context. Failed calls mark their span as an error. It decorates any Db,
+// so that MockDb is traced the same as the SQL backends; spans for the
+// SQL itself are left to the driver.
+func Traced(db Db, tp trace.TracerProvider) Db {
+	tracer := tp.Tracer(tracerName)
+	return &tracedDb{tracedReader: tracedReader{reader: db, tracer: tracer}, db: db}
+}
+
+// tracedReader traces a Reader. Readers of the past record the time they
+// read as of, and those in a transaction make their spans children of the
+// transaction's, tx.
+type tracedReader struct {
+	reader Reader
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+	tx     trace.Span
+}
+
+type tracedDb struct {
+	tracedReader
+	db Db
+}
+
+func (t tracedReader) start(ctx context.Context, method string) (context.Context, trace.Span) {
+	if t.tx != nil {
+		ctx = trace.ContextWithSpan(ctx, t.tx)
+	}
+	return t.tracer.Start(ctx, "Db."+method, trace.WithAttributes(t.attrs...))
+}
+
+// end ends span, recording err if there is one, and returns err.
+func end(span trace.Span, err error) error {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	return err
+}
+
+func (t *tracedDb) AsOf(at time.Time) Reader {
+	return tracedReader{
+		reader: t.db.AsOf(at),
+		tracer: t.tracer,
+		tx:     t.tx,
+		attrs:  []attribute.KeyValue{attribute.String("db.as_of", at.Format(time.RFC3339Nano))},
+	}
+}
+
+// WithTx traces the transaction as a whole, and passes fn a traced Db whose
+// calls are children of it, whatever context they are made with.
+func (t *tracedDb) WithTx(ctx context.Context, fn func(Db) error) error {
+	ctx, span := t.start(ctx, "WithTx")
+	return end(span, t.db.WithTx(ctx, func(tx Db) error {
+		return fn(&tracedDb{tracedReader: tracedReader{reader: tx, tracer: t.tracer, tx: span}, db: tx})
+	}))
+}
+
+func (t tracedReader) ReadFunds(ctx context.Context, query models.FundQuery) (models.Page[models.Fund], error) {
+	ctx, span := t.start(ctx, "ReadFunds")
+	v, err := t.reader.ReadFunds(ctx, query)
+	return v, end(span, err)
+}
+
+func (t tracedReader) ReadFundByID(ctx context.Context, id uuid.UUID) (models.Fund, error) {
+	ctx, span := t.start(ctx, "ReadFundByID")
+	v, err := t.reader.ReadFundByID(ctx, id)
+	return v, end(span, err)
+}
+
+func (t tracedReader) ReadInvestors(ctx context.Context, query models.InvestorQuery) (models.Page[models.Investor], error) {
+	ctx, span := t.start(ctx, "ReadInvestors")
+	v, err := t.reader.ReadInvestors(ctx, query)
+	return v, end(span, err)
+}
+
+func (t tracedReader) ReadInvestorByID(ctx context.Context, id uuid.UUID) (models.Investor, error) {
+	ctx, span := t.start(ctx, "ReadInvestorByID")
+	v, err := t.reader.ReadInvestorByID(ctx, id)
+	return v, end(span, err)
+}
+
+func (t tracedReader) ReadInvestments(ctx context.Context, fundID uuid.UUID, query models.InvestmentQuery) (models.Page[models.Investment], error) {
+	ctx, span := t.start(ctx, "ReadInvestments")
+	v, err := t.reader.ReadInvestments(ctx, fundID, query)
+	return v, end(span, err)
+}
+
+func (t tracedReader) Search(ctx context.Context, query models.SearchQuery) (models.Page[models.SearchResult], error) {
+	ctx, span := t.start(ctx, "Search")
+	v, err := t.reader.Search(ctx, query)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) CreateFund(ctx context.Context, create models.CreateFund) (models.Fund, error) {
+	ctx, span := t.start(ctx, "CreateFund")
+	v, err := t.db.CreateFund(ctx, create)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) UpdateFund(ctx context.Context, fund models.Fund) (models.Fund, error) {
+	ctx, span := t.start(ctx, "UpdateFund")
+	v, err := t.db.UpdateFund(ctx, fund)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) DeleteFund(ctx context.Context, id uuid.UUID, del models.DeleteResource) (models.Fund, error) {
+	ctx, span := t.start(ctx, "DeleteFund")
+	v, err := t.db.DeleteFund(ctx, id, del)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) RestoreFund(ctx context.Context, id uuid.UUID) (models.Fund, error) {
+	ctx, span := t.start(ctx, "RestoreFund")
+	v, err := t.db.RestoreFund(ctx, id)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) CreateInvestor(ctx context.Context, create models.CreateInvestor) (models.Investor, error) {
+	ctx, span := t.start(ctx, "CreateInvestor")
+	v, err := t.db.CreateInvestor(ctx, create)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) UpdateInvestor(ctx context.Context, investor models.Investor) (models.Investor, error) {
+	ctx, span := t.start(ctx, "UpdateInvestor")
+	v, err := t.db.UpdateInvestor(ctx, investor)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) DeleteInvestor(ctx context.Context, id uuid.UUID, del models.DeleteResource) (models.Investor, error) {
+	ctx, span := t.start(ctx, "DeleteInvestor")
+	v, err := t.db.DeleteInvestor(ctx, id, del)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) RestoreInvestor(ctx context.Context, id uuid.UUID) (models.Investor, error) {
+	ctx, span := t.start(ctx, "RestoreInvestor")
+	v, err := t.db.RestoreInvestor(ctx, id)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) CreateInvestment(ctx context.Context, create models.CreateInvestment) (models.Investment, error) {
+	ctx, span := t.start(ctx, "CreateInvestment")
+	v, err := t.db.CreateInvestment(ctx, create)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) DeleteInvestment(ctx context.Context, fundID, id uuid.UUID, del models.DeleteResource) (models.Investment, error) {
+	ctx, span := t.start(ctx, "DeleteInvestment")
+	v, err := t.db.DeleteInvestment(ctx, fundID, id, del)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) RestoreInvestment(ctx context.Context, fundID, id uuid.UUID) (models.Investment, error) {
+	ctx, span := t.start(ctx, "RestoreInvestment")
+	v, err := t.db.RestoreInvestment(ctx, fundID, id)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReadAuditLog(ctx context.Context, query models.AuditQuery) (models.Page[models.AuditEntry], error) {
+	ctx, span := t.start(ctx, "ReadAuditLog")
+	v, err := t.db.ReadAuditLog(ctx, query)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) RebuildProjections(ctx context.Context) (int, error) {
+	ctx, span := t.start(ctx, "RebuildProjections")
+	v, err := t.db.RebuildProjections(ctx)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) CreateWebhook(ctx context.Context, create models.CreateWebhook) (models.Webhook, error) {
+	ctx, span := t.start(ctx, "CreateWebhook")
+	v, err := t.db.CreateWebhook(ctx, create)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReadWebhooks(ctx context.Context, query models.WebhookQuery) (models.Page[models.Webhook], error) {
+	ctx, span := t.start(ctx, "ReadWebhooks")
+	v, err := t.db.ReadWebhooks(ctx, query)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReadWebhookByID(ctx context.Context, id uuid.UUID) (models.Webhook, error) {
+	ctx, span := t.start(ctx, "ReadWebhookByID")
+	v, err := t.db.ReadWebhookByID(ctx, id)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) UpdateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, span := t.start(ctx, "UpdateWebhook")
+	v, err := t.db.UpdateWebhook(ctx, webhook)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	ctx, span := t.start(ctx, "DeleteWebhook")
+	return end(span, t.db.DeleteWebhook(ctx, id))
+}
+
+func (t *tracedDb) ReadWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, query models.WebhookDeliveryQuery) (models.Page[models.WebhookDelivery], error) {
+	ctx, span := t.start(ctx, "ReadWebhookDeliveries")
+	v, err := t.db.ReadWebhookDeliveries(ctx, webhookID, query)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReplayWebhookDelivery(ctx context.Context, webhookID uuid.UUID, deliveryID int) (models.WebhookDelivery, error) {
+	ctx, span := t.start(ctx, "ReplayWebhookDelivery")
+	v, err := t.db.ReplayWebhookDelivery(ctx, webhookID, deliveryID)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	ctx, span := t.start(ctx, "ClaimWebhookDeliveries")
+	v, err := t.db.ClaimWebhookDeliveries(ctx, limit, lease)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) RecordWebhookAttempt(ctx context.Context, attempt models.WebhookAttempt) error {
+	ctx, span := t.start(ctx, "RecordWebhookAttempt")
+	return end(span, t.db.RecordWebhookAttempt(ctx, attempt))
+}
+
+func (t *tracedDb) ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl time.Duration) (models.IdempotencyKey, bool, error) {
+	ctx, span := t.start(ctx, "ReserveIdempotencyKey")
+	stored, reserved, err := t.db.ReserveIdempotencyKey(ctx, key, ttl)
+	return stored, reserved, end(span, err)
+}
+
+func (t *tracedDb) CompleteIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	ctx, span := t.start(ctx, "CompleteIdempotencyKey")
+	return end(span, t.db.CompleteIdempotencyKey(ctx, key))
+}
+
+func (t *tracedDb) ReleaseIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	ctx, span := t.start(ctx, "ReleaseIdempotencyKey")
+	return end(span, t.db.ReleaseIdempotencyKey(ctx, key))
+}
+
+func (t *tracedDb) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	ctx, span := t.start(ctx, "PurgeIdempotencyKeys")
+	v, err := t.db.PurgeIdempotencyKeys(ctx)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	ctx, span := t.start(ctx, "CreateAPIKey")
+	v, err := t.db.CreateAPIKey(ctx, key)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReadAPIKeys(ctx context.Context, query models.APIKeyQuery) (models.Page[models.APIKey], error) {
+	ctx, span := t.start(ctx, "ReadAPIKeys")
+	v, err := t.db.ReadAPIKeys(ctx, query)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReadAPIKeyByID(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	ctx, span := t.start(ctx, "ReadAPIKeyByID")
+	v, err := t.db.ReadAPIKeyByID(ctx, id)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (models.APIKey, error) {
+	ctx, span := t.start(ctx, "RotateAPIKey")
+	v, err := t.db.RotateAPIKey(ctx, id, hash, prefix)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) RevokeAPIKey(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	ctx, span := t.start(ctx, "RevokeAPIKey")
+	v, err := t.db.RevokeAPIKey(ctx, id)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) UseAPIKey(ctx context.Context, hash string) (models.APIKey, error) {
+	ctx, span := t.start(ctx, "UseAPIKey")
+	v, err := t.db.UseAPIKey(ctx, hash)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReadFundStats(ctx context.Context) ([]models.FundStats, error) {
+	ctx, span := t.start(ctx, "ReadFundStats")
+	v, err := t.db.ReadFundStats(ctx)
+	return v, end(span, err)
+}
+
+func (t *tracedDb) ReadFundCommitments(ctx context.Context, limit int) ([]models.FundCommitment, error) {
+	ctx, span := t.start(ctx, "ReadFundCommitments")
+	v, err := t.db.ReadFundCommitments(ctx, limit)
+	return v, end(span, err)
+}
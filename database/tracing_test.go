@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTraced(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	db := Traced(NewMockDb(), tp)
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+
+	fund, err := db.CreateFund(ctx, models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+	_, err = db.ReadFundByID(ctx, uuid.New())
+	assert.Error(t, err)
+	_, err = db.AsOf(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)).ReadFundByID(ctx, fund.ID)
+	assert.Error(t, err)
+	errRollback := errors.New("roll back")
+	err = db.WithTx(ctx, func(tx Db) error {
+		_, err := tx.ReadFunds(ctx, models.FundQuery{})
+		assert.NoError(t, err)
+		return errRollback
+	})
+	assert.ErrorIs(t, err, errRollback)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"Db.CreateFund", "Db.ReadFundByID", "Db.ReadFundByID", "Db.ReadFunds", "Db.WithTx", "request"}, names)
+	request := spans[5].SpanContext.SpanID()
+	assert.Equal(t, request, spans[0].Parent.SpanID(), "spans are children of the span in the call's context")
+	assert.Equal(t, codes.Unset, spans[0].Status.Code)
+	assert.Equal(t, codes.Error, spans[1].Status.Code)
+	assert.Len(t, spans[1].Events, 1, "the error is recorded")
+	assert.Equal(t, "db.as_of", string(spans[2].Attributes[0].Key))
+	assert.Equal(t, "2024-05-01T00:00:00Z", spans[2].Attributes[0].Value.AsString())
+	assert.Equal(t, spans[4].SpanContext.SpanID(), spans[3].Parent.SpanID(), "calls in a transaction are children of its span")
+	assert.Equal(t, codes.Error, spans[4].Status.Code)
+}
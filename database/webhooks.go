@@ -0,0 +1,274 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/iuhmirza/titanbay-take-home/database/events"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (g *gormDb) CreateWebhook(ctx context.Context, create models.CreateWebhook) (models.Webhook, error) {
+	webhook := newWebhook(create, tenantOf(ctx))
+	err := g.db.WithContext(ctx).Create(&webhook).Error
+	return webhook, g.translate(err, "webhook")
+}
+
+func (g *gormDb) ReadWebhooks(ctx context.Context, query models.WebhookQuery) (models.Page[models.Webhook], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.Webhook]{}, err
+	}
+	tx := inTenant(ctx, g.db.WithContext(ctx))
+	if query.Enabled != nil {
+		tx = tx.Where("enabled = ?", *query.Enabled)
+	}
+	return paginate[models.Webhook](tx, query.PageQuery)
+}
+
+func (g *gormDb) ReadWebhookByID(ctx context.Context, id uuid.UUID) (models.Webhook, error) {
+	var webhook models.Webhook
+	return webhook, g.translate(inTenant(ctx, g.db.WithContext(ctx)).First(&webhook, "id = ?", id).Error, "webhook")
+}
+
+func (g *gormDb) UpdateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing models.Webhook
+		if err := inTenant(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&existing, "id = ?", webhook.ID).Error; err != nil {
+			return err
+		}
+		if webhook.Version != 0 && webhook.Version != existing.Version {
+			return &dberr.Error{Kind: dberr.VersionMismatch, Entity: "webhook"}
+		}
+		webhook = updateWebhook(existing, webhook)
+		return tx.Save(&webhook).Error
+	})
+	if err != nil {
+		return models.Webhook{}, g.translate(err, "webhook")
+	}
+	return webhook, nil
+}
+
+func (g *gormDb) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	res := inTenant(ctx, g.db.WithContext(ctx)).Delete(&models.Webhook{}, "id = ?", id)
+	if res.Error != nil {
+		return g.translate(res.Error, "webhook")
+	}
+	if res.RowsAffected == 0 {
+		return &dberr.Error{Kind: dberr.NotFound, Entity: "webhook"}
+	}
+	return nil
+}
+
+func (g *gormDb) ReadWebhookDeliveries(ctx context.Context, webhookID uuid.UUID, query models.WebhookDeliveryQuery) (models.Page[models.WebhookDelivery], error) {
+	if err := query.Validate(); err != nil {
+		return models.Page[models.WebhookDelivery]{}, err
+	}
+	tx := g.db.WithContext(ctx).Where("webhook_id IN (?)", tenantWebhook(ctx, g.db, webhookID))
+	if query.Status != "" {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	return paginate[models.WebhookDelivery](tx, query.PageQuery)
+}
+
+func (g *gormDb) ReplayWebhookDelivery(ctx context.Context, webhookID uuid.UUID, deliveryID int) (models.WebhookDelivery, error) {
+	var replay models.WebhookDelivery
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var original models.WebhookDelivery
+		err := tx.Where("webhook_id IN (?)", tenantWebhook(ctx, tx, webhookID)).First(&original, "id = ?", deliveryID).Error
+		if err != nil {
+			return err
+		}
+		replay = replayDelivery(original, tx.NowFunc())
+		return tx.Create(&replay).Error
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, g.translate(err, "delivery")
+	}
+	return replay, nil
+}
+
+func (g *gormDb) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	var due []models.DueDelivery
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		err := tx.Table("webhook_deliveries").
+			Select("webhook_deliveries.*, webhooks.url, webhooks.secret").
+			Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id").
+			Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ? AND webhooks.enabled", models.DeliveryPending, now).
+			Order("webhook_deliveries.next_attempt_at, webhook_deliveries.id").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "webhook_deliveries"}, Options: "SKIP LOCKED"}).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+		ids := make([]int, len(due))
+		for i, d := range due {
+			ids[i] = d.ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return due, err
+}
+
+func (g *gormDb) RecordWebhookAttempt(ctx context.Context, attempt models.WebhookAttempt) error {
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var delivery models.WebhookDelivery
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&delivery, "id = ?", attempt.DeliveryID).Error; err != nil {
+			return err
+		}
+		delivery = recordAttempt(delivery, attempt, tx.NowFunc())
+		if err := tx.Save(&delivery).Error; err != nil {
+			return err
+		}
+		webhook := tx.Model(&models.Webhook{}).Where("id = ?", delivery.WebhookID)
+		if attempt.Error == "" {
+			return webhook.Update("consecutive_failures", 0).Error
+		}
+		if err := webhook.Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error; err != nil {
+			return err
+		}
+		if attempt.DisableAfter == 0 {
+			return nil
+		}
+		return tx.Model(&models.Webhook{}).
+			Where("id = ? AND enabled AND consecutive_failures >= ?", delivery.WebhookID, attempt.DisableAfter).
+			Updates(map[string]any{
+				"enabled":         false,
+				"disabled_reason": disabledReason(attempt.DisableAfter),
+				"version":         gorm.Expr("version + 1"),
+			}).Error
+	})
+	return g.translate(err, "delivery")
+}
+
+// queueWebhookDeliveries queues a delivery of ev to every enabled webhook of
+// the tenant in ctx subscribed to it.
+func queueWebhookDeliveries(ctx context.Context, tx *gorm.DB, ev events.Event) error {
+	var webhooks []models.Webhook
+	if err := inTenant(ctx, tx.WithContext(ctx)).Where("enabled = ?", true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+	deliveries, err := newDeliveries(webhooks, ev)
+	if err != nil || len(deliveries) == 0 {
+		return err
+	}
+	return tx.WithContext(ctx).Create(&deliveries).Error
+}
+
+// tenantWebhook selects the ID of the webhook with the given id, if it
+// belongs to the tenant in ctx, to limit queries on its deliveries.
+func tenantWebhook(ctx context.Context, db *gorm.DB, id uuid.UUID) *gorm.DB {
+	tx := db.Session(&gorm.Session{NewDB: true}).WithContext(ctx)
+	return inTenant(ctx, tx).Model(&models.Webhook{}).Select("id").Where("id = ?", id)
+}
+
+// The helpers below are shared by gormDb and MockDb, so that both apply the
+// same rules.
+
+func newWebhook(create models.CreateWebhook, tenant string) models.Webhook {
+	return models.Webhook{
+		ID:         uuid.New(),
+		URL:        create.URL,
+		EventTypes: nonNil(create.EventTypes),
+		Secret:     create.Secret,
+		Enabled:    true,
+		Version:    1,
+		Tenancy:    models.Tenancy{TenantID: tenant},
+	}
+}
+
+// updateWebhook applies the client-editable fields of update to existing.
+// Enabling a webhook gives it a clean slate.
+func updateWebhook(existing, update models.Webhook) models.Webhook {
+	existing.URL = update.URL
+	existing.EventTypes = nonNil(update.EventTypes)
+	if update.Enabled && !existing.Enabled {
+		existing.ConsecutiveFailures = 0
+		existing.DisabledReason = ""
+	}
+	existing.Enabled = update.Enabled
+	existing.Version++
+	return existing
+}
+
+// nonNil makes an absent list of event types read back as [] rather than
+// null.
+func nonNil(types []string) []string {
+	if types == nil {
+		return []string{}
+	}
+	return types
+}
+
+func disabledReason(failures int) string {
+	return fmt.Sprintf("disabled after %d consecutive failed delivery attempts", failures)
+}
+
+// newDeliveries returns a pending delivery of ev to each of webhooks that is
+// enabled and subscribed to it. The payload is the event, as the outbox
+// publishes it.
+func newDeliveries(webhooks []models.Webhook, ev events.Event) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	var payload json.RawMessage
+	for _, webhook := range webhooks {
+		if !webhook.Enabled || !webhook.Subscribes(string(ev.Type)) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			if payload, err = json.Marshal(ev); err != nil {
+				return nil, err
+			}
+		}
+		due := ev.OccurredAt
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventSeq:      ev.Seq,
+			EventType:     string(ev.Type),
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &due,
+			CreatedAt:     ev.OccurredAt,
+		})
+	}
+	return deliveries, nil
+}
+
+func replayDelivery(original models.WebhookDelivery, now time.Time) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventSeq:      original.EventSeq,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+		ReplayOf:      &original.ID,
+		CreatedAt:     now,
+	}
+}
+
+// recordAttempt applies the outcome of an attempt to the delivery it was
+// for.
+func recordAttempt(delivery models.WebhookDelivery, attempt models.WebhookAttempt, now time.Time) models.WebhookDelivery {
+	delivery.Attempts++
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+	delivery.NextAttemptAt = nil
+	switch {
+	case attempt.Error == "":
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+	case attempt.RetryAt != nil:
+		delivery.NextAttemptAt = attempt.RetryAt
+	default:
+		delivery.Status = models.DeliveryFailed
+	}
+	return delivery
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database/audit"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+)
+
+// issuedAPIKey is the response to creating or rotating an API key, the only
+// ones that include the key itself. Retries with the same Idempotency-Key get
+// the API key without it.
+type issuedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey issues a key with the requested scopes, which callers can
+// only grant if they may do those actions themselves.
+func (h Handler) CreateAPIKey(ctx echo.Context) error {
+	var create models.CreateAPIKey
+	if err := ctx.Bind(&create); err != nil {
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
+	}
+	if err := create.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+	if id, ok := Identity(ctx); ok {
+		for _, s := range create.Scopes {
+			if !id.Can(auth.Action(s)) {
+				return WriteProblem(ctx, Problem{
+					Type:   ProblemForbidden,
+					Title:  "Forbidden",
+					Status: http.StatusForbidden,
+					Detail: fmt.Sprintf("You cannot grant %s, as you are not permitted it.", s),
+				})
+			}
+		}
+	}
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return DbError(ctx, "Failed to create api key", err)
+	}
+
+	key, err := h.Db.CreateAPIKey(ctx.Request().Context(), models.APIKey{
+		Name:      create.Name,
+		Prefix:    prefix,
+		Hash:      hash,
+		Scopes:    create.Scopes,
+		CreatedBy: audit.FromContext(ctx.Request().Context()).Actor,
+		ExpiresAt: create.ExpiresAt,
+	})
+	if err != nil {
+		return DbError(ctx, "Failed to create api key", err)
+	}
+	replayWithout(ctx, key)
+	return ctx.JSON(http.StatusCreated, issuedAPIKey{key, secret})
+}
+
+func (h Handler) ReadAPIKeys(ctx echo.Context) error {
+	var query models.APIKeyQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	keys, err := h.Db.ReadAPIKeys(ctx.Request().Context(), query)
+	if err != nil {
+		return DbError(ctx, "Failed to read api keys", err)
+	}
+	return ctx.JSON(http.StatusOK, keys)
+}
+
+func (h Handler) ReadAPIKeyByID(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("api_key_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for api_key_id", err)
+	}
+
+	key, err := h.Db.ReadAPIKeyByID(ctx.Request().Context(), id)
+	if err != nil {
+		return DbError(ctx, "Failed to read api key", err)
+	}
+	return ctx.JSON(http.StatusOK, key)
+}
+
+// RotateAPIKey replaces a key with a new one, returned once, here. The old
+// key stops working at once.
+func (h Handler) RotateAPIKey(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("api_key_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for api_key_id", err)
+	}
+	secret, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return DbError(ctx, "Failed to rotate api key", err)
+	}
+
+	key, err := h.Db.RotateAPIKey(ctx.Request().Context(), id, hash, prefix)
+	if err != nil {
+		return DbError(ctx, "Failed to rotate api key", err)
+	}
+	replayWithout(ctx, key)
+	return ctx.JSON(http.StatusOK, issuedAPIKey{key, secret})
+}
+
+// RevokeAPIKey stops a key from working for good. The key is still listed
+// with include_revoked=true.
+func (h Handler) RevokeAPIKey(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("api_key_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for api_key_id", err)
+	}
+
+	key, err := h.Db.RevokeAPIKey(ctx.Request().Context(), id)
+	if err != nil {
+		return DbError(ctx, "Failed to revoke api key", err)
+	}
+	return ctx.JSON(http.StatusOK, key)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeys(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		verifier, err := auth.NewStaticVerifier(testAuthKey, auth.Options{})
+		assert.NoError(t, err)
+		h := Handler{Db: db}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(h.Authenticate(verifier))
+		e.Use(AuditSource)
+		e.GET("/funds", h.ReadFunds, Authorize(auth.FundRead))
+		e.POST("/funds", h.CreateFund, Authorize(auth.FundCreate))
+		e.GET("/api-keys", h.ReadAPIKeys, Authorize(auth.APIKeyManage))
+		e.POST("/api-keys", h.CreateAPIKey, Authorize(auth.APIKeyManage))
+		e.GET("/api-keys/:api_key_id", h.ReadAPIKeyByID, Authorize(auth.APIKeyManage))
+		e.POST("/api-keys/:api_key_id/rotate", h.RotateAPIKey, Authorize(auth.APIKeyManage))
+		e.DELETE("/api-keys/:api_key_id", h.RevokeAPIKey, Authorize(auth.APIKeyManage))
+
+		serve := func(method, target, body, authorization string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+		token, err := auth.Sign(testAuthKey, "alice", time.Hour, auth.Options{}, map[string]any{"roles": []string{"admin"}})
+		assert.NoError(t, err)
+		admin := "Bearer " + token
+		issue := func(authorization, body string) (issued issuedAPIKey, rec *httptest.ResponseRecorder) {
+			rec = serve(http.MethodPost, "/api-keys", body, authorization)
+			_ = json.Unmarshal(rec.Body.Bytes(), &issued)
+			return issued, rec
+		}
+
+		etl, rec := issue(admin, `{"name":"etl","scopes":["fund:read"]}`)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"key":"tbk_`)
+		assert.NotContains(t, rec.Body.String(), auth.HashAPIKey(etl.Key))
+		assert.Equal(t, etl.Key[:len(etl.Prefix)], etl.Prefix)
+		assert.Equal(t, "alice", etl.CreatedBy)
+
+		rec = serve(http.MethodGet, "/api-keys/"+etl.ID.String(), "", admin)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), etl.Key[len(etl.Prefix):], "keys cannot be read back")
+		assert.NotContains(t, rec.Body.String(), `"key"`)
+
+		rec = serve(http.MethodGet, "/funds", "", "ApiKey "+etl.Key)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = serve(http.MethodPost, "/funds", `{}`, "ApiKey "+etl.Key)
+		assert.Equal(t, http.StatusForbidden, rec.Code, "keys may only do what their scopes permit")
+		rec = serve(http.MethodGet, "/funds", "", "ApiKey tbk_unknown")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "ApiKey", rec.Header().Get(echo.HeaderWWWAuthenticate))
+
+		rec = serve(http.MethodGet, "/api-keys/"+etl.ID.String(), "", admin)
+		var read models.APIKey
+		_ = json.Unmarshal(rec.Body.Bytes(), &read)
+		assert.NotNil(t, read.LastUsedAt)
+
+		_, rec = issue(admin, `{"name":"bad","scopes":["fund:fly"]}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		_, rec = issue(admin, `{"name":"old","scopes":["fund:read"],"expires_at":"2000-01-01T00:00:00Z"}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		_, rec = issue("ApiKey "+etl.Key, `{"name":"mine","scopes":["fund:read"]}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		manager, rec := issue(admin, `{"name":"manager","scopes":["api-key:manage","fund:read"]}`)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		_, rec = issue("ApiKey "+manager.Key, `{"name":"escalate","scopes":["fund:create"]}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code, "callers cannot grant what they are not permitted")
+		assert.Contains(t, decodeProblem(t, rec).Detail, "fund:create")
+
+		rec = serve(http.MethodPost, "/api-keys/"+etl.ID.String()+"/rotate", "", admin)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var rotated issuedAPIKey
+		_ = json.Unmarshal(rec.Body.Bytes(), &rotated)
+		assert.NotEqual(t, etl.Key, rotated.Key)
+		assert.NotNil(t, rotated.RotatedAt)
+		rec = serve(http.MethodGet, "/funds", "", "ApiKey "+etl.Key)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "rotation retires the old key")
+		rec = serve(http.MethodGet, "/funds", "", "ApiKey "+rotated.Key)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec = serve(http.MethodDelete, "/api-keys/"+etl.ID.String(), "", admin)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		rec = serve(http.MethodGet, "/funds", "", "ApiKey "+rotated.Key)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		rec = serve(http.MethodDelete, "/api-keys/"+etl.ID.String(), "", admin)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serve(http.MethodGet, "/api-keys", "", admin)
+		var page models.Page[models.APIKey]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		if assert.Len(t, page.Items, 1) {
+			assert.Equal(t, "manager", page.Items[0].Name)
+		}
+		rec = serve(http.MethodGet, "/api-keys?include_revoked=true", "", admin)
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 2)
+	})
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database/audit"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+)
+
+// headerActor names the caller in the audit log when authentication is
+// turned off, in which case it is taken on trust.
+const headerActor = "X-Actor"
+
+// AuditSource is middleware that stores the request's actor and ID in its
+// context, where the Db picks them up when writing the audit log. The actor
+// is the authenticated caller's subject, so it must run after Authenticate
+// as well as middleware.RequestID.
+func AuditSource(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		req := ctx.Request()
+		actor := cmp.Or(req.Header.Get(headerActor), "anonymous")
+		if id, ok := Identity(ctx); ok {
+			actor = id.Subject
+		}
+		src := audit.Source{
+			Actor:     actor,
+			RequestID: ctx.Response().Header().Get(echo.HeaderXRequestID),
+		}
+		ctx.SetRequest(req.WithContext(audit.NewContext(req.Context(), src)))
+		return next(ctx)
+	}
+}
+
+func (h Handler) ReadAuditLog(ctx echo.Context) error {
+	var query models.AuditQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	entries, err := h.Db.ReadAuditLog(ctx.Request().Context(), query)
+	if err != nil {
+		return DbError(ctx, "Failed to read audit log", err)
+	}
+	return ctx.JSON(http.StatusOK, entries)
+}
+
+// ReadFundHistory lists the audit log of a fund, including a deleted one.
+func (h Handler) ReadFundHistory(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("fund_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
+	}
+	if _, err := h.Db.ReadFundByID(ctx.Request().Context(), id); err != nil {
+		return DbError(ctx, "Failed to read fund from database", err)
+	}
+	return h.readHistory(ctx, "fund", id)
+}
+
+// ReadInvestorHistory lists the audit log of an investor, including a
+// deleted one.
+func (h Handler) ReadInvestorHistory(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("investor_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for investor_id", err)
+	}
+	if _, err := h.Db.ReadInvestorByID(ctx.Request().Context(), id); err != nil {
+		return DbError(ctx, "Failed to read investor from database", err)
+	}
+	return h.readHistory(ctx, "investor", id)
+}
+
+func (h Handler) readHistory(ctx echo.Context, entity string, id uuid.UUID) error {
+	var query models.AuditQuery
+	if err := ctx.Bind(&query.PageQuery); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	query.Entity, query.EntityID = entity, &id
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	entries, err := h.Db.ReadAuditLog(ctx.Request().Context(), query)
+	if err != nil {
+		return DbError(ctx, "Failed to read history", err)
+	}
+	return ctx.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog_RecordsActorAndRequestID(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		h := Handler{Db: db}
+		e := echo.New()
+		e.Use(middleware.RequestID())
+		e.Use(AuditSource)
+		e.POST("/funds", h.CreateFund)
+		e.PATCH("/funds/:fund_id", h.PatchFund)
+		e.GET("/funds/:fund_id/history", h.ReadFundHistory)
+		e.GET("/audit", h.ReadAuditLog)
+
+		serve := func(method, target, body string, header http.Header) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			for name, values := range header {
+				req.Header[name] = values
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := serve(http.MethodPost, "/funds", `{"name":"Fund A","vintage_year":2021,"target_size_usd":1000000,"status":"Fundraising"}`,
+			http.Header{headerActor: {"alice@example.com"}, echo.HeaderXRequestID: {"req-create"}})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var fund models.Fund
+		_ = json.Unmarshal(rec.Body.Bytes(), &fund)
+
+		rec = serve(http.MethodPatch, "/funds/"+fund.ID.String(), `{"status":"Investing"}`, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		patchRequestID := rec.Header().Get(echo.HeaderXRequestID)
+
+		rec = serve(http.MethodGet, "/funds/"+fund.ID.String()+"/history", "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var history models.Page[models.AuditEntry]
+		_ = json.Unmarshal(rec.Body.Bytes(), &history)
+		if assert.Len(t, history.Items, 2) {
+			assert.Equal(t, "create", history.Items[0].Action)
+			assert.Equal(t, "alice@example.com", history.Items[0].Actor)
+			assert.Equal(t, "req-create", history.Items[0].RequestID)
+
+			assert.Equal(t, "update", history.Items[1].Action)
+			assert.Equal(t, "anonymous", history.Items[1].Actor)
+			assert.Equal(t, patchRequestID, history.Items[1].RequestID)
+			assert.JSONEq(t, `{"before":"Fundraising","after":"Investing"}`, string(marshalBytes(t, history.Items[1].Changes["status"])))
+		}
+
+		rec = serve(http.MethodGet, "/audit?entity=fund&id="+fund.ID.String()+"&sort=-created_at&limit=1", "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var page models.Page[models.AuditEntry]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		if assert.Len(t, page.Items, 1) {
+			assert.Equal(t, "update", page.Items[0].Action)
+		}
+		assert.NotEmpty(t, page.NextCursor)
+
+		rec = serve(http.MethodGet, "/audit?entity=unicorn", "", nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = serve(http.MethodGet, "/funds/"+uuid.NewString()+"/history", "", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func marshalBytes(t *testing.T, v any) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return b
+}
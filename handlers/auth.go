@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/labstack/echo/v4"
+)
+
+// contextKeyIdentity is the echo.Context key of the caller's auth.Identity.
+const contextKeyIdentity = "identity"
+
+// Authenticate is middleware that requires credentials on every request:
+// either a bearer token, checked by v, or an API key, as
+// "Authorization: ApiKey <key>", checked against h.Db. The caller's
+// identity is stored on the echo.Context, see Identity, and in the request's
+// context, see auth.FromContext, along with the access.Scope that the Db
+// filters by. Requests without valid credentials are rejected with 401.
+func (h Handler) Authenticate(v *auth.Verifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			scheme, credentials, _ := strings.Cut(req.Header.Get(echo.HeaderAuthorization), " ")
+			credentials = strings.TrimSpace(credentials)
+			var id auth.Identity
+			switch {
+			case credentials == "":
+				return unauthorized(ctx, `Bearer`, "A bearer token or API key is required.")
+			case strings.EqualFold(scheme, "Bearer"):
+				var err error
+				if id, err = v.Verify(req.Context(), credentials); err != nil {
+					return unauthorized(ctx, `Bearer error="invalid_token"`, "The bearer token is invalid: "+err.Error())
+				}
+			case strings.EqualFold(scheme, "ApiKey"):
+				key, err := h.Db.UseAPIKey(req.Context(), auth.HashAPIKey(credentials))
+				if errors.Is(err, dberr.NotFound) {
+					return unauthorized(ctx, `ApiKey`, "The API key is unknown, expired or revoked.")
+				} else if err != nil {
+					return DbError(ctx, "Failed to check api key", err)
+				}
+				id = auth.APIKeyIdentity(key.ID, key.TenantID, key.Scopes)
+			default:
+				return unauthorized(ctx, `Bearer`, "A bearer token or API key is required.")
+			}
+			ctx.Set(contextKeyIdentity, id)
+			reqCtx := access.NewContext(auth.NewContext(req.Context(), id), id.Scope())
+			ctx.SetRequest(req.WithContext(reqCtx))
+			return next(ctx)
+		}
+	}
+}
+
+// Authorize is route middleware that lets callers through only if they may
+// do every one of actions; see auth.Identity.Can. Others are rejected
+// with 403, or 401 if Authenticate has not identified them.
+func Authorize(actions ...auth.Action) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			id, ok := Identity(ctx)
+			if !ok {
+				return unauthorized(ctx, `Bearer`, "A bearer token is required.")
+			}
+			for _, action := range actions {
+				if !id.Can(action) {
+					return WriteProblem(ctx, Problem{
+						Type:   ProblemForbidden,
+						Title:  "Forbidden",
+						Status: http.StatusForbidden,
+						Detail: fmt.Sprintf("You are not permitted %s.", action),
+					})
+				}
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// Identity returns the authenticated caller, if Authenticate has run.
+func Identity(ctx echo.Context) (auth.Identity, bool) {
+	id, ok := ctx.Get(contextKeyIdentity).(auth.Identity)
+	return id, ok
+}
+
+// unauthorized responds 401 with an RFC 6750 challenge.
+func unauthorized(ctx echo.Context, challenge, detail string) error {
+	ctx.Response().Header().Set(echo.HeaderWWWAuthenticate, challenge)
+	return WriteProblem(ctx, Problem{
+		Type:   ProblemUnauthorized,
+		Title:  "Unauthorized",
+		Status: http.StatusUnauthorized,
+		Detail: detail,
+	})
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAuthKey = []byte("handlers-test-key-0123456789abcdef")
+
+func TestAuthenticate(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		verifier, err := auth.NewStaticVerifier(testAuthKey, auth.Options{Audience: "titanbay"})
+		assert.NoError(t, err)
+		h := Handler{Db: db}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(middleware.RequestID())
+		e.Use(h.Authenticate(verifier))
+		e.Use(AuditSource)
+		e.POST("/funds", h.CreateFund)
+		e.GET("/audit", h.ReadAuditLog)
+
+		serve := func(method, target, body string, header http.Header) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			for name, values := range header {
+				req.Header[name] = values
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+		bearer := func(token string) http.Header {
+			return http.Header{echo.HeaderAuthorization: {"Bearer " + token}}
+		}
+		sign := func(subject string, ttl time.Duration, opts auth.Options) string {
+			token, err := auth.Sign(testAuthKey, subject, ttl, opts, nil)
+			assert.NoError(t, err)
+			return token
+		}
+
+		rec := serve(http.MethodGet, "/audit", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get(echo.HeaderWWWAuthenticate))
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, ProblemUnauthorized, problem.Type)
+		assert.NotEmpty(t, problem.RequestID)
+
+		for name, header := range map[string]http.Header{
+			"basic":     {echo.HeaderAuthorization: {"Basic YWxpY2U6c2VjcmV0"}},
+			"garbage":   bearer("not-a-token"),
+			"expired":   bearer(sign("alice", -time.Hour, auth.Options{Audience: "titanbay"})),
+			"wrong aud": bearer(sign("alice", time.Hour, auth.Options{Audience: "elsewhere"})),
+		} {
+			rec := serve(http.MethodGet, "/audit", "", header)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code, name)
+			assert.Equal(t, ProblemUnauthorized, decodeProblem(t, rec).Type, name)
+		}
+		rec = serve(http.MethodGet, "/nowhere", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "unknown paths are not revealed")
+		rec = serve(http.MethodGet, "/audit", "", bearer("not-a-token"))
+		assert.Contains(t, rec.Header().Get(echo.HeaderWWWAuthenticate), `error="invalid_token"`)
+
+		token := sign("alice", time.Hour, auth.Options{Audience: "titanbay"})
+		header := bearer(token)
+		header.Set(headerActor, "mallory")
+		rec = serve(http.MethodPost, "/funds", `{"name":"Fund A","vintage_year":2021,"target_size_usd":1000000,"status":"Fundraising"}`, header)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = serve(http.MethodGet, "/audit", "", bearer(token))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var entries models.Page[models.AuditEntry]
+		_ = json.Unmarshal(rec.Body.Bytes(), &entries)
+		if assert.Len(t, entries.Items, 1) {
+			assert.Equal(t, "alice", entries.Items[0].Actor, "the token's subject overrides X-Actor")
+		}
+	})
+}
+
+func TestAuthorize(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		verifier, err := auth.NewStaticVerifier(testAuthKey, auth.Options{})
+		assert.NoError(t, err)
+		h := Handler{Db: db}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(h.Authenticate(verifier))
+		e.GET("/funds", h.ReadFunds, Authorize(auth.FundRead))
+		e.POST("/funds", h.CreateFund, Authorize(auth.FundCreate))
+		e.GET("/funds/:fund_id", h.ReadFundByID, Authorize(auth.FundRead))
+		e.GET("/audit", h.ReadAuditLog, Authorize(auth.AuditRead))
+
+		serve := func(method, target, body string, claims map[string]any) *httptest.ResponseRecorder {
+			token, err := auth.Sign(testAuthKey, "caller", time.Hour, auth.Options{}, claims)
+			assert.NoError(t, err)
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+		admin := map[string]any{"roles": []string{"admin"}}
+		createFund := func(name string) models.Fund {
+			rec := serve(http.MethodPost, "/funds", `{"name":"`+name+`","vintage_year":2021,"target_size_usd":1000000,"status":"Fundraising"}`, admin)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			var fund models.Fund
+			_ = json.Unmarshal(rec.Body.Bytes(), &fund)
+			return fund
+		}
+		fundA, fundB := createFund("Fund A"), createFund("Fund B")
+
+		rec := serve(http.MethodPost, "/funds", `{}`, map[string]any{"roles": []string{"lp"}})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, ProblemForbidden, problem.Type)
+		assert.Contains(t, problem.Detail, "fund:create")
+		rec = serve(http.MethodGet, "/audit", "", map[string]any{"roles": []string{"fund-manager"}})
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		rec = serve(http.MethodGet, "/funds", "", nil)
+		assert.Equal(t, http.StatusForbidden, rec.Code, "callers without roles may do nothing")
+
+		manager := map[string]any{"roles": []string{"fund-manager"}, "fund_ids": []string{fundA.ID.String()}}
+		rec = serve(http.MethodGet, "/funds", "", manager)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var page models.Page[models.Fund]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		if assert.Len(t, page.Items, 1, "managers only see their own funds") {
+			assert.Equal(t, fundA.ID, page.Items[0].ID)
+		}
+		rec = serve(http.MethodGet, "/funds/"+fundB.ID.String(), "", manager)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = serve(http.MethodGet, "/funds/"+fundB.ID.String(), "", map[string]any{"roles": []string{"read-only"}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
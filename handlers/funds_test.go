@@ -2,14 +2,18 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/audit"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
 	"github.com/iuhmirza/titanbay-take-home/models"
 	"github.com/labstack/echo/v4"
 	"github.com/shopspring/decimal"
@@ -17,30 +21,31 @@ import (
 )
 
 func TestCreateFund_Success(t *testing.T) {
-	e := echo.New()
-	db := database.NewMockDb()
-	h := Handler{Db: db}
-
-	req := httptest.NewRequest(http.MethodPost, "/funds", marshal(models.CreateFund{
-		Name:          "Fund I",
-		VintageYear:   2020,
-		TargetSizeUsd: decimal.NewFromInt(5_000_000),
-		Status:        "Fundraising",
-	}))
-	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-	rec := httptest.NewRecorder()
-
-	ctx := e.NewContext(req, rec)
-
-	err := h.CreateFund(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusCreated, rec.Code)
-
-	var got models.Fund
-	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
-	assert.Equal(t, "Fund I", got.Name)
-	assert.False(t, got.ID == uuid.Nil)
-	assert.WithinDuration(t, time.Now(), got.CreatedAt, 2*time.Second)
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		req := httptest.NewRequest(http.MethodPost, "/funds", marshal(models.CreateFund{
+			Name:          "Fund I",
+			VintageYear:   2020,
+			TargetSizeUsd: decimal.NewFromInt(5_000_000),
+			Status:        "Fundraising",
+		}))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		ctx := e.NewContext(req, rec)
+
+		err := h.CreateFund(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var got models.Fund
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, "Fund I", got.Name)
+		assert.False(t, got.ID == uuid.Nil)
+		assert.WithinDuration(t, time.Now(), got.CreatedAt, 2*time.Second)
+	})
 }
 
 func TestCreateFund_BadJSON(t *testing.T) {
@@ -57,9 +62,9 @@ func TestCreateFund_BadJSON(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-	var resp map[string]any
-	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
-	assert.Contains(t, resp["error"], "Invalid JSON payload")
+	problem := decodeProblem(t, rec)
+	assert.Equal(t, ProblemInvalidRequest, problem.Type)
+	assert.Contains(t, problem.Detail, "Invalid JSON payload")
 }
 
 func TestCreateFund_ValidationError(t *testing.T) {
@@ -70,7 +75,7 @@ func TestCreateFund_ValidationError(t *testing.T) {
 		Name:          "",
 		VintageYear:   2020,
 		TargetSizeUsd: decimal.NewFromInt(10),
-		Status:        "Fundraising",
+		Status:        "Open",
 	}
 
 	req := httptest.NewRequest(http.MethodPost, "/funds", marshal(body))
@@ -82,80 +87,90 @@ func TestCreateFund_ValidationError(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 
-	var resp map[string]any
-	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
-	assert.Contains(t, resp["error"], "Bad fund request")
+	problem := decodeProblem(t, rec)
+	assert.Equal(t, ProblemValidationFailed, problem.Type)
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, []models.FieldError{
+		{Field: "name", Message: "name is required"},
+		{Field: "target_size_usd", Message: "target_size_usd must be greater than or equal to 1,000,000.00"},
+		{Field: "status", Message: "status must be either 'Fundraising', 'Investing', or 'Closed'"},
+	}, problem.Errors)
 }
 
 func TestReadFunds_EmptyOK(t *testing.T) {
-	e := echo.New()
-	h := Handler{Db: database.NewMockDb()}
-
-	req := httptest.NewRequest(http.MethodGet, "/funds", nil)
-	rec := httptest.NewRecorder()
-	ctx := e.NewContext(req, rec)
-
-	err := h.ReadFunds(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, rec.Code)
-
-	var funds []models.Fund
-	_ = json.Unmarshal(rec.Body.Bytes(), &funds)
-	assert.Len(t, funds, 0)
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		req := httptest.NewRequest(http.MethodGet, "/funds", nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.ReadFunds(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var page models.Page[models.Fund]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 0)
+		assert.Empty(t, page.NextCursor)
+	})
 }
 
 func TestReadFunds_WithData(t *testing.T) {
-	e := echo.New()
-	db := database.NewMockDb()
-	h := Handler{Db: db}
-
-	_, _ = db.CreateFund(models.CreateFund{
-		Name:          "Fund A",
-		VintageYear:   2019,
-		TargetSizeUsd: decimal.NewFromInt(1_000_000),
-		Status:        "Investing",
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		_, _ = db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund A",
+			VintageYear:   2019,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/funds", nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.ReadFunds(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var page models.Page[models.Fund]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "Fund A", page.Items[0].Name)
 	})
-
-	req := httptest.NewRequest(http.MethodGet, "/funds", nil)
-	rec := httptest.NewRecorder()
-	ctx := e.NewContext(req, rec)
-
-	err := h.ReadFunds(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, rec.Code)
-
-	var funds []models.Fund
-	_ = json.Unmarshal(rec.Body.Bytes(), &funds)
-	assert.Len(t, funds, 1)
-	assert.Equal(t, "Fund A", funds[0].Name)
 }
 
 func TestReadFundByID_Success(t *testing.T) {
-	e := echo.New()
-	db := database.NewMockDb()
-	h := Handler{Db: db}
-
-	f, _ := db.CreateFund(models.CreateFund{
-		Name:          "FindMe",
-		VintageYear:   2021,
-		TargetSizeUsd: decimal.NewFromInt(2_000_000),
-		Status:        "Fundraising",
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		f, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "FindMe",
+			VintageYear:   2021,
+			TargetSizeUsd: decimal.NewFromInt(2_000_000),
+			Status:        "Fundraising",
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/funds/"+f.ID.String(), nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(f.ID.String())
+
+		err := h.ReadFundByID(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var got models.Fund
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+		assert.Equal(t, f.ID, got.ID)
+		assert.Equal(t, "FindMe", got.Name)
 	})
-
-	req := httptest.NewRequest(http.MethodGet, "/funds/"+f.ID.String(), nil)
-	rec := httptest.NewRecorder()
-	ctx := e.NewContext(req, rec)
-	ctx.SetParamNames("id")
-	ctx.SetParamValues(f.ID.String())
-
-	err := h.ReadFundByID(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, rec.Code)
-
-	var got models.Fund
-	_ = json.Unmarshal(rec.Body.Bytes(), &got)
-	assert.Equal(t, f.ID, got.ID)
-	assert.Equal(t, "FindMe", got.Name)
 }
 
 func TestReadFundByID_InvalidUUID(t *testing.T) {
@@ -165,7 +180,7 @@ func TestReadFundByID_InvalidUUID(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/funds/not-a-uuid", nil)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
-	ctx.SetParamNames("id")
+	ctx.SetParamNames("fund_id")
 	ctx.SetParamValues("not-a-uuid")
 
 	err := h.ReadFundByID(ctx)
@@ -182,7 +197,7 @@ func TestReadFundByID_NotFoundMapsTo404(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/funds/"+id.String(), nil)
 	rec := httptest.NewRecorder()
 	ctx := e.NewContext(req, rec)
-	ctx.SetParamNames("id")
+	ctx.SetParamNames("fund_id")
 	ctx.SetParamValues(id.String())
 
 	err := h.ReadFundByID(ctx)
@@ -191,38 +206,39 @@ func TestReadFundByID_NotFoundMapsTo404(t *testing.T) {
 }
 
 func TestUpdateFund_Success(t *testing.T) {
-	e := echo.New()
-	db := database.NewMockDb()
-	h := Handler{Db: db}
-
-	seed, _ := db.CreateFund(models.CreateFund{
-		Name:          "Old",
-		VintageYear:   2010,
-		TargetSizeUsd: decimal.NewFromInt(1_500_000),
-		Status:        "Investing",
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		seed, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Old",
+			VintageYear:   2010,
+			TargetSizeUsd: decimal.NewFromInt(1_500_000),
+			Status:        "Investing",
+		})
+
+		update := models.Fund{
+			ID:            seed.ID,
+			Name:          "New",
+			VintageYear:   seed.VintageYear,
+			TargetSizeUsd: seed.TargetSizeUsd,
+			Status:        seed.Status,
+			CreatedAt:     seed.CreatedAt,
+		}
+
+		req := httptest.NewRequest(http.MethodPut, "/funds", marshal(update))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.UpdateFund(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var got models.Fund
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+		assert.Equal(t, "New", got.Name)
 	})
-
-	update := models.Fund{
-		ID:            seed.ID,
-		Name:          "New",
-		VintageYear:   seed.VintageYear,
-		TargetSizeUsd: seed.TargetSizeUsd,
-		Status:        seed.Status,
-		CreatedAt:     seed.CreatedAt,
-	}
-
-	req := httptest.NewRequest(http.MethodPut, "/funds", marshal(update))
-	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-	rec := httptest.NewRecorder()
-	ctx := e.NewContext(req, rec)
-
-	err := h.UpdateFund(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, rec.Code)
-
-	var got models.Fund
-	_ = json.Unmarshal(rec.Body.Bytes(), &got)
-	assert.Equal(t, "New", got.Name)
 }
 
 func TestUpdateFund_NotFoundMapsTo404(t *testing.T) {
@@ -259,4 +275,472 @@ func TestUpdateFund_BadJSON(t *testing.T) {
 	err := h.UpdateFund(ctx)
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
-}
\ No newline at end of file
+}
+func TestReadFunds_PaginatesWithCursor(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		for year := 2010; year < 2015; year++ {
+			_, _ = db.CreateFund(context.Background(), models.CreateFund{
+				Name:          "Fund",
+				VintageYear:   year,
+				TargetSizeUsd: decimal.NewFromInt(1_000_000),
+				Status:        "Investing",
+			})
+		}
+
+		var years []int
+		cursor := ""
+		for pages := 0; pages < 5; pages++ {
+			req := httptest.NewRequest(http.MethodGet, "/funds?limit=2&sort=-vintage_year&cursor="+cursor, nil)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+
+			err := h.ReadFunds(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var page models.Page[models.Fund]
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+			for _, f := range page.Items {
+				years = append(years, f.VintageYear)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+
+		assert.Equal(t, []int{2014, 2013, 2012, 2011, 2010}, years)
+	})
+}
+
+func TestReadFunds_Filters(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		for i, status := range []string{"Fundraising", "Investing", "Investing", "Closed"} {
+			_, _ = db.CreateFund(context.Background(), models.CreateFund{
+				Name:          "Fund",
+				VintageYear:   2018 + i,
+				TargetSizeUsd: decimal.NewFromInt(1_000_000),
+				Status:        status,
+			})
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/funds?status=Investing&vintage_year_min=2020", nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.ReadFunds(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var page models.Page[models.Fund]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, 2020, page.Items[0].VintageYear)
+	})
+}
+
+func TestReadFunds_InvalidQuery(t *testing.T) {
+	e := echo.New()
+	h := Handler{Db: database.NewMockDb()}
+
+	for _, query := range []string{
+		"sort=status",
+		"limit=1000",
+		"limit=-1",
+		"limit=abc",
+		"cursor=garbage",
+		"status=Open",
+		"vintage_year_min=2020&vintage_year_max=2010",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/funds?"+query, nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.ReadFunds(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
+
+func TestReadFunds_ZeroLimit(t *testing.T) {
+	e := echo.New()
+	h := Handler{Db: database.NewMockDb()}
+
+	req := httptest.NewRequest(http.MethodGet, "/funds?limit=0", nil)
+	rec := httptest.NewRecorder()
+	assert.NoError(t, h.ReadFunds(e.NewContext(req, rec)))
+	assert.Equal(t, http.StatusOK, rec.Code, "0 means the default page size")
+
+	req = httptest.NewRequest(http.MethodGet, "/funds?limit=1000", nil)
+	rec = httptest.NewRecorder()
+	assert.NoError(t, h.ReadFunds(e.NewContext(req, rec)))
+	assert.Contains(t, rec.Body.String(), "or 0 for the default")
+}
+
+func TestReadFunds_CursorFromDifferentSortRejected(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		for i := 0; i < 2; i++ {
+			_, _ = db.CreateFund(context.Background(), models.CreateFund{
+				Name:          "Fund",
+				VintageYear:   2020,
+				TargetSizeUsd: decimal.NewFromInt(1_000_000),
+				Status:        "Closed",
+			})
+		}
+		page, err := db.ReadFunds(context.Background(), models.FundQuery{PageQuery: models.PageQuery{Limit: 1, Sort: "name"}})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, page.NextCursor)
+
+		req := httptest.NewRequest(http.MethodGet, "/funds?sort=-name&cursor="+page.NextCursor, nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err = h.ReadFunds(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestReadFundByID_UnknownFundMapsTo404(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		id := uuid.New()
+		req := httptest.NewRequest(http.MethodGet, "/funds/"+id.String(), nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(id.String())
+
+		err := h.ReadFundByID(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func patchFund(t *testing.T, h Handler, id string, body string, ifMatch string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPatch, "/funds/"+id, bytes.NewBufferString(body))
+	req.Header.Set(echo.HeaderContentType, MIMEApplicationMergePatchJSON)
+	if ifMatch != "" {
+		req.Header.Set(headerIfMatch, ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("fund_id")
+	ctx.SetParamValues(id)
+
+	err := h.PatchFund(ctx)
+	assert.NoError(t, err)
+	return rec
+}
+
+func TestPatchFund_MergesAndBumpsVersion(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		h := Handler{Db: db}
+		seed, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund I",
+			VintageYear:   2020,
+			TargetSizeUsd: decimal.NewFromInt(5_000_000),
+			Status:        "Fundraising",
+		})
+
+		rec := patchFund(t, h, seed.ID.String(), `{"status":"Investing"}`, "")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get(headerETag))
+
+		var got models.Fund
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, "Investing", got.Status)
+		assert.Equal(t, "Fund I", got.Name, "fields absent from the patch are kept")
+		assert.True(t, seed.TargetSizeUsd.Equal(got.TargetSizeUsd))
+		assert.Equal(t, 2, got.Version)
+	})
+}
+
+func TestPatchFund_IfMatch(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		h := Handler{Db: db}
+		seed, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund I",
+			VintageYear:   2020,
+			TargetSizeUsd: decimal.NewFromInt(5_000_000),
+			Status:        "Fundraising",
+		})
+		id := seed.ID.String()
+
+		rec := patchFund(t, h, id, `{"name":"First"}`, `"1"`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// A second editor still holding version 1 must not overwrite the first.
+		rec = patchFund(t, h, id, `{"name":"Second"}`, `"1"`)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, ProblemPreconditionFailed, problem.Type)
+
+		rec = patchFund(t, h, id, `{"name":"Second"}`, `W/"2"`)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code, "weak tags never match")
+
+		rec = patchFund(t, h, id, `{"name":"Second"}`, `"7", "2"`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get(headerETag))
+
+		rec = patchFund(t, h, id, `{"name":"Third"}`, `*`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestPatchFund_InvalidPatches(t *testing.T) {
+	db := database.NewMockDb()
+	h := Handler{Db: db}
+	seed, _ := db.CreateFund(context.Background(), models.CreateFund{
+		Name:          "Fund I",
+		VintageYear:   2020,
+		TargetSizeUsd: decimal.NewFromInt(5_000_000),
+		Status:        "Fundraising",
+	})
+	id := seed.ID.String()
+
+	cases := []struct {
+		name   string
+		body   string
+		ptype  string
+		fields []string
+	}{
+		{"not an object", `["name"]`, ProblemInvalidRequest, nil},
+		{"wrong type", `{"vintage_year":"soon"}`, ProblemInvalidRequest, nil},
+		{"read-only member", `{"id":"x","version":9}`, ProblemValidationFailed, []string{"id", "version"}},
+		{"null removes a required field", `{"name":null}`, ProblemValidationFailed, []string{"name"}},
+		{"invalid value", `{"status":"Open"}`, ProblemValidationFailed, []string{"status"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := patchFund(t, h, id, tc.body, "")
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			problem := decodeProblem(t, rec)
+			assert.Equal(t, tc.ptype, problem.Type)
+			var fields []string
+			for _, fe := range problem.Errors {
+				fields = append(fields, fe.Field)
+			}
+			assert.ElementsMatch(t, tc.fields, fields)
+		})
+	}
+
+	got, _ := db.ReadFundByID(context.Background(), seed.ID)
+	assert.Equal(t, 1, got.Version, "rejected patches change nothing")
+}
+
+func TestPatchFund_NotFound(t *testing.T) {
+	h := Handler{Db: database.NewMockDb()}
+
+	rec := patchFund(t, h, uuid.NewString(), `{"name":"Ghost"}`, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUpdateFund_StaleIfMatchMapsTo412(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+		seed, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund I",
+			VintageYear:   2020,
+			TargetSizeUsd: decimal.NewFromInt(5_000_000),
+			Status:        "Fundraising",
+		})
+		seed.Name = "Renamed elsewhere"
+		_, _ = db.UpdateFund(context.Background(), seed)
+
+		seed.Name = "Renamed here"
+		req := httptest.NewRequest(http.MethodPut, "/funds", marshal(seed))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(headerIfMatch, `"1"`)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.UpdateFund(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+		got, _ := db.ReadFundByID(context.Background(), seed.ID)
+		assert.Equal(t, "Renamed elsewhere", got.Name)
+	})
+}
+
+const deleteBody = `{"reason":"created in error"}`
+
+func deleteFund(t *testing.T, h Handler, id string, body string, ifMatch string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/funds/"+id, bytes.NewBufferString(body))
+	req = req.WithContext(audit.NewContext(req.Context(), audit.Source{Actor: "ops@example.com"}))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if ifMatch != "" {
+		req.Header.Set(headerIfMatch, ifMatch)
+	}
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.SetParamNames("fund_id")
+	ctx.SetParamValues(id)
+
+	err := h.DeleteFund(ctx)
+	assert.NoError(t, err)
+	return rec
+}
+
+func TestDeleteFund_HiddenUntilRestored(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+		seed, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund D",
+			VintageYear:   2021,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Closed",
+		})
+		id := seed.ID.String()
+
+		rec := deleteFund(t, h, id, deleteBody, `"1"`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"2"`, rec.Header().Get(headerETag))
+		var deleted models.Fund
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &deleted))
+		assert.NotNil(t, deleted.DeletedAt)
+		assert.Equal(t, "ops@example.com", deleted.DeletedBy)
+		assert.Equal(t, "created in error", deleted.DeleteReason)
+
+		read := func(target string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+			ctx.SetParamNames("fund_id")
+			ctx.SetParamValues(id)
+			assert.NoError(t, h.ReadFundByID(ctx))
+			return rec
+		}
+		assert.Equal(t, http.StatusNotFound, read("/funds/"+id).Code)
+		assert.Equal(t, http.StatusOK, read("/funds/"+id+"?include_deleted=true").Code)
+		assert.Equal(t, http.StatusBadRequest, read("/funds/"+id+"?include_deleted=maybe").Code)
+
+		list := func(target string) models.Page[models.Fund] {
+			rec := httptest.NewRecorder()
+			assert.NoError(t, h.ReadFunds(e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)))
+			var page models.Page[models.Fund]
+			_ = json.Unmarshal(rec.Body.Bytes(), &page)
+			return page
+		}
+		assert.Empty(t, list("/funds").Items)
+		assert.Len(t, list("/funds?include_deleted=true").Items, 1)
+
+		rec = httptest.NewRecorder()
+		ctx := e.NewContext(httptest.NewRequest(http.MethodPost, "/funds/"+id+"/restore", nil), rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(id)
+		assert.NoError(t, h.RestoreFund(ctx))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"3"`, rec.Header().Get(headerETag))
+		assert.Equal(t, http.StatusOK, read("/funds/"+id).Code)
+		assert.NotContains(t, rec.Body.String(), "deleted_at")
+	})
+}
+
+func TestDeleteFund_WithLiveInvestmentsMapsTo409(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		h := Handler{Db: db}
+		fund, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund E",
+			VintageYear:   2021,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+		investor, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Eve",
+			InvestorType: "Institution",
+			Email:        "eve@inst.com",
+		})
+		_, _ = db.CreateInvestment(context.Background(), models.CreateInvestment{
+			InvestorID:     investor.ID,
+			FundID:         fund.ID,
+			AmountUsd:      decimal.NewFromInt(250_000),
+			InvestmentDate: "2024-02-01",
+		})
+
+		rec := deleteFund(t, h, fund.ID.String(), deleteBody, "")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, ProblemConflict, problem.Type)
+		assert.Equal(t, "fund has live investments", problem.Detail)
+	})
+}
+
+func TestDeleteFund_InvalidRequests(t *testing.T) {
+	db := database.NewMockDb()
+	h := Handler{Db: db}
+	fund, _ := db.CreateFund(context.Background(), models.CreateFund{
+		Name:          "Fund F",
+		VintageYear:   2021,
+		TargetSizeUsd: decimal.NewFromInt(1_000_000),
+		Status:        "Fundraising",
+	})
+	id := fund.ID.String()
+
+	rec := deleteFund(t, h, id, `{}`, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	problem := decodeProblem(t, rec)
+	assert.Equal(t, ProblemValidationFailed, problem.Type)
+	assert.Len(t, problem.Errors, 1)
+
+	rec = deleteFund(t, h, id, deleteBody, `"7"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	got, _ := db.ReadFundByID(context.Background(), fund.ID)
+	assert.False(t, got.Deleted(), "a failed precondition leaves the fund live")
+
+	rec = deleteFund(t, h, uuid.NewString(), deleteBody, "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestReadFundByID_AsOf(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+		seed, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund T",
+			VintageYear:   2021,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Fundraising",
+		})
+		id := seed.ID.String()
+		time.Sleep(5 * time.Millisecond)
+		asOf := url.QueryEscape(time.Now().Format(time.RFC3339Nano))
+		time.Sleep(5 * time.Millisecond)
+		seed.Status = "Investing"
+		_, err := db.UpdateFund(context.Background(), seed)
+		assert.NoError(t, err)
+
+		read := func(target string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), rec)
+			ctx.SetParamNames("fund_id")
+			ctx.SetParamValues(id)
+			assert.NoError(t, h.ReadFundByID(ctx))
+			return rec
+		}
+		rec := read("/funds/" + id + "?as_of=" + asOf)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"1"`, rec.Header().Get(headerETag))
+		var got models.Fund
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, "Fundraising", got.Status)
+
+		assert.Equal(t, http.StatusNotFound, read("/funds/"+id+"?as_of=2000-01-01T00:00:00Z").Code)
+		assert.Equal(t, http.StatusBadRequest, read("/funds/"+id+"?as_of=yesterday").Code)
+	})
+}
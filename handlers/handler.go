@@ -1,50 +1,56 @@
 package handlers
 
 import (
-	"errors"
-	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
 	"github.com/iuhmirza/titanbay-take-home/models"
 	"github.com/labstack/echo/v4"
-	"gorm.io/gorm"
 )
 
 type Handler struct {
 	Db database.Db
-}
-
-func JSONError(ctx echo.Context, httpStatus int, errorMessage string, err error) error {
-	return ctx.JSON(httpStatus, echo.Map{"error": fmt.Sprintf("%v: %v", errorMessage, err)})
+	// InsecureWebhooks lets webhooks use plain http, for development
+	// against local receivers.
+	InsecureWebhooks bool
 }
 
 func (h Handler) CreateFund(ctx echo.Context) error {
 	createFund := models.CreateFund{}
 	if err := ctx.Bind(&createFund); err != nil {
-		return ctx.JSON(http.StatusBadRequest, echo.Map{
-			"error": fmt.Sprintf("Invalid JSON payload: %v", err),
-		})
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
 	}
 	if err := createFund.Validate(); err != nil {
-		return ctx.JSON(http.StatusBadRequest, echo.Map{
-			"error": fmt.Sprintf("Bad fund request: %v", err),
-		})
+		return ValidationFailed(ctx, err)
 	}
 	// add row to db funds table using gorm
-	fund, err := h.Db.CreateFund(createFund)
+	fund, err := h.Db.CreateFund(ctx.Request().Context(), createFund)
 	if err != nil {
-		// consider not exposing db error
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to write fund to database", err)
+		return DbError(ctx, "Failed to write fund to database", err)
 	}
+	setETag(ctx, fund.Version)
 	return ctx.JSON(http.StatusCreated, fund)
 }
 
 func (h Handler) ReadFunds(ctx echo.Context) error {
-	funds, err := h.Db.ReadFunds()
+	var query models.FundQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+	db, err := h.reader(ctx)
 	if err != nil {
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to read funds from database", err)
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+
+	funds, err := db.ReadFunds(ctx.Request().Context(), query)
+	if err != nil {
+		return DbError(ctx, "Failed to read funds from database", err)
 	}
 	return ctx.JSON(http.StatusOK, funds)
 }
@@ -52,82 +58,341 @@ func (h Handler) ReadFunds(ctx echo.Context) error {
 func (h Handler) CreateInvestor(ctx echo.Context) error {
 	ci := models.CreateInvestor{}
 	if err := ctx.Bind(&ci); err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Invalid JSON payload", err)
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
 	}
 	if err := ci.Validate(); err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return ValidationFailed(ctx, err)
 	}
 
-	fund, err := h.Db.CreateInvestor(ci)
+	investor, err := h.Db.CreateInvestor(ctx.Request().Context(), ci)
 	if err != nil {
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to write fund to database", err)
+		return DbError(ctx, "Failed to write investor to database", err)
 	}
-	return ctx.JSON(http.StatusCreated, fund)
+	setETag(ctx, investor.Version)
+	return ctx.JSON(http.StatusCreated, investor)
 }
 
+// ReadFundByID reports a deleted fund as not found, unless include_deleted
+// is set. Like every read endpoint, it accepts as_of to read the fund as it
+// was at that instant.
 func (h Handler) ReadFundByID(ctx echo.Context) error {
-	id, err := uuid.Parse(ctx.Param("id"))
+	id, err := uuid.Parse(ctx.Param("fund_id"))
 	if err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Invalid UUID provided in path parameter", err)
+		return InvalidRequest(ctx, "Invalid UUID provided in path parameter", err)
+	}
+	include, err := includeDeleted(ctx)
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	db, err := h.reader(ctx)
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
 	}
 
-	fund, err := h.Db.ReadFundByID(id)
+	fund, err := db.ReadFundByID(ctx.Request().Context(), id)
+	if err == nil && fund.Deleted() && !include {
+		err = &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
+	}
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return JSONError(ctx, http.StatusNotFound, "Fund not found", err)
-		}
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to read fund from database", err)
+		return DbError(ctx, "Failed to read fund from database", err)
 	}
 
+	setETag(ctx, fund.Version)
 	return ctx.JSON(http.StatusOK, fund)
 }
 
+// UpdateFund replaces a fund. The version to replace comes from If-Match
+// or, failing that, the body's version field; with neither, the update is
+// unconditional.
 func (h Handler) UpdateFund(ctx echo.Context) error {
 	var fund models.Fund
 	if err := ctx.Bind(&fund); err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Invalid JSON payload", err)
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
+	}
+	writable := fund.Writable()
+	if err := writable.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
 	}
 
-	updated, err := h.Db.UpdateFund(fund)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return JSONError(ctx, http.StatusNotFound, "Fund not found", err)
+	pre := ifMatch(ctx)
+	reqCtx := ctx.Request().Context()
+	var updated models.Fund
+	err := h.Db.WithTx(reqCtx, func(tx database.Db) error {
+		if pre.present {
+			current, err := tx.ReadFundByID(reqCtx, fund.ID)
+			if err != nil {
+				return err
+			}
+			if err := pre.check("fund", current.Version); err != nil {
+				return err
+			}
+			fund.Version = current.Version
 		}
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to update fund", err)
+		var err error
+		updated, err = tx.UpdateFund(reqCtx, fund)
+		return err
+	})
+	if err != nil {
+		return DbError(ctx, "Failed to update fund", err)
 	}
 
+	setETag(ctx, updated.Version)
 	return ctx.JSON(http.StatusOK, updated)
 }
 
+// PatchFund applies a JSON Merge Patch to a fund's writable fields.
+func (h Handler) PatchFund(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("fund_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
+	}
+	patch, err := bindMergePatch(ctx, &models.CreateFund{})
+	if err != nil {
+		return invalidMergePatch(ctx, err)
+	}
+
+	pre := ifMatch(ctx)
+	reqCtx := ctx.Request().Context()
+	var updated models.Fund
+	err = retryUnconditional(pre, func() error {
+		return h.Db.WithTx(reqCtx, func(tx database.Db) error {
+			fund, err := tx.ReadFundByID(reqCtx, id)
+			if err != nil {
+				return err
+			}
+			if err := pre.check("fund", fund.Version); err != nil {
+				return err
+			}
+			var patched models.CreateFund
+			if err := applyMergePatch(fund.Writable(), patch, &patched); err != nil {
+				return err
+			}
+			if err := patched.Validate(); err != nil {
+				return err
+			}
+			fund.Name = patched.Name
+			fund.VintageYear = patched.VintageYear
+			fund.TargetSizeUsd = patched.TargetSizeUsd
+			fund.Status = patched.Status
+			updated, err = tx.UpdateFund(reqCtx, fund)
+			return err
+		})
+	})
+	if err != nil {
+		return updateFailed(ctx, "Failed to update fund", err)
+	}
+
+	setETag(ctx, updated.Version)
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+// DeleteFund soft-deletes a fund. If-Match is checked against the version
+// the deletion replaced, inside the same transaction, so that it cannot race
+// with another writer.
+func (h Handler) DeleteFund(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("fund_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
+	}
+	var del models.DeleteResource
+	if err := ctx.Bind(&del); err != nil {
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
+	}
+	if err := del.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	pre := ifMatch(ctx)
+	reqCtx := ctx.Request().Context()
+	var fund models.Fund
+	err = h.Db.WithTx(reqCtx, func(tx database.Db) error {
+		var err error
+		if fund, err = tx.DeleteFund(reqCtx, id, del); err != nil {
+			return err
+		}
+		return pre.check("fund", fund.Version-1)
+	})
+	if err != nil {
+		return DbError(ctx, "Failed to delete fund", err)
+	}
+
+	setETag(ctx, fund.Version)
+	return ctx.JSON(http.StatusOK, fund)
+}
+
+func (h Handler) RestoreFund(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("fund_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
+	}
+
+	fund, err := h.Db.RestoreFund(ctx.Request().Context(), id)
+	if err != nil {
+		return DbError(ctx, "Failed to restore fund", err)
+	}
+
+	setETag(ctx, fund.Version)
+	return ctx.JSON(http.StatusOK, fund)
+}
+
 func (h Handler) ReadInvestors(ctx echo.Context) error {
-	investors, err := h.Db.ReadInvestors()
+	var query models.InvestorQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+	db, err := h.reader(ctx)
 	if err != nil {
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to fetch investors", err)
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+
+	investors, err := db.ReadInvestors(ctx.Request().Context(), query)
+	if err != nil {
+		return DbError(ctx, "Failed to fetch investors", err)
 	}
 	return ctx.JSON(http.StatusOK, investors)
 }
 
+// ReadInvestorByID reports a deleted investor as not found, unless
+// include_deleted is set.
+func (h Handler) ReadInvestorByID(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("investor_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for investor_id", err)
+	}
+	include, err := includeDeleted(ctx)
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	db, err := h.reader(ctx)
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+
+	investor, err := db.ReadInvestorByID(ctx.Request().Context(), id)
+	if err == nil && investor.Deleted() && !include {
+		err = &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+	}
+	if err != nil {
+		return DbError(ctx, "Failed to read investor from database", err)
+	}
+
+	setETag(ctx, investor.Version)
+	return ctx.JSON(http.StatusOK, investor)
+}
+
+// PatchInvestor applies a JSON Merge Patch to an investor's writable fields.
+func (h Handler) PatchInvestor(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("investor_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for investor_id", err)
+	}
+	patch, err := bindMergePatch(ctx, &models.CreateInvestor{})
+	if err != nil {
+		return invalidMergePatch(ctx, err)
+	}
+
+	pre := ifMatch(ctx)
+	reqCtx := ctx.Request().Context()
+	var updated models.Investor
+	err = retryUnconditional(pre, func() error {
+		return h.Db.WithTx(reqCtx, func(tx database.Db) error {
+			investor, err := tx.ReadInvestorByID(reqCtx, id)
+			if err != nil {
+				return err
+			}
+			if err := pre.check("investor", investor.Version); err != nil {
+				return err
+			}
+			var patched models.CreateInvestor
+			if err := applyMergePatch(investor.Writable(), patch, &patched); err != nil {
+				return err
+			}
+			if err := patched.Validate(); err != nil {
+				return err
+			}
+			investor.Name = patched.Name
+			investor.InvestorType = patched.InvestorType
+			investor.Email = patched.Email
+			updated, err = tx.UpdateInvestor(reqCtx, investor)
+			return err
+		})
+	})
+	if err != nil {
+		return updateFailed(ctx, "Failed to update investor", err)
+	}
+
+	setETag(ctx, updated.Version)
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+// DeleteInvestor soft-deletes an investor, checking If-Match as DeleteFund
+// does.
+func (h Handler) DeleteInvestor(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("investor_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for investor_id", err)
+	}
+	var del models.DeleteResource
+	if err := ctx.Bind(&del); err != nil {
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
+	}
+	if err := del.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	pre := ifMatch(ctx)
+	reqCtx := ctx.Request().Context()
+	var investor models.Investor
+	err = h.Db.WithTx(reqCtx, func(tx database.Db) error {
+		var err error
+		if investor, err = tx.DeleteInvestor(reqCtx, id, del); err != nil {
+			return err
+		}
+		return pre.check("investor", investor.Version-1)
+	})
+	if err != nil {
+		return DbError(ctx, "Failed to delete investor", err)
+	}
+
+	setETag(ctx, investor.Version)
+	return ctx.JSON(http.StatusOK, investor)
+}
+
+func (h Handler) RestoreInvestor(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("investor_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for investor_id", err)
+	}
+
+	investor, err := h.Db.RestoreInvestor(ctx.Request().Context(), id)
+	if err != nil {
+		return DbError(ctx, "Failed to restore investor", err)
+	}
+
+	setETag(ctx, investor.Version)
+	return ctx.JSON(http.StatusOK, investor)
+}
+
 func (h Handler) CreateInvestment(ctx echo.Context) error {
 	fundID, err := uuid.Parse(ctx.Param("fund_id"))
 	if err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Invalid UUID for fund_id", err)
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
 	}
 	var ci models.CreateInvestment
 	if err := ctx.Bind(&ci); err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Invalid JSON payload", err)
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
 	}
 	ci.FundID = fundID
 	if err := ci.Validate(); err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Validation failed", err)
+		return ValidationFailed(ctx, err)
 	}
 
-	investment, err := h.Db.CreateInvestment(ci)
+	investment, err := h.Db.CreateInvestment(ctx.Request().Context(), ci)
 	if err != nil {
-		if errors.Is(gorm.ErrRecordNotFound, err) {
-			return JSONError(ctx, http.StatusNotFound, "Fund not found", err)
-		}
-		// handle non-existent fund/investor more gracefully
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to create investment", err)
+		return DbError(ctx, "Failed to create investment", err)
 	}
 
 	return ctx.JSON(http.StatusCreated, investment)
@@ -136,16 +401,108 @@ func (h Handler) CreateInvestment(ctx echo.Context) error {
 func (h Handler) ReadInvestments(ctx echo.Context) error {
 	fundID, err := uuid.Parse(ctx.Param("fund_id"))
 	if err != nil {
-		return JSONError(ctx, http.StatusBadRequest, "Invalid UUID for fund_id", err)
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
+	}
+	var query models.InvestmentQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+	db, err := h.reader(ctx)
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
 	}
 
-	investments, err := h.Db.ReadInvestments(fundID)
+	investments, err := db.ReadInvestments(ctx.Request().Context(), fundID, query)
 	if err != nil {
-		if errors.Is(gorm.ErrRecordNotFound, err) {
-			return JSONError(ctx, http.StatusNotFound, "Fund not found", err)
-		}
-		return JSONError(ctx, http.StatusInternalServerError, "Failed to fetch investments", err)
+		return DbError(ctx, "Failed to fetch investments", err)
 	}
 
 	return ctx.JSON(http.StatusOK, investments)
 }
+
+func (h Handler) DeleteInvestment(ctx echo.Context) error {
+	fundID, err := uuid.Parse(ctx.Param("fund_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
+	}
+	id, err := uuid.Parse(ctx.Param("investment_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for investment_id", err)
+	}
+	var del models.DeleteResource
+	if err := ctx.Bind(&del); err != nil {
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
+	}
+	if err := del.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	investment, err := h.Db.DeleteInvestment(ctx.Request().Context(), fundID, id, del)
+	if err != nil {
+		return DbError(ctx, "Failed to delete investment", err)
+	}
+
+	return ctx.JSON(http.StatusOK, investment)
+}
+
+func (h Handler) RestoreInvestment(ctx echo.Context) error {
+	fundID, err := uuid.Parse(ctx.Param("fund_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for fund_id", err)
+	}
+	id, err := uuid.Parse(ctx.Param("investment_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for investment_id", err)
+	}
+
+	investment, err := h.Db.RestoreInvestment(ctx.Request().Context(), fundID, id)
+	if err != nil {
+		return DbError(ctx, "Failed to restore investment", err)
+	}
+
+	return ctx.JSON(http.StatusOK, investment)
+}
+
+func (h Handler) Search(ctx echo.Context) error {
+	var query models.SearchQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+	db, err := h.reader(ctx)
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+
+	results, err := db.Search(ctx.Request().Context(), query)
+	if err != nil {
+		return DbError(ctx, "Failed to search", err)
+	}
+	return ctx.JSON(http.StatusOK, results)
+}
+
+// includeDeleted reads the include_deleted query parameter of a request for
+// a single resource. List endpoints bind it as part of their query.
+func includeDeleted(ctx echo.Context) (bool, error) {
+	var include bool
+	err := echo.QueryParamsBinder(ctx).Bool("include_deleted", &include).BindError()
+	return include, err
+}
+
+// reader returns the Db or, when the as_of query parameter is set, a view of
+// the data at that RFC 3339 instant.
+func (h Handler) reader(ctx echo.Context) (database.Reader, error) {
+	var asOf time.Time
+	if err := echo.QueryParamsBinder(ctx).Time("as_of", &asOf, time.RFC3339Nano).BindError(); err != nil {
+		return nil, err
+	}
+	if asOf.IsZero() {
+		return h.Db, nil
+	}
+	return h.Db.AsOf(asOf), nil
+}
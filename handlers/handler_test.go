@@ -2,44 +2,190 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
 	"github.com/iuhmirza/titanbay-take-home/models"
-	"gorm.io/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
 )
 
 type notFoundDb struct {
 	delegate database.Db
 }
 
-func (n notFoundDb) CreateFund(cf models.CreateFund) (models.Fund, error) {
-	return n.delegate.CreateFund(cf)
+func (n notFoundDb) CreateFund(ctx context.Context, cf models.CreateFund) (models.Fund, error) {
+	return n.delegate.CreateFund(ctx, cf)
 }
 
-func (n notFoundDb) ReadFunds() ([]models.Fund, error) { return n.delegate.ReadFunds() }
+func (n notFoundDb) ReadFunds(ctx context.Context, q models.FundQuery) (models.Page[models.Fund], error) {
+	return n.delegate.ReadFunds(ctx, q)
+}
+
+func (n notFoundDb) UpdateFund(context.Context, models.Fund) (models.Fund, error) {
+	return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
+}
+
+func (n notFoundDb) ReadFundByID(context.Context, uuid.UUID) (models.Fund, error) {
+	return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
+}
+
+func (n notFoundDb) DeleteFund(context.Context, uuid.UUID, models.DeleteResource) (models.Fund, error) {
+	return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
+}
+
+func (n notFoundDb) RestoreFund(context.Context, uuid.UUID) (models.Fund, error) {
+	return models.Fund{}, &dberr.Error{Kind: dberr.NotFound, Entity: "fund"}
+}
+
+func (n notFoundDb) CreateInvestor(ctx context.Context, ci models.CreateInvestor) (models.Investor, error) {
+	return n.delegate.CreateInvestor(ctx, ci)
+}
+
+func (n notFoundDb) ReadInvestors(ctx context.Context, q models.InvestorQuery) (models.Page[models.Investor], error) {
+	return n.delegate.ReadInvestors(ctx, q)
+}
+
+func (n notFoundDb) ReadInvestorByID(context.Context, uuid.UUID) (models.Investor, error) {
+	return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+}
+
+func (n notFoundDb) UpdateInvestor(context.Context, models.Investor) (models.Investor, error) {
+	return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+}
+
+func (n notFoundDb) DeleteInvestor(context.Context, uuid.UUID, models.DeleteResource) (models.Investor, error) {
+	return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+}
+
+func (n notFoundDb) RestoreInvestor(context.Context, uuid.UUID) (models.Investor, error) {
+	return models.Investor{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investor"}
+}
+
+func (n notFoundDb) CreateInvestment(ctx context.Context, ci models.CreateInvestment) (models.Investment, error) {
+	return n.delegate.CreateInvestment(ctx, ci)
+}
+
+func (n notFoundDb) ReadInvestments(ctx context.Context, _ uuid.UUID, q models.InvestmentQuery) (models.Page[models.Investment], error) {
+	return n.delegate.ReadInvestments(ctx, uuid.UUID{}, q)
+}
+
+func (n notFoundDb) DeleteInvestment(context.Context, uuid.UUID, uuid.UUID, models.DeleteResource) (models.Investment, error) {
+	return models.Investment{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investment"}
+}
+
+func (n notFoundDb) RestoreInvestment(context.Context, uuid.UUID, uuid.UUID) (models.Investment, error) {
+	return models.Investment{}, &dberr.Error{Kind: dberr.NotFound, Entity: "investment"}
+}
+
+func (n notFoundDb) Search(ctx context.Context, q models.SearchQuery) (models.Page[models.SearchResult], error) {
+	return n.delegate.Search(ctx, q)
+}
+
+func (n notFoundDb) ReadAuditLog(ctx context.Context, q models.AuditQuery) (models.Page[models.AuditEntry], error) {
+	return n.delegate.ReadAuditLog(ctx, q)
+}
+
+func (n notFoundDb) AsOf(t time.Time) database.Reader {
+	return n.delegate.AsOf(t)
+}
+
+func (n notFoundDb) RebuildProjections(ctx context.Context) (int, error) {
+	return n.delegate.RebuildProjections(ctx)
+}
+
+func (n notFoundDb) CreateWebhook(ctx context.Context, cw models.CreateWebhook) (models.Webhook, error) {
+	return n.delegate.CreateWebhook(ctx, cw)
+}
+
+func (n notFoundDb) ReadWebhooks(ctx context.Context, q models.WebhookQuery) (models.Page[models.Webhook], error) {
+	return n.delegate.ReadWebhooks(ctx, q)
+}
+
+func (n notFoundDb) ReadWebhookByID(context.Context, uuid.UUID) (models.Webhook, error) {
+	return models.Webhook{}, &dberr.Error{Kind: dberr.NotFound, Entity: "webhook"}
+}
+
+func (n notFoundDb) UpdateWebhook(context.Context, models.Webhook) (models.Webhook, error) {
+	return models.Webhook{}, &dberr.Error{Kind: dberr.NotFound, Entity: "webhook"}
+}
+
+func (n notFoundDb) DeleteWebhook(context.Context, uuid.UUID) error {
+	return &dberr.Error{Kind: dberr.NotFound, Entity: "webhook"}
+}
+
+func (n notFoundDb) ReadWebhookDeliveries(ctx context.Context, id uuid.UUID, q models.WebhookDeliveryQuery) (models.Page[models.WebhookDelivery], error) {
+	return n.delegate.ReadWebhookDeliveries(ctx, id, q)
+}
+
+func (n notFoundDb) ReplayWebhookDelivery(context.Context, uuid.UUID, int) (models.WebhookDelivery, error) {
+	return models.WebhookDelivery{}, &dberr.Error{Kind: dberr.NotFound, Entity: "delivery"}
+}
+
+func (n notFoundDb) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]models.DueDelivery, error) {
+	return n.delegate.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+
+func (n notFoundDb) RecordWebhookAttempt(ctx context.Context, a models.WebhookAttempt) error {
+	return n.delegate.RecordWebhookAttempt(ctx, a)
+}
+
+func (n notFoundDb) ReserveIdempotencyKey(ctx context.Context, key models.IdempotencyKey, ttl time.Duration) (models.IdempotencyKey, bool, error) {
+	return n.delegate.ReserveIdempotencyKey(ctx, key, ttl)
+}
+
+func (n notFoundDb) CompleteIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	return n.delegate.CompleteIdempotencyKey(ctx, key)
+}
+
+func (n notFoundDb) ReleaseIdempotencyKey(ctx context.Context, key models.IdempotencyKey) error {
+	return n.delegate.ReleaseIdempotencyKey(ctx, key)
+}
+
+func (n notFoundDb) PurgeIdempotencyKeys(ctx context.Context) (int, error) {
+	return n.delegate.PurgeIdempotencyKeys(ctx)
+}
 
-func (n notFoundDb) UpdateFund(models.Fund) (models.Fund, error) {
-	return models.Fund{}, gorm.ErrRecordNotFound
+func (n notFoundDb) CreateAPIKey(ctx context.Context, key models.APIKey) (models.APIKey, error) {
+	return n.delegate.CreateAPIKey(ctx, key)
 }
 
-func (n notFoundDb) ReadFundByID(uuid.UUID) (models.Fund, error) {
-	return models.Fund{}, gorm.ErrRecordNotFound
+func (n notFoundDb) ReadAPIKeys(ctx context.Context, q models.APIKeyQuery) (models.Page[models.APIKey], error) {
+	return n.delegate.ReadAPIKeys(ctx, q)
 }
 
-func (n notFoundDb) CreateInvestor(ci models.CreateInvestor) (models.Investor, error) {
-	return n.delegate.CreateInvestor(ci)
+func (n notFoundDb) ReadAPIKeyByID(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	return n.delegate.ReadAPIKeyByID(ctx, id)
 }
 
-func (n notFoundDb) ReadInvestors() ([]models.Investor, error) { return n.delegate.ReadInvestors() }
+func (n notFoundDb) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (models.APIKey, error) {
+	return n.delegate.RotateAPIKey(ctx, id, hash, prefix)
+}
+
+func (n notFoundDb) RevokeAPIKey(ctx context.Context, id uuid.UUID) (models.APIKey, error) {
+	return n.delegate.RevokeAPIKey(ctx, id)
+}
+
+func (n notFoundDb) UseAPIKey(ctx context.Context, hash string) (models.APIKey, error) {
+	return n.delegate.UseAPIKey(ctx, hash)
+}
 
-func (n notFoundDb) CreateInvestment(ci models.CreateInvestment) (models.Investment, error) {
-	return n.delegate.CreateInvestment(ci)
+func (n notFoundDb) ReadFundStats(ctx context.Context) ([]models.FundStats, error) {
+	return n.delegate.ReadFundStats(ctx)
 }
 
-func (n notFoundDb) ReadInvestments(uuid.UUID) ([]models.Investment, error) {
-	return n.delegate.ReadInvestments(uuid.UUID{})
+func (n notFoundDb) ReadFundCommitments(ctx context.Context, limit int) ([]models.FundCommitment, error) {
+	return n.delegate.ReadFundCommitments(ctx, limit)
+}
+
+func (n notFoundDb) WithTx(ctx context.Context, fn func(database.Db) error) error {
+	return n.delegate.WithTx(ctx, fn)
 }
 
 func marshal(v any) *bytes.Reader {
@@ -47,6 +193,15 @@ func marshal(v any) *bytes.Reader {
 	return bytes.NewReader(b)
 }
 
+func decodeProblem(t *testing.T, rec *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	assert.Equal(t, MIMEApplicationProblemJSON, rec.Header().Get(echo.HeaderContentType))
+	var problem Problem
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, rec.Code, problem.Status)
+	return problem
+}
+
 // func TestFail(t *testing.T) {
 // 	t.FailNow()
 // }
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// checkTimeout bounds each readiness check, so that a hung dependency
+// fails the probe rather than hanging it.
+const checkTimeout = 2 * time.Second
+
+// Server states reported by Health.
+const (
+	StateStarting = "starting"
+	StateReady    = "ready"
+	StateStopping = "stopping"
+)
+
+// Check reports whether a dependency is usable. Detail, if not nil, is
+// included in the readiness report, e.g. a schema version.
+type Check func(ctx context.Context) (detail any, err error)
+
+// Health serves the liveness and readiness probes. The server is ready once
+// it is in StateReady and every check passes; it starts in StateStarting,
+// and is put in StateStopping when it begins to shut down, so that load
+// balancers stop sending it requests before it stops taking them.
+type Health struct {
+	state  atomic.Value
+	checks map[string]Check
+}
+
+// NewHealth returns a Health, in StateStarting, that runs checks, keyed by
+// the dependency they check, for readiness.
+func NewHealth(checks map[string]Check) *Health {
+	h := &Health{checks: checks}
+	h.SetState(StateStarting)
+	return h
+}
+
+// SetState sets the server's state, one of the State constants.
+func (h *Health) SetState(state string) {
+	h.state.Store(state)
+}
+
+// checkResult is one dependency's part of the readiness report.
+type checkResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Detail    any     `json:"detail,omitempty"`
+}
+
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// Live reports that the process is up and serving. It checks nothing else,
+// so that a failing dependency gets the server taken out of rotation by
+// Ready rather than restarted.
+func (h *Health) Live(ctx echo.Context) error {
+	return ctx.JSON(http.StatusOK, healthReport{Status: "ok"})
+}
+
+// Ready runs every check, concurrently, and responds 200 if the server is
+// ready, or 503 otherwise, with each dependency's result either way. It
+// needs no authentication, so failures are only reported as failing; their
+// errors, which can name hosts and databases, are logged instead.
+func (h *Health) Ready(ctx echo.Context) error {
+	report := healthReport{Status: h.state.Load().(string), Checks: make(map[string]checkResult)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx.Request().Context(), checkTimeout)
+			defer cancel()
+			start := time.Now()
+			detail, err := check(checkCtx)
+			result := checkResult{Status: "ok", LatencyMs: float64(time.Since(start).Microseconds()) / 1000, Detail: detail}
+			if err != nil {
+				result.Status, result.Detail = "failing", nil
+				slog.WarnContext(ctx.Request().Context(), "Readiness check failed", "check", name, "error", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil && report.Status == StateReady {
+				report.Status = "not ready"
+			}
+		}()
+	}
+	wg.Wait()
+	status := http.StatusOK
+	if report.Status != StateReady {
+		status = http.StatusServiceUnavailable
+	}
+	return ctx.JSON(status, report)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealth(t *testing.T) {
+	var failing error
+	health := NewHealth(map[string]Check{
+		"database": func(ctx context.Context) (any, error) { return nil, failing },
+		"migrations": func(ctx context.Context) (any, error) {
+			return map[string]int{"version": 13}, nil
+		},
+	})
+	e := echo.New()
+	e.GET("/healthz", health.Live)
+	e.GET("/readyz", health.Ready)
+
+	type report struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status string         `json:"status"`
+			Detail map[string]int `json:"detail"`
+		} `json:"checks"`
+	}
+	serve := func(target string) (int, report) {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var body report
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return rec.Code, body
+	}
+
+	code, body := serve("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StateStarting, body.Status)
+	assert.Equal(t, "ok", body.Checks["database"].Status, "checks run while starting")
+
+	health.SetState(StateReady)
+	code, body = serve("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StateReady, body.Status)
+	assert.Equal(t, "ok", body.Checks["migrations"].Status)
+	assert.Equal(t, 13, body.Checks["migrations"].Detail["version"])
+
+	failing = errors.New("dial tcp db.internal:5432: connection refused")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.NotContains(t, rec.Body.String(), "db.internal", "errors are not exposed")
+	code, body = serve("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "not ready", body.Status)
+	assert.Equal(t, "failing", body.Checks["database"].Status)
+	assert.Equal(t, "ok", body.Checks["migrations"].Status)
+
+	failing = nil
+	health.SetState(StateStopping)
+	code, body = serve("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StateStopping, body.Status)
+
+	code, body = serve("/healthz")
+	assert.Equal(t, http.StatusOK, code, "live while stopping")
+	assert.Equal(t, "ok", body.Status)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderIdempotencyKey = "Idempotency-Key"
+	// headerIdempotentReplayed marks a response replayed from an earlier
+	// request with the same key.
+	headerIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// maxIdempotencyKeyLength bounds the keys clients may send. UUIDs are the
+// recommended choice.
+const maxIdempotencyKeyLength = 255
+
+// replayedHeaders are the response headers stored with an idempotency key.
+var replayedHeaders = []string{echo.HeaderContentType, headerETag}
+
+// replayBodyKey is the echo context key under which handlers leave the body
+// to store in place of the response they sent.
+const replayBodyKey = "idempotency.replay_body"
+
+// replayWithout stores v, rather than the response, for retries of this
+// request. Handlers whose responses carry credentials use it so that the
+// credentials are never stored with the idempotency key: a retry gets v,
+// the response without them.
+func replayWithout(ctx echo.Context, v any) {
+	ctx.Set(replayBodyKey, v)
+}
+
+// Idempotency is middleware that makes POST requests with an
+// Idempotency-Key header safe to retry. The first request with a key runs
+// as usual, and its response is stored for ttl; retries with the same key
+// and body get the stored response without running again. Reusing a key
+// for a different request is rejected with 422, and retrying while the
+// first request is still running with 409. Keys are stored in the Db, so
+// they hold across replicas. Responses with a 5xx status are not stored, so
+// that the request can be retried. Handlers may store a body other than the
+// one they sent with replayWithout.
+func (h Handler) Idempotency(ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			key := req.Header.Get(HeaderIdempotencyKey)
+			if req.Method != http.MethodPost || key == "" {
+				return next(ctx)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				return InvalidRequest(ctx, "Invalid Idempotency-Key header", fmt.Errorf("must be at most %d characters", maxIdempotencyKeyLength))
+			}
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return InvalidRequest(ctx, "Failed to read request body", err)
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			reqCtx := req.Context()
+			id, _ := auth.FromContext(reqCtx)
+			hash := requestHash(req, body)
+			stored, reserved, err := h.Db.ReserveIdempotencyKey(reqCtx, models.IdempotencyKey{
+				TenantID:    access.FromContext(reqCtx).TenantID(),
+				Subject:     id.Subject,
+				Key:         key,
+				RequestHash: hash,
+			}, ttl)
+			if err != nil {
+				return DbError(ctx, "Failed to reserve idempotency key", err)
+			}
+			if !reserved {
+				return replayIdempotent(ctx, stored, hash)
+			}
+
+			rec := &bodyRecorder{ResponseWriter: ctx.Response().Writer}
+			ctx.Response().Writer = rec
+			err = next(ctx)
+			res := ctx.Response()
+			// Release or complete the key even if the client has gone away.
+			storeCtx := context.WithoutCancel(reqCtx)
+			if err != nil || !res.Committed || res.Status >= http.StatusInternalServerError {
+				if releaseErr := h.Db.ReleaseIdempotencyKey(storeCtx, stored); releaseErr != nil {
+					slog.ErrorContext(storeCtx, "Failed to release idempotency key", "error", releaseErr)
+				}
+				return err
+			}
+			stored.StatusCode = res.Status
+			stored.ResponseHeader = make(map[string][]string)
+			for _, name := range replayedHeaders {
+				if values := res.Header().Values(name); len(values) > 0 {
+					stored.ResponseHeader[name] = values
+				}
+			}
+			stored.ResponseBody = rec.body.Bytes()
+			if v := ctx.Get(replayBodyKey); v != nil {
+				body, err := json.Marshal(v)
+				if err != nil {
+					slog.ErrorContext(storeCtx, "Failed to encode idempotent response", "error", err)
+					if releaseErr := h.Db.ReleaseIdempotencyKey(storeCtx, stored); releaseErr != nil {
+						slog.ErrorContext(storeCtx, "Failed to release idempotency key", "error", releaseErr)
+					}
+					return nil
+				}
+				stored.ResponseBody = body
+			}
+			if err := h.Db.CompleteIdempotencyKey(storeCtx, stored); err != nil {
+				slog.ErrorContext(storeCtx, "Failed to store idempotent response", "error", err)
+			}
+			return nil
+		}
+	}
+}
+
+// requestHash identifies a request by its method, path and body. Keys are
+// stored per caller and tenant, so this only tells apart the requests of
+// one caller.
+func requestHash(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.Path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replayIdempotent responds to a request whose key was already reserved.
+func replayIdempotent(ctx echo.Context, stored models.IdempotencyKey, hash string) error {
+	switch {
+	case stored.RequestHash != hash:
+		return WriteProblem(ctx, Problem{
+			Type:   ProblemIdempotencyKeyReused,
+			Title:  "Idempotency key reused",
+			Status: http.StatusUnprocessableEntity,
+			Detail: "Idempotency-Key was already used for a different request.",
+		})
+	case !stored.Completed():
+		return WriteProblem(ctx, Problem{
+			Type:   ProblemRequestInProgress,
+			Title:  "Request in progress",
+			Status: http.StatusConflict,
+			Detail: "A request with this Idempotency-Key is still being processed; retry later.",
+		})
+	}
+	header := ctx.Response().Header()
+	for name, values := range stored.ResponseHeader {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	header.Set(headerIdempotentReplayed, "true")
+	return ctx.Blob(stored.StatusCode, header.Get(echo.HeaderContentType), stored.ResponseBody)
+}
+
+// bodyRecorder keeps a copy of the response body as it is written.
+type bodyRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *bodyRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotency(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		h := Handler{Db: db}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(h.Idempotency(time.Hour))
+		e.POST("/funds", h.CreateFund)
+		e.GET("/funds", h.ReadFunds)
+		failures := 1
+		e.POST("/flaky", func(ctx echo.Context) error {
+			if failures > 0 {
+				failures--
+				return ctx.NoContent(http.StatusServiceUnavailable)
+			}
+			return ctx.String(http.StatusOK, "done")
+		})
+
+		serve := func(method, target, body, key string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			if key != "" {
+				req.Header.Set(HeaderIdempotencyKey, key)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		const fund = `{"name":"Fund A","vintage_year":2021,"target_size_usd":1000000,"status":"Fundraising"}`
+		first := serve(http.MethodPost, "/funds", fund, "create-fund-a")
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get(headerIdempotentReplayed))
+		retry := serve(http.MethodPost, "/funds", fund, "create-fund-a")
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get(headerIdempotentReplayed))
+		assert.Equal(t, first.Header().Get(headerETag), retry.Header().Get(headerETag))
+		assert.Equal(t, first.Header().Get(echo.HeaderContentType), retry.Header().Get(echo.HeaderContentType))
+		assert.JSONEq(t, first.Body.String(), retry.Body.String())
+
+		rec := serve(http.MethodGet, "/funds", "", "")
+		var page models.Page[models.Fund]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 1, "a retried request runs once")
+
+		rec = serve(http.MethodPost, "/funds", strings.Replace(fund, "Fund A", "Fund B", 1), "create-fund-a")
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, ProblemIdempotencyKeyReused, decodeProblem(t, rec).Type)
+
+		rec = serve(http.MethodPost, "/funds", `{"name":""}`, "invalid")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = serve(http.MethodPost, "/funds", `{"name":""}`, "invalid")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, "true", rec.Header().Get(headerIdempotentReplayed), "client errors are replayed too")
+
+		rec = serve(http.MethodPost, "/flaky", "", "flaky")
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		rec = serve(http.MethodPost, "/flaky", "", "flaky")
+		assert.Equal(t, http.StatusOK, rec.Code, "server errors are not stored")
+		assert.Empty(t, rec.Header().Get(headerIdempotentReplayed))
+
+		_, _, err := db.ReserveIdempotencyKey(context.Background(), models.IdempotencyKey{TenantID: models.DefaultTenant, Key: "running", RequestHash: requestHash(httptest.NewRequest(http.MethodPost, "/funds", nil), []byte(fund))}, time.Hour)
+		assert.NoError(t, err)
+		rec = serve(http.MethodPost, "/funds", fund, "running")
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, ProblemRequestInProgress, decodeProblem(t, rec).Type)
+
+		rec = serve(http.MethodPost, "/funds", fund, strings.Repeat("k", maxIdempotencyKeyLength+1))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestIdempotency_PerCaller(t *testing.T) {
+	h := Handler{Db: database.NewMockDb()}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			id := auth.Identity{Subject: req.Header.Get("X-Subject")}
+			ctx.SetRequest(req.WithContext(auth.NewContext(req.Context(), id)))
+			return next(ctx)
+		}
+	})
+	e.Use(h.Idempotency(time.Hour))
+	e.POST("/funds", h.CreateFund)
+
+	serve := func(subject, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/funds", bytes.NewBufferString(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, "create-fund")
+		req.Header.Set("X-Subject", subject)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := serve("alice", `{"name":"Fund A","vintage_year":2021,"target_size_usd":1000000,"status":"Fundraising"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	rec = serve("bob", `{"name":"Fund B","vintage_year":2021,"target_size_usd":1000000,"status":"Fundraising"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, "callers do not share keys")
+	assert.Empty(t, rec.Header().Get(headerIdempotentReplayed))
+	assert.Contains(t, rec.Body.String(), "Fund B")
+}
+
+func TestIdempotency_Credentials(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		h := Handler{Db: db}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(h.Idempotency(time.Hour))
+		e.POST("/api-keys", h.CreateAPIKey)
+		e.POST("/api-keys/:api_key_id/rotate", h.RotateAPIKey)
+		e.POST("/webhooks", h.CreateWebhook)
+
+		serve := func(target, body, key string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderIdempotencyKey, key)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+		stored := func(key string) models.IdempotencyKey {
+			t.Helper()
+			stored, reserved, err := db.ReserveIdempotencyKey(context.Background(), models.IdempotencyKey{TenantID: models.DefaultTenant, Key: key}, time.Hour)
+			assert.NoError(t, err)
+			assert.False(t, reserved)
+			return stored
+		}
+
+		const create = `{"name":"etl","scopes":["fund:read"]}`
+		rec := serve("/api-keys", create, "issue")
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var issued issuedAPIKey
+		_ = json.Unmarshal(rec.Body.Bytes(), &issued)
+		assert.NotEmpty(t, issued.Key)
+		assert.NotContains(t, string(stored("issue").ResponseBody), issued.Key)
+
+		retry := serve("/api-keys", create, "issue")
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get(headerIdempotentReplayed))
+		assert.NotContains(t, retry.Body.String(), `"key"`, "retries do not get the key")
+		assert.Contains(t, retry.Body.String(), issued.ID.String())
+
+		rec = serve("/api-keys/"+issued.ID.String()+"/rotate", "", "rotate")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var rotated issuedAPIKey
+		_ = json.Unmarshal(rec.Body.Bytes(), &rotated)
+		assert.NotEmpty(t, rotated.Key)
+		assert.NotContains(t, string(stored("rotate").ResponseBody), rotated.Key)
+
+		const webhook = `{"url":"https://example.com/hooks","event_types":["FundCreated"],"secret":"0123456789abcdef-secret"}`
+		rec = serve("/webhooks", webhook, "subscribe")
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), "0123456789abcdef-secret")
+		assert.NotContains(t, string(stored("subscribe").ResponseBody), "0123456789abcdef-secret")
+		retry = serve("/webhooks", webhook, "subscribe")
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get(headerIdempotentReplayed))
+		assert.NotContains(t, retry.Body.String(), `"secret"`, "retries do not get the secret")
+		assert.Equal(t, rec.Header().Get(headerETag), retry.Header().Get(headerETag))
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
 	"github.com/iuhmirza/titanbay-take-home/models"
 	"github.com/labstack/echo/v4"
 	"github.com/shopspring/decimal"
@@ -37,93 +39,95 @@ func TestCreateInvestment_InvalidFundUUID(t *testing.T) {
 }
 
 func TestCreateInvestment_ValidationError(t *testing.T) {
-	e := echo.New()
-	db := database.NewMockDb()
-	h := Handler{Db: db}
-
-	// seed required fund + investor to avoid FK error—let validation fail on amount
-	f, _ := db.CreateFund(models.CreateFund{
-		Name:          "Fund Y",
-		VintageYear:   2020,
-		TargetSizeUsd: decimal.NewFromInt(1_000_000),
-		Status:        "Investing",
-	})
-	inv, _ := db.CreateInvestor(models.CreateInvestor{
-		Name:         "Carl",
-		InvestorType: "Family Office",
-		Email:        "carl@family.com",
-	})
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
 
-	body := map[string]any{
-		"investor_id":     inv.ID,
-		"amount_usd":      decimal.NewFromInt(0), // invalid per validation
-		"investment_date": "2024-06-01",
-	}
-	req := httptest.NewRequest(http.MethodPost, "/funds/"+f.ID.String()+"/investments", marshal(body))
-	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-	rec := httptest.NewRecorder()
+		// seed required fund + investor to avoid FK error—let validation fail on amount
+		f, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund Y",
+			VintageYear:   2020,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+		inv, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Carl",
+			InvestorType: "Family Office",
+			Email:        "carl@family.com",
+		})
 
-	ctx := e.NewContext(req, rec)
-	ctx.SetParamNames("fund_id")
-	ctx.SetParamValues(f.ID.String())
+		body := map[string]any{
+			"investor_id":     inv.ID,
+			"amount_usd":      decimal.NewFromInt(0), // invalid per validation
+			"investment_date": "2024-06-01",
+		}
+		req := httptest.NewRequest(http.MethodPost, "/funds/"+f.ID.String()+"/investments", marshal(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
 
-	err := h.CreateInvestment(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusBadRequest, rec.Code)
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(f.ID.String())
+
+		err := h.CreateInvestment(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
 }
 
 func TestReadInvestments_Success(t *testing.T) {
-	e := echo.New()
-	db := database.NewMockDb()
-	h := Handler{Db: db}
-
-	// seed fund + investor + two investments (only one for our fund)
-	f1, _ := db.CreateFund(models.CreateFund{
-		Name:          "Fund One",
-		VintageYear:   2018,
-		TargetSizeUsd: decimal.NewFromInt(1_000_000),
-		Status:        "Investing",
-	})
-	f2, _ := db.CreateFund(models.CreateFund{
-		Name:          "Fund Two",
-		VintageYear:   2019,
-		TargetSizeUsd: decimal.NewFromInt(1_000_000),
-		Status:        "Investing",
-	})
-	inv, _ := db.CreateInvestor(models.CreateInvestor{
-		Name:         "Dana",
-		InvestorType: "Individual",
-		Email:        "dana@example.com",
-	})
-	_, _ = db.CreateInvestment(models.CreateInvestment{
-		InvestorID:     inv.ID,
-		FundID:         f1.ID,
-		AmountUsd:      decimal.NewFromInt(1234),
-		InvestmentDate: "2024-05-05",
-	})
-	_, _ = db.CreateInvestment(models.CreateInvestment{
-		InvestorID:     inv.ID,
-		FundID:         f2.ID,
-		AmountUsd:      decimal.NewFromInt(5678),
-		InvestmentDate: "2024-06-06",
-	})
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
 
-	req := httptest.NewRequest(http.MethodGet, "/funds/"+f1.ID.String()+"/investments", nil)
-	rec := httptest.NewRecorder()
+		// seed fund + investor + two investments (only one for our fund)
+		f1, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund One",
+			VintageYear:   2018,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+		f2, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund Two",
+			VintageYear:   2019,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+		inv, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Dana",
+			InvestorType: "Individual",
+			Email:        "dana@example.com",
+		})
+		_, _ = db.CreateInvestment(context.Background(), models.CreateInvestment{
+			InvestorID:     inv.ID,
+			FundID:         f1.ID,
+			AmountUsd:      decimal.NewFromInt(1234),
+			InvestmentDate: "2024-05-05",
+		})
+		_, _ = db.CreateInvestment(context.Background(), models.CreateInvestment{
+			InvestorID:     inv.ID,
+			FundID:         f2.ID,
+			AmountUsd:      decimal.NewFromInt(5678),
+			InvestmentDate: "2024-06-06",
+		})
 
-	ctx := e.NewContext(req, rec)
-	ctx.SetParamNames("fund_id")
-	ctx.SetParamValues(f1.ID.String())
+		req := httptest.NewRequest(http.MethodGet, "/funds/"+f1.ID.String()+"/investments", nil)
+		rec := httptest.NewRecorder()
 
-	err := h.ReadInvestments(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, rec.Code)
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(f1.ID.String())
 
-	var list []models.Investment
-	_ = json.Unmarshal(rec.Body.Bytes(), &list)
-	assert.Len(t, list, 1)
-	assert.Equal(t, f1.ID, list[0].FundID)
-	assert.Equal(t, "2024-05-05", list[0].InvestmentDate)
+		err := h.ReadInvestments(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var page models.Page[models.Investment]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, f1.ID, page.Items[0].FundID)
+		assert.Equal(t, "2024-05-05", page.Items[0].InvestmentDate)
+	})
 }
 
 func TestReadInvestments_InvalidFundUUID(t *testing.T) {
@@ -141,3 +145,139 @@ func TestReadInvestments_InvalidFundUUID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 }
+
+func TestReadInvestments_FiltersByAmountAndDate(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		f, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund Z",
+			VintageYear:   2021,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+		inv, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Fay",
+			InvestorType: "Individual",
+			Email:        "fay@example.com",
+		})
+		for i, date := range []string{"2024-01-10", "2024-02-10", "2024-03-10"} {
+			_, _ = db.CreateInvestment(context.Background(), models.CreateInvestment{
+				InvestorID:     inv.ID,
+				FundID:         f.ID,
+				AmountUsd:      decimal.NewFromInt(int64(1000 * (i + 1))),
+				InvestmentDate: date,
+			})
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/funds/"+f.ID.String()+
+			"/investments?amount_usd_min=1500&investment_date_to=2024-02-28&sort=-amount_usd", nil)
+		rec := httptest.NewRecorder()
+
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(f.ID.String())
+
+		err := h.ReadInvestments(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var page models.Page[models.Investment]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "2024-02-10", page.Items[0].InvestmentDate)
+	})
+}
+
+func TestCreateInvestment_UnknownInvestorMapsTo422(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		f, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund W",
+			VintageYear:   2020,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+
+		body := map[string]any{
+			"investor_id":     uuid.New(),
+			"amount_usd":      decimal.NewFromInt(100),
+			"investment_date": "2024-06-01",
+		}
+		req := httptest.NewRequest(http.MethodPost, "/funds/"+f.ID.String()+"/investments", marshal(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(f.ID.String())
+
+		err := h.CreateInvestment(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, ProblemConstraintViolation, problem.Type)
+		assert.Equal(t, []models.FieldError{
+			{Field: "investor_id", Message: "investor_id does not reference an existing investor"},
+		}, problem.Errors)
+	})
+}
+
+func TestDeleteInvestment_ScopedToFund(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+		f, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund V",
+			VintageYear:   2020,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Investing",
+		})
+		inv, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Vic",
+			InvestorType: "Individual",
+			Email:        "vic@example.com",
+		})
+		investment, _ := db.CreateInvestment(context.Background(), models.CreateInvestment{
+			InvestorID:     inv.ID,
+			FundID:         f.ID,
+			AmountUsd:      decimal.NewFromInt(100),
+			InvestmentDate: "2024-06-01",
+		})
+
+		del := func(fundID uuid.UUID) *httptest.ResponseRecorder {
+			body := marshal(models.DeleteResource{Reason: "booked twice"})
+			req := httptest.NewRequest(http.MethodDelete, "/funds/"+fundID.String()+"/investments/"+investment.ID.String(), body)
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("fund_id", "investment_id")
+			ctx.SetParamValues(fundID.String(), investment.ID.String())
+			assert.NoError(t, h.DeleteInvestment(ctx))
+			return rec
+		}
+
+		assert.Equal(t, http.StatusNotFound, del(uuid.New()).Code)
+		rec := del(f.ID)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got models.Investment
+		_ = json.Unmarshal(rec.Body.Bytes(), &got)
+		assert.Equal(t, "booked twice", got.DeleteReason)
+
+		page, _ := db.ReadInvestments(context.Background(), f.ID, models.InvestmentQuery{})
+		assert.Empty(t, page.Items)
+
+		rec = httptest.NewRecorder()
+		ctx := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+		ctx.SetParamNames("fund_id", "investment_id")
+		ctx.SetParamValues(f.ID.String(), investment.ID.String())
+		assert.NoError(t, h.RestoreInvestment(ctx))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		page, _ = db.ReadInvestments(context.Background(), f.ID, models.InvestmentQuery{})
+		assert.Len(t, page.Items, 1)
+	})
+}
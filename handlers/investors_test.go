@@ -1,12 +1,15 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
 	"github.com/iuhmirza/titanbay-take-home/models"
 	"github.com/labstack/echo/v4"
 	"github.com/shopspring/decimal"
@@ -14,28 +17,30 @@ import (
 )
 
 func TestCreateInvestor_Success(t *testing.T) {
-	e := echo.New()
-	h := Handler{Db: database.NewMockDb()}
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
 
-	body := models.CreateInvestor{
-		Name:         "Alice",
-		InvestorType: "Individual",
-		Email:        "alice@example.com",
-	}
+		body := models.CreateInvestor{
+			Name:         "Alice",
+			InvestorType: "Individual",
+			Email:        "alice@example.com",
+		}
 
-	req := httptest.NewRequest(http.MethodPost, "/investors", marshal(body))
-	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-	rec := httptest.NewRecorder()
-	ctx := e.NewContext(req, rec)
+		req := httptest.NewRequest(http.MethodPost, "/investors", marshal(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
 
-	err := h.CreateInvestor(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusCreated, rec.Code)
+		err := h.CreateInvestor(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
 
-	var inv models.Investor
-	_ = json.Unmarshal(rec.Body.Bytes(), &inv)
-	assert.Equal(t, "Alice", inv.Name)
-	assert.Equal(t, "alice@example.com", inv.Email)
+		var inv models.Investor
+		_ = json.Unmarshal(rec.Body.Bytes(), &inv)
+		assert.Equal(t, "Alice", inv.Name)
+		assert.Equal(t, "alice@example.com", inv.Email)
+	})
 }
 
 func TestCreateInvestor_ValidationError(t *testing.T) {
@@ -59,60 +64,218 @@ func TestCreateInvestor_ValidationError(t *testing.T) {
 }
 
 func TestReadInvestors_EmptyOK(t *testing.T) {
-	e := echo.New()
-	h := Handler{Db: database.NewMockDb()}
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
 
-	req := httptest.NewRequest(http.MethodGet, "/investors", nil)
-	rec := httptest.NewRecorder()
-	ctx := e.NewContext(req, rec)
+		req := httptest.NewRequest(http.MethodGet, "/investors", nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
 
-	err := h.ReadInvestors(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusOK, rec.Code)
+		err := h.ReadInvestors(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
 
-	var list []models.Investor
-	_ = json.Unmarshal(rec.Body.Bytes(), &list)
-	assert.Len(t, list, 0)
+		var page models.Page[models.Investor]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 0)
+	})
 }
 
 func TestCreateInvestment_Success(t *testing.T) {
-	e := echo.New()
-	db := database.NewMockDb()
-	h := Handler{Db: db}
-
-	// seed fund + investor
-	f, _ := db.CreateFund(models.CreateFund{
-		Name:          "Fund X",
-		VintageYear:   2022,
-		TargetSizeUsd: decimal.NewFromInt(2_000_000),
-		Status:        "Fundraising",
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		// seed fund + investor
+		f, _ := db.CreateFund(context.Background(), models.CreateFund{
+			Name:          "Fund X",
+			VintageYear:   2022,
+			TargetSizeUsd: decimal.NewFromInt(2_000_000),
+			Status:        "Fundraising",
+		})
+		inv, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Eve",
+			InvestorType: "Institution",
+			Email:        "eve@inst.com",
+		})
+
+		body := map[string]any{
+			"investor_id":     inv.ID,
+			"amount_usd":      decimal.NewFromInt(250000),
+			"investment_date": "2024-02-01",
+		}
+		req := httptest.NewRequest(http.MethodPost, "/funds/"+f.ID.String()+"/investments", marshal(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("fund_id")
+		ctx.SetParamValues(f.ID.String())
+
+		err := h.CreateInvestment(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var invst models.Investment
+		_ = json.Unmarshal(rec.Body.Bytes(), &invst)
+		assert.Equal(t, f.ID, invst.FundID)
+		assert.Equal(t, inv.ID, invst.InvestorID)
+		assert.Equal(t, "2024-02-01", invst.InvestmentDate)
+	})
+}
+
+func TestReadInvestors_FilterByTypeSortedByName(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		for _, ci := range []models.CreateInvestor{
+			{Name: "Zed", InvestorType: "Individual", Email: "zed@example.com"},
+			{Name: "Acme", InvestorType: "Institution", Email: "ops@acme.com"},
+			{Name: "Amy", InvestorType: "Individual", Email: "amy@example.com"},
+		} {
+			_, _ = db.CreateInvestor(context.Background(), ci)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/investors?investor_type=Individual&sort=name", nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.ReadInvestors(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var page models.Page[models.Investor]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 2)
+		assert.Equal(t, "Amy", page.Items[0].Name)
+		assert.Equal(t, "Zed", page.Items[1].Name)
 	})
-	inv, _ := db.CreateInvestor(models.CreateInvestor{
-		Name:         "Eve",
-		InvestorType: "Institution",
-		Email:        "eve@inst.com",
+}
+
+func TestCreateInvestor_DuplicateEmailMapsTo409(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+
+		body := models.CreateInvestor{
+			Name:         "Alice",
+			InvestorType: "Individual",
+			Email:        "alice@example.com",
+		}
+		_, _ = db.CreateInvestor(context.Background(), body)
+
+		req := httptest.NewRequest(http.MethodPost, "/investors", marshal(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+
+		err := h.CreateInvestor(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, ProblemConflict, problem.Type)
+		assert.Equal(t, "investor with this email already exists", problem.Detail)
 	})
+}
 
-	body := map[string]any{
-		"investor_id":     inv.ID,
-		"amount_usd":      decimal.NewFromInt(250000),
-		"investment_date": "2024-02-01",
-	}
-	req := httptest.NewRequest(http.MethodPost, "/funds/"+f.ID.String()+"/investments", marshal(body))
-	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-	rec := httptest.NewRecorder()
+func TestReadInvestorByID_SetsETag(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+		seed, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Alice",
+			InvestorType: "Individual",
+			Email:        "alice@example.com",
+		})
 
-	ctx := e.NewContext(req, rec)
-	ctx.SetParamNames("fund_id")
-	ctx.SetParamValues(f.ID.String())
+		req := httptest.NewRequest(http.MethodGet, "/investors/"+seed.ID.String(), nil)
+		rec := httptest.NewRecorder()
+		ctx := e.NewContext(req, rec)
+		ctx.SetParamNames("investor_id")
+		ctx.SetParamValues(seed.ID.String())
 
-	err := h.CreateInvestment(ctx)
-	assert.NoError(t, err)
-	assert.Equal(t, http.StatusCreated, rec.Code)
+		err := h.ReadInvestorByID(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `"1"`, rec.Header().Get(headerETag))
+	})
+}
+
+func TestPatchInvestor_TakenEmailMapsTo409(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+		alice, _ := db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Alice",
+			InvestorType: "Individual",
+			Email:        "alice@example.com",
+		})
+		_, _ = db.CreateInvestor(context.Background(), models.CreateInvestor{
+			Name:         "Bob",
+			InvestorType: "Individual",
+			Email:        "bob@example.com",
+		})
+
+		patch := func(body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPatch, "/investors/"+alice.ID.String(), bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, MIMEApplicationMergePatchJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("investor_id")
+			ctx.SetParamValues(alice.ID.String())
+			assert.NoError(t, h.PatchInvestor(ctx))
+			return rec
+		}
+
+		rec := patch(`{"email":"bob@example.com"}`)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+
+		rec = patch(`{"email":"alice@wonderland.org","investor_type":"Family Office"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got models.Investor
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, "alice@wonderland.org", got.Email)
+		assert.Equal(t, "Family Office", got.InvestorType)
+		assert.Equal(t, "Alice", got.Name)
+		assert.Equal(t, `"2"`, rec.Header().Get(headerETag))
+	})
+}
+
+func TestRestoreInvestor_TakenEmailMapsTo409(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		e := echo.New()
+		h := Handler{Db: db}
+		ci := models.CreateInvestor{
+			Name:         "Alice",
+			InvestorType: "Individual",
+			Email:        "alice@example.com",
+		}
+		alice, _ := db.CreateInvestor(context.Background(), ci)
+
+		call := func(method, target, body string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			ctx := e.NewContext(req, rec)
+			ctx.SetParamNames("investor_id")
+			ctx.SetParamValues(alice.ID.String())
+			assert.NoError(t, handler(ctx))
+			return rec
+		}
 
-	var invst models.Investment
-	_ = json.Unmarshal(rec.Body.Bytes(), &invst)
-	assert.Equal(t, f.ID, invst.FundID)
-	assert.Equal(t, inv.ID, invst.InvestorID)
-	assert.Equal(t, "2024-02-01", invst.InvestmentDate)
+		rec := call(http.MethodDelete, "/investors/"+alice.ID.String(), `{"reason":"duplicate"}`, h.DeleteInvestor)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// The email is free once its investor is deleted.
+		rec = call(http.MethodPost, "/investors", `{"name":"Alice","investor_type":"Individual","email":"alice@example.com"}`, h.CreateInvestor)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		rec = call(http.MethodPost, "/investors/"+alice.ID.String()+"/restore", "", h.RestoreInvestor)
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		problem := decodeProblem(t, rec)
+		assert.Equal(t, "investor with this email already exists", problem.Detail)
+	})
 }
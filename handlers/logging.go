@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/logging"
+	"github.com/labstack/echo/v4"
+)
+
+// maxRequestIDLength bounds the request IDs accepted from clients.
+const maxRequestIDLength = 128
+
+// RequestID is middleware that gives each request an ID: the client's
+// X-Request-ID, if it sends a reasonable one, or a new random one. The ID
+// is returned in X-Request-ID, and in error responses, and every log line
+// logged with the request's context carries it as request_id.
+func RequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		req := ctx.Request()
+		id := req.Header.Get(echo.HeaderXRequestID)
+		if !validRequestID(id) {
+			id = newRequestID()
+			req.Header.Set(echo.HeaderXRequestID, id)
+		}
+		ctx.Response().Header().Set(echo.HeaderXRequestID, id)
+		ctx.SetRequest(req.WithContext(logging.NewContext(req.Context(), slog.String("request_id", id))))
+		return next(ctx)
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII without spaces, so that
+// clients cannot forge log lines or headers with them.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog is middleware that logs every request once it has been served,
+// with its status and latency: 5xx responses as errors, the rest as info.
+// Errors returned by later handlers are handled here, so that the status
+// logged is the one sent. It must run after RequestID, so that the line
+// carries the request's ID.
+func AccessLog(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		start := time.Now()
+		if err := next(ctx); err != nil {
+			ctx.Error(err)
+		}
+		req, res := ctx.Request(), ctx.Response()
+		attrs := []slog.Attr{
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.String("route", ctx.Path()),
+			slog.Int("status", res.Status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int64("bytes_out", res.Size),
+			slog.String("remote_ip", ctx.RealIP()),
+		}
+		if id, ok := Identity(ctx); ok {
+			attrs = append(attrs, slog.String("subject", id.Subject))
+		}
+		level := slog.LevelInfo
+		if res.Status >= 500 {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(req.Context(), level, "request", attrs...)
+		return nil
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logging.New(&buf, slog.LevelInfo))
+
+	h := Handler{Db: database.NewMockDb()}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RequestID)
+	e.Use(AccessLog)
+	e.GET("/funds/:fund_id", h.ReadFundByID)
+
+	serve := func(target, requestID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if requestID != "" {
+			req.Header.Set(echo.HeaderXRequestID, requestID)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	lines := func() []map[string]any {
+		var records []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var record map[string]any
+			assert.NoError(t, json.Unmarshal([]byte(line), &record))
+			records = append(records, record)
+		}
+		buf.Reset()
+		return records
+	}
+
+	rec := serve("/funds/00000000-0000-0000-0000-000000000001", "req-123")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "req-123", rec.Header().Get(echo.HeaderXRequestID))
+	assert.Equal(t, "req-123", decodeProblem(t, rec).RequestID)
+	records := lines()
+	assert.Len(t, records, 1)
+	assert.Equal(t, "request", records[0]["msg"])
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, "req-123", records[0]["request_id"])
+	assert.Equal(t, "GET", records[0]["method"])
+	assert.Equal(t, "/funds/:fund_id", records[0]["route"])
+	assert.EqualValues(t, http.StatusNotFound, records[0]["status"])
+	assert.Contains(t, records[0], "latency_ms")
+
+	rec = serve("/funds/00000000-0000-0000-0000-000000000001", "forged\nline")
+	generated := rec.Header().Get(echo.HeaderXRequestID)
+	assert.Len(t, generated, 32, "unprintable IDs are replaced")
+	assert.Equal(t, generated, lines()[0]["request_id"])
+
+	rec = serve("/nowhere", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	records = lines()
+	assert.EqualValues(t, http.StatusNotFound, records[0]["status"], "errors from later handlers are logged with their status")
+	assert.Equal(t, rec.Header().Get(echo.HeaderXRequestID), records[0]["request_id"])
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// Instrument is middleware that counts and times every request in m, by
+// method, route pattern and status. Like AccessLog, it handles errors from
+// later handlers itself, so that the status recorded is the one sent.
+func Instrument(m *metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			start := time.Now()
+			if err := next(ctx); err != nil {
+				ctx.Error(err)
+			}
+			m.ObserveRequest(ctx.Request().Method, ctx.Path(), ctx.Response().Status, time.Since(start))
+			return nil
+		}
+	}
+}
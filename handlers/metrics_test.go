@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	m := metrics.New()
+	h := Handler{Db: database.NewMockDb()}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(Instrument(m))
+	e.GET("/metrics", echo.WrapHandler(m.Handler()))
+	e.GET("/funds/:fund_id", h.ReadFundByID)
+
+	serve := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+	serve("/funds/00000000-0000-0000-0000-000000000001")
+	serve("/funds/00000000-0000-0000-0000-000000000002")
+	serve("/funds/not-a-uuid")
+	rec := serve("/metrics")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `titanbay_http_requests_total{method="GET",route="/funds/:fund_id",status="404"} 2`, "routes are labelled by pattern, not path")
+	assert.Contains(t, string(body), `titanbay_http_requests_total{method="GET",route="/funds/:fund_id",status="400"} 1`)
+}
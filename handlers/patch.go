@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+)
+
+// MIMEApplicationMergePatchJSON is the media type of an RFC 7386 JSON Merge
+// Patch. PATCH endpoints also accept plain application/json.
+const MIMEApplicationMergePatchJSON = "application/merge-patch+json"
+
+const (
+	headerETag    = "ETag"
+	headerIfMatch = "If-Match"
+)
+
+// maxUpdateAttempts bounds how often an unconditional PATCH is retried after
+// losing a race with another writer.
+const maxUpdateAttempts = 3
+
+// etag formats a row version as a strong entity tag.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(ctx echo.Context, version int) {
+	ctx.Response().Header().Set(headerETag, etag(version))
+}
+
+// precondition is the parsed If-Match header of a request.
+type precondition struct {
+	present bool
+	tags    []string
+}
+
+func ifMatch(ctx echo.Context) precondition {
+	values := ctx.Request().Header.Values(headerIfMatch)
+	if len(values) == 0 {
+		return precondition{}
+	}
+	var tags []string
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tags = append(tags, strings.TrimSpace(tag))
+		}
+	}
+	return precondition{present: true, tags: tags}
+}
+
+// matches reports whether a resource at version satisfies the precondition.
+// Weak tags never match, as If-Match uses strong comparison.
+func (p precondition) matches(version int) bool {
+	if !p.present {
+		return true
+	}
+	return slices.Contains(p.tags, "*") || slices.Contains(p.tags, etag(version))
+}
+
+// check returns a dberr.VersionMismatch for entity if version does not
+// satisfy the precondition.
+func (p precondition) check(entity string, version int) error {
+	if !p.matches(version) {
+		return &dberr.Error{Kind: dberr.VersionMismatch, Entity: entity}
+	}
+	return nil
+}
+
+// retryUnconditional runs update again when it fails because another writer
+// got there first. Conditional requests are not retried: for them, losing
+// the race is exactly what If-Match guards against.
+func retryUnconditional(p precondition, update func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := update()
+		if p.present || attempt == maxUpdateAttempts || !errors.Is(err, dberr.VersionMismatch) {
+			return err
+		}
+	}
+}
+
+// bindMergePatch reads a JSON Merge Patch for a resource whose editable
+// fields are those of writable, a pointer to the zero value of its create
+// DTO. Members naming any other field are reported as validation errors.
+func bindMergePatch(ctx echo.Context, writable any) (map[string]any, error) {
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		return nil, err
+	}
+	var patch map[string]any
+	if err := decodeJSON(body, &patch); err != nil || patch == nil {
+		return nil, errors.New("merge patch must be a JSON object")
+	}
+	// Decode the patch on its own first, so that type errors are reported
+	// against the request rather than the merged document.
+	if err := json.Unmarshal(body, writable); err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	b, _ := json.Marshal(writable)
+	_ = json.Unmarshal(b, &fields)
+	var errs models.ValidationErrors
+	for member := range patch {
+		if _, ok := fields[member]; !ok {
+			errs.Add(member, fmt.Sprintf("%s cannot be changed", member))
+		}
+	}
+	return patch, errs.OrNil()
+}
+
+// applyMergePatch applies patch to the JSON representation of current and
+// decodes the result into dst, as described by RFC 7386.
+func applyMergePatch(current any, patch map[string]any, dst any) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	var target any
+	if err := decodeJSON(doc, &target); err != nil {
+		return err
+	}
+	merged, err := json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(merged, dst)
+}
+
+func mergePatch(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// decodeJSON decodes numbers as json.Number, so that amounts survive a round
+// trip without passing through float64.
+func decodeJSON(b []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// invalidMergePatch reports an error from bindMergePatch.
+func invalidMergePatch(ctx echo.Context, err error) error {
+	var errs models.ValidationErrors
+	if errors.As(err, &errs) {
+		return ValidationFailed(ctx, err)
+	}
+	return InvalidRequest(ctx, "Invalid merge patch", err)
+}
+
+// updateFailed reports an error from a read-modify-write transaction, which
+// is either the validation of the updated resource or a storage error.
+func updateFailed(ctx echo.Context, detail string, err error) error {
+	var errs models.ValidationErrors
+	if _, ok := dberr.As(err); !ok && errors.As(err, &errs) {
+		return ValidationFailed(ctx, err)
+	}
+	return DbError(ctx, detail, err)
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/iuhmirza/titanbay-take-home/database/dberr"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+)
+
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// Problem types are stable identifiers that clients can switch on; the title
+// and detail are for humans and may change.
+const (
+	ProblemInvalidRequest       = "/problems/invalid-request"
+	ProblemValidationFailed     = "/problems/validation-failed"
+	ProblemNotFound             = "/problems/not-found"
+	ProblemConflict             = "/problems/conflict"
+	ProblemConstraintViolation  = "/problems/constraint-violation"
+	ProblemPreconditionFailed   = "/problems/precondition-failed"
+	ProblemInternal             = "/problems/internal-error"
+	ProblemIdempotencyKeyReused = "/problems/idempotency-key-reused"
+	ProblemRequestInProgress    = "/problems/request-in-progress"
+	ProblemUnauthorized         = "/problems/unauthorized"
+	ProblemForbidden            = "/problems/forbidden"
+	ProblemRateLimited          = "/problems/rate-limited"
+)
+
+// Problem is an RFC 7807 problem details object, extended with the request ID
+// and, for validation failures, the individual field errors.
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail,omitempty"`
+	Instance  string              `json:"instance,omitempty"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []models.FieldError `json:"errors,omitempty"`
+}
+
+func WriteProblem(ctx echo.Context, p Problem) error {
+	p.Instance = ctx.Request().URL.Path
+	p.RequestID = ctx.Response().Header().Get(echo.HeaderXRequestID)
+	if p.RequestID == "" {
+		p.RequestID = ctx.Request().Header.Get(echo.HeaderXRequestID)
+	}
+	ctx.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return ctx.JSON(p.Status, p)
+}
+
+// InvalidRequest reports a body, path or query parameter that could not be
+// parsed at all.
+func InvalidRequest(ctx echo.Context, detail string, err error) error {
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		err = fmt.Errorf("%v", he.Message)
+	}
+	return WriteProblem(ctx, Problem{
+		Type:   ProblemInvalidRequest,
+		Title:  "Invalid request",
+		Status: http.StatusBadRequest,
+		Detail: fmt.Sprintf("%s: %v", detail, err),
+	})
+}
+
+// ValidationFailed reports every field error collected by a Validate method.
+func ValidationFailed(ctx echo.Context, err error) error {
+	var errs models.ValidationErrors
+	errors.As(err, &errs)
+	return WriteProblem(ctx, Problem{
+		Type:   ProblemValidationFailed,
+		Title:  "Validation failed",
+		Status: http.StatusBadRequest,
+		Detail: "One or more fields are invalid.",
+		Errors: errs,
+	})
+}
+
+// DbError maps dberr errors to 404, 409, 412 or 422. Anything else is logged and
+// reported as a 500 carrying only detail, so that database internals never
+// reach the client.
+func DbError(ctx echo.Context, detail string, err error) error {
+	e, ok := dberr.As(err)
+	if !ok {
+		slog.ErrorContext(ctx.Request().Context(), detail, "error", err)
+		return WriteProblem(ctx, Problem{
+			Type:   ProblemInternal,
+			Title:  "Internal server error",
+			Status: http.StatusInternalServerError,
+			Detail: detail,
+		})
+	}
+
+	p := Problem{Detail: e.Error()}
+	switch e.Kind {
+	case dberr.NotFound:
+		p.Type, p.Title, p.Status = ProblemNotFound, "Not found", http.StatusNotFound
+	case dberr.Conflict, dberr.InUse:
+		p.Type, p.Title, p.Status = ProblemConflict, "Conflict", http.StatusConflict
+	case dberr.VersionMismatch:
+		p.Type, p.Title, p.Status = ProblemPreconditionFailed, "Precondition failed", http.StatusPreconditionFailed
+	default:
+		p.Type, p.Title, p.Status = ProblemConstraintViolation, "Constraint violation", http.StatusUnprocessableEntity
+	}
+
+	var errs models.ValidationErrors
+	if errors.As(e.Err, &errs) {
+		p.Errors = errs
+	} else if e.Field != "" {
+		p.Errors = []models.FieldError{{Field: e.Field, Message: e.Error()}}
+	}
+	return WriteProblem(ctx, p)
+}
+
+// HTTPErrorHandler replaces echo's default so that routing errors, such as an
+// unknown path or method, are also reported as problem details.
+func HTTPErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+	status, detail := http.StatusInternalServerError, ""
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		status, detail = he.Code, fmt.Sprint(he.Message)
+	} else {
+		slog.ErrorContext(ctx.Request().Context(), "Request failed", "error", err)
+	}
+	if err := WriteProblem(ctx, Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}); err != nil {
+		slog.ErrorContext(ctx.Request().Context(), "Failed to write error response", "error", err)
+	}
+}
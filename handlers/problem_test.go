@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingDb struct {
+	notFoundDb
+}
+
+func (failingDb) ReadFunds(context.Context, models.FundQuery) (models.Page[models.Fund], error) {
+	return models.Page[models.Fund]{}, errors.New(`pq: relation "funds" does not exist`)
+}
+
+func TestDbError_RedactsInternalErrors(t *testing.T) {
+	e := echo.New()
+	h := Handler{Db: failingDb{notFoundDb{delegate: database.NewMockDb()}}}
+
+	req := httptest.NewRequest(http.MethodGet, "/funds", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-123")
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	err := h.ReadFunds(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	problem := decodeProblem(t, rec)
+	assert.Equal(t, ProblemInternal, problem.Type)
+	assert.Equal(t, "Failed to read funds from database", problem.Detail)
+	assert.NotContains(t, rec.Body.String(), "relation")
+	assert.Equal(t, "/funds", problem.Instance)
+	assert.Equal(t, "req-123", problem.RequestID)
+}
+
+func TestHTTPErrorHandler_UnknownRoute(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+
+	req := httptest.NewRequest(http.MethodGet, "/nope", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	problem := decodeProblem(t, rec)
+	assert.Equal(t, "about:blank", problem.Type)
+	assert.Equal(t, "Not Found", problem.Title)
+}
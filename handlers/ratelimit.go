@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+// Rate limit headers, from the IETF draft "RateLimit header fields for HTTP".
+const (
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+	headerRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// RateLimit is middleware that limits each client to policy, taking a
+// token from its bucket in store for every request. Clients are told where
+// they stand in RateLimit-* headers, and requests over the limit fail with
+// 429 and Retry-After. Authenticated callers are told apart by their
+// subject, so that an API key or user has the same limit from any address;
+// others by their IP address. If store fails, the request is let through:
+// an outage of the limiter should not become an outage of the API. It must
+// run after Authenticate, when that is in use.
+func RateLimit(store ratelimit.Store, policy ratelimit.Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			bucket, limit := policy.For(ctx.Request().Method + " " + ctx.Path())
+			res, ok := takeToken(ctx, store, rateLimitClient(ctx)+" "+bucket, limit)
+			if !ok {
+				return next(ctx)
+			}
+			setRateLimitHeaders(ctx, limit, res)
+			if !res.Allowed {
+				return rateLimited(ctx, res)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// RateLimitAddress is middleware that limits each IP address to
+// policy.Address, whoever the caller turns out to be. It runs before
+// Authenticate, so that requests that fail authentication, such as guessed
+// API keys, are limited too, before they cost a lookup. Only a request over
+// the limit gets RateLimit-* headers from it; the rest get RateLimit's.
+func RateLimitAddress(store ratelimit.Store, policy ratelimit.Policy) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			res, ok := takeToken(ctx, store, "addr:"+ctx.RealIP(), policy.Address)
+			if ok && !res.Allowed {
+				setRateLimitHeaders(ctx, policy.Address, res)
+				return rateLimited(ctx, res)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// takeToken takes a token from key's bucket in store, reporting false if
+// store failed, in which case the request should be let through.
+func takeToken(ctx echo.Context, store ratelimit.Store, key string, limit ratelimit.Limit) (ratelimit.Result, bool) {
+	res, err := store.Take(ctx.Request().Context(), key, limit)
+	if err != nil {
+		slog.ErrorContext(ctx.Request().Context(), "Rate limiting failed, allowing the request", "error", err)
+		return ratelimit.Result{}, false
+	}
+	return res, true
+}
+
+func setRateLimitHeaders(ctx echo.Context, limit ratelimit.Limit, res ratelimit.Result) {
+	header := ctx.Response().Header()
+	header.Set(headerRateLimitLimit, strconv.Itoa(limit.Requests))
+	header.Set(headerRateLimitRemaining, strconv.Itoa(res.Remaining))
+	header.Set(headerRateLimitReset, seconds(res.Reset))
+	header.Set(headerRateLimitPolicy, fmt.Sprintf("%d;w=%s", limit.Requests, seconds(limit.Per)))
+}
+
+// rateLimited fails a request that is over its limit.
+func rateLimited(ctx echo.Context, res ratelimit.Result) error {
+	ctx.Response().Header().Set(echo.HeaderRetryAfter, seconds(res.RetryAfter))
+	return WriteProblem(ctx, Problem{
+		Type:   ProblemRateLimited,
+		Title:  "Too many requests",
+		Status: http.StatusTooManyRequests,
+		Detail: fmt.Sprintf("Too many requests from this client; retry in %s seconds.", seconds(res.RetryAfter)),
+	})
+}
+
+// rateLimitClient names the client a request is counted against.
+func rateLimitClient(ctx echo.Context) string {
+	if id, ok := Identity(ctx); ok {
+		return "sub:" + id.Subject
+	}
+	return "ip:" + ctx.RealIP()
+}
+
+// seconds formats d as whole seconds, rounded up, as the headers want.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/ratelimit"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	verifier, err := auth.NewStaticVerifier(testAuthKey, auth.Options{})
+	assert.NoError(t, err)
+	h := Handler{Db: database.NewMockDb()}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			if ctx.Request().Header.Get(echo.HeaderAuthorization) == "" {
+				return next(ctx)
+			}
+			return h.Authenticate(verifier)(next)(ctx)
+		}
+	})
+	e.Use(RateLimit(ratelimit.NewMemoryStore(), ratelimit.Policy{
+		Default: ratelimit.Limit{Requests: 3, Per: time.Minute},
+		Routes:  map[string]ratelimit.Limit{"GET /search": {Requests: 1, Per: time.Minute}},
+	}))
+	e.GET("/funds", h.ReadFunds)
+	e.GET("/search", h.Search)
+
+	serve := func(target, authorization, addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.RemoteAddr = addr
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	token, err := auth.Sign(testAuthKey, "alice", time.Hour, auth.Options{}, map[string]any{"roles": []string{"admin"}})
+	assert.NoError(t, err)
+	alice := "Bearer " + token
+
+	rec := serve("/funds", alice, "192.0.2.1:1234")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "20", rec.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "3;w=60", rec.Header().Get("RateLimit-Policy"))
+
+	serve("/funds", alice, "192.0.2.2:1234")
+	serve("/funds", alice, "192.0.2.3:1234")
+	rec = serve("/funds", alice, "192.0.2.4:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "callers are limited by subject, from any address")
+	assert.Equal(t, ProblemRateLimited, decodeProblem(t, rec).Type)
+	assert.Equal(t, "20", rec.Header().Get(echo.HeaderRetryAfter))
+	assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+
+	rec = serve("/search?q=fund", alice, "192.0.2.1:1234")
+	assert.Equal(t, http.StatusOK, rec.Code, "routes with their own limit have their own bucket")
+	rec = serve("/search?q=fund", alice, "192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get(echo.HeaderRetryAfter))
+
+	rec = serve("/funds", "", "192.0.2.1:1234")
+	assert.Equal(t, http.StatusOK, rec.Code, "anonymous callers are limited by address")
+	serve("/funds", "", "192.0.2.1:1234")
+	serve("/funds", "", "192.0.2.1:1234")
+	rec = serve("/funds", "", "192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	rec = serve("/funds", "", "192.0.2.9:1234")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRateLimitAddress(t *testing.T) {
+	verifier, err := auth.NewStaticVerifier(testAuthKey, auth.Options{})
+	assert.NoError(t, err)
+	h := Handler{Db: database.NewMockDb()}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RateLimitAddress(ratelimit.NewMemoryStore(), ratelimit.Policy{Address: ratelimit.Limit{Requests: 2, Per: time.Minute}}))
+	e.Use(h.Authenticate(verifier))
+	e.GET("/funds", h.ReadFunds)
+
+	serve := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/funds", nil)
+		req.RemoteAddr = addr
+		req.Header.Set(echo.HeaderAuthorization, "Bearer tbk_guessed")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := serve("192.0.2.1:1234")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, rec.Header().Get("RateLimit-Limit"), "headers are only sent over the limit")
+	serve("192.0.2.1:1234")
+	rec = serve("192.0.2.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "failed authentication is limited too")
+	assert.Equal(t, "2", rec.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "30", rec.Header().Get(echo.HeaderRetryAfter))
+	rec = serve("192.0.2.2:1234")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
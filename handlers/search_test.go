@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func seedSearch(db database.Db) {
+	for _, name := range []string{"Growth", "Growth Equity II", "Titan Growth", "Evergreen Credit"} {
+		_, _ = db.CreateFund(context.Background(), models.CreateFund{
+			Name:          name,
+			VintageYear:   2021,
+			TargetSizeUsd: decimal.NewFromInt(1_000_000),
+			Status:        "Fundraising",
+		})
+	}
+	for _, ci := range []models.CreateInvestor{
+		{Name: "Alex Rivera", InvestorType: "Individual", Email: "alex@rivera.net"},
+		{Name: "Sam Jones", InvestorType: "Institution", Email: "sam.jones@acmepension.com"},
+	} {
+		_, _ = db.CreateInvestor(context.Background(), ci)
+	}
+}
+
+func search(t *testing.T, h Handler, q string) (int, models.Page[models.SearchResult]) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/search?q="+url.QueryEscape(q), nil)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+
+	err := h.Search(ctx)
+	assert.NoError(t, err)
+
+	var page models.Page[models.SearchResult]
+	_ = json.Unmarshal(rec.Body.Bytes(), &page)
+	return rec.Code, page
+}
+
+func TestSearch_RanksExactPrefixWordAndSubstring(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		seedSearch(db)
+		h := Handler{Db: db}
+
+		code, page := search(t, h, "growth")
+		assert.Equal(t, http.StatusOK, code)
+
+		var names []string
+		for _, r := range page.Items {
+			assert.Equal(t, "fund", r.Type)
+			names = append(names, r.Fund.Name)
+		}
+		assert.Equal(t, []string{"Growth", "Growth Equity II", "Titan Growth"}, names)
+		assert.Greater(t, page.Items[0].Score, page.Items[1].Score)
+		assert.Greater(t, page.Items[1].Score, page.Items[2].Score)
+	})
+}
+
+func TestSearch_InvestorByEmailDomain(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		seedSearch(db)
+		h := Handler{Db: db}
+
+		code, page := search(t, h, "acmepension.com")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "investor", page.Items[0].Type)
+		assert.Equal(t, "Sam Jones", page.Items[0].Investor.Name)
+	})
+}
+
+func TestSearch_FuzzyMatchesTypos(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		seedSearch(db)
+		h := Handler{Db: db}
+
+		code, page := search(t, h, "Alex Riviera")
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEmpty(t, page.Items)
+		assert.Equal(t, "Alex Rivera", page.Items[0].Investor.Name)
+		assert.Less(t, page.Items[0].Score, 0.6)
+	})
+}
+
+func TestSearch_QueryTooShort(t *testing.T) {
+	h := Handler{Db: database.NewMockDb()}
+
+	code, _ := search(t, h, " a ")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/iuhmirza/titanbay-take-home/database/access"
+	"github.com/labstack/echo/v4"
+)
+
+// headerTenant names the tenant a request is for. Callers bound to a tenant
+// need not send it.
+const headerTenant = "X-Tenant-ID"
+
+// tenantPattern is what tenant IDs look like: short lowercase slugs.
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Tenant is middleware that confines each request to one tenant, by storing
+// it in the access.Scope of the request's context. Callers bound to a tenant,
+// by their token's tenant_id claim or their API key, act for it, and may not
+// name another in X-Tenant-ID. Operators bound to none act for the tenant
+// X-Tenant-ID names, or the default tenant, as does every request when
+// authentication is off. Any other caller is refused, rather than trusted
+// with every tenant's data. It must run after Authenticate, when that is in
+// use.
+func Tenant(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(ctx echo.Context) error {
+		req := ctx.Request()
+		tenant := req.Header.Get(headerTenant)
+		if tenant != "" && !tenantPattern.MatchString(tenant) {
+			return InvalidRequest(ctx, "Invalid "+headerTenant+" header", errors.New("must be a lowercase slug of up to 63 characters"))
+		}
+		if id, ok := Identity(ctx); ok {
+			switch {
+			case id.Tenant != "":
+				if tenant != "" && tenant != id.Tenant {
+					return WriteProblem(ctx, Problem{
+						Type:   ProblemForbidden,
+						Title:  "Forbidden",
+						Status: http.StatusForbidden,
+						Detail: fmt.Sprintf("You cannot act for tenant %s.", tenant),
+					})
+				}
+				tenant = id.Tenant
+			case !id.Operator():
+				return WriteProblem(ctx, Problem{
+					Type:   ProblemForbidden,
+					Title:  "Forbidden",
+					Status: http.StatusForbidden,
+					Detail: "Your credentials are not bound to a tenant.",
+				})
+			}
+		}
+		scope := access.FromContext(req.Context())
+		scope.Tenant = tenant
+		ctx.SetRequest(req.WithContext(access.NewContext(req.Context(), scope)))
+		return next(ctx)
+	}
+}
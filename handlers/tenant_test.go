@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/auth"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenant(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		verifier, err := auth.NewStaticVerifier(testAuthKey, auth.Options{})
+		assert.NoError(t, err)
+		h := Handler{Db: db}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.Use(h.Authenticate(verifier))
+		e.Use(Tenant)
+		e.GET("/funds", h.ReadFunds, Authorize(auth.FundRead))
+		e.POST("/funds", h.CreateFund, Authorize(auth.FundCreate))
+
+		serve := func(method, target, body, authorization, tenant string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+			if tenant != "" {
+				req.Header.Set(headerTenant, tenant)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+		bearer := func(claims map[string]any) string {
+			token, err := auth.Sign(testAuthKey, "alice", time.Hour, auth.Options{}, claims)
+			assert.NoError(t, err)
+			return "Bearer " + token
+		}
+		operator := bearer(map[string]any{"roles": []string{"admin", "operator"}})
+		unbound := bearer(map[string]any{"roles": []string{"read-only"}})
+		acme := bearer(map[string]any{"roles": []string{"admin"}, "tenant_id": "acme"})
+		fund := `{"name":"Fund A","vintage_year":2024,"target_size_usd":"1000000","status":"Fundraising"}`
+
+		rec := serve(http.MethodPost, "/funds", fund, acme, "")
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"tenant_id":"acme"`)
+
+		rec = serve(http.MethodGet, "/funds", "", acme, "")
+		assert.Contains(t, rec.Body.String(), "Fund A")
+		rec = serve(http.MethodGet, "/funds", "", acme, "acme")
+		assert.Contains(t, rec.Body.String(), "Fund A", "a bound caller may name its own tenant")
+		rec = serve(http.MethodGet, "/funds", "", operator, "")
+		assert.NotContains(t, rec.Body.String(), "Fund A", "operators default to the default tenant")
+		rec = serve(http.MethodGet, "/funds", "", operator, "acme")
+		assert.Contains(t, rec.Body.String(), "Fund A", "operators choose a tenant by header")
+
+		rec = serve(http.MethodGet, "/funds", "", acme, "globex")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, "You cannot act for tenant globex.", decodeProblem(t, rec).Detail)
+
+		rec = serve(http.MethodGet, "/funds", "", unbound, "acme")
+		assert.Equal(t, http.StatusForbidden, rec.Code, "only operators may go without a tenant")
+		assert.Equal(t, "Your credentials are not bound to a tenant.", decodeProblem(t, rec).Detail)
+		rec = serve(http.MethodGet, "/funds", "", unbound, "")
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+
+		rec = serve(http.MethodGet, "/funds", "", operator, "Not A Tenant")
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
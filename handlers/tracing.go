@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/iuhmirza/titanbay-take-home/logging"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the instrumentation scope of Trace's spans.
+const tracerName = "github.com/iuhmirza/titanbay-take-home/handlers"
+
+// Trace is middleware that serves each request in a server span from tp,
+// named after its method and route, such as "GET /funds/:fund_id". A W3C
+// traceparent header from the caller makes the span part of the caller's
+// trace. The span is in the request's context, so that the Db's spans are
+// its children, and its trace ID is logged with the request as trace_id.
+// 5xx responses mark the span as an error. Like AccessLog, it handles
+// errors from later handlers itself, so that the status recorded is the one
+// sent.
+func Trace(tp trace.TracerProvider) echo.MiddlewareFunc {
+	tracer := tp.Tracer(tracerName)
+	propagator := propagation.TraceContext{}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			req := ctx.Request()
+			parent := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			spanCtx, span := tracer.Start(parent, req.Method+" "+ctx.Path(),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(ctx.Path()),
+					semconv.URLPath(req.URL.Path),
+				))
+			defer span.End()
+			if sc := span.SpanContext(); sc.IsValid() {
+				spanCtx = logging.NewContext(spanCtx, slog.String("trace_id", sc.TraceID().String()))
+			}
+			ctx.SetRequest(req.WithContext(spanCtx))
+
+			if err := next(ctx); err != nil {
+				ctx.Error(err)
+			}
+			status := ctx.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return nil
+		}
+	}
+}
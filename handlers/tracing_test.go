@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(logging.New(&buf, slog.LevelInfo))
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	h := Handler{Db: database.Traced(database.NewMockDb(), tp)}
+	e := echo.New()
+	e.HTTPErrorHandler = HTTPErrorHandler
+	e.Use(RequestID)
+	e.Use(Trace(tp))
+	e.Use(AccessLog)
+	e.GET("/funds/:fund_id", h.ReadFundByID)
+
+	req := httptest.NewRequest(http.MethodGet, "/funds/00000000-0000-0000-0000-000000000001", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	dbSpan, server := spans[0], spans[1]
+	assert.Equal(t, "GET /funds/:fund_id", server.Name)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String(), "the caller's trace is continued")
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, codes.Unset, server.Status.Code, "4xx responses are not errors")
+	assert.Contains(t, server.Attributes, attribute.Int("http.response.status_code", http.StatusNotFound))
+	assert.Equal(t, "Db.ReadFundByID", dbSpan.Name)
+	assert.Equal(t, server.SpanContext.SpanID(), dbSpan.Parent.SpanID())
+	assert.Contains(t, buf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+
+	exporter.Reset()
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/funds/00000000-0000-0000-0000-000000000001", nil))
+	spans = exporter.GetSpans()
+	assert.False(t, spans[len(spans)-1].Parent.IsValid(), "requests without traceparent start a trace")
+}
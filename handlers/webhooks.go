@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+)
+
+// createdWebhook is the response to creating a webhook, the only one that
+// includes its secret. Retries with the same Idempotency-Key get the webhook
+// without it.
+type createdWebhook struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// CreateWebhook subscribes a URL to events. Without a secret in the
+// request, one is generated; either way it is returned once, here.
+func (h Handler) CreateWebhook(ctx echo.Context) error {
+	var cw models.CreateWebhook
+	if err := ctx.Bind(&cw); err != nil {
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
+	}
+	if err := h.validateWebhook(cw); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+	if cw.Secret == "" {
+		cw.Secret = newWebhookSecret()
+	}
+
+	webhook, err := h.Db.CreateWebhook(ctx.Request().Context(), cw)
+	if err != nil {
+		return DbError(ctx, "Failed to create webhook", err)
+	}
+	setETag(ctx, webhook.Version)
+	replayWithout(ctx, webhook)
+	return ctx.JSON(http.StatusCreated, createdWebhook{webhook, webhook.Secret})
+}
+
+// validateWebhook validates cw and, unless InsecureWebhooks is set,
+// requires its URL to use https, so that deliveries cannot be read or
+// altered on their way.
+func (h Handler) validateWebhook(cw models.CreateWebhook) error {
+	if err := cw.Validate(); err != nil {
+		return err
+	}
+	if u, _ := url.Parse(cw.URL); !h.InsecureWebhooks && u.Scheme != "https" {
+		return models.ValidationErrors{{Field: "url", Message: "url must use https"}}
+	}
+	return nil
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+func (h Handler) ReadWebhooks(ctx echo.Context) error {
+	var query models.WebhookQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	webhooks, err := h.Db.ReadWebhooks(ctx.Request().Context(), query)
+	if err != nil {
+		return DbError(ctx, "Failed to read webhooks", err)
+	}
+	return ctx.JSON(http.StatusOK, webhooks)
+}
+
+func (h Handler) ReadWebhookByID(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("webhook_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for webhook_id", err)
+	}
+
+	webhook, err := h.Db.ReadWebhookByID(ctx.Request().Context(), id)
+	if err != nil {
+		return DbError(ctx, "Failed to read webhook", err)
+	}
+	setETag(ctx, webhook.Version)
+	return ctx.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook replaces a webhook's url, event_types and enabled flag,
+// checking If-Match or the body's version as UpdateFund does. Enabling a
+// disabled webhook resumes its pending deliveries.
+func (h Handler) UpdateWebhook(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("webhook_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for webhook_id", err)
+	}
+	var webhook models.Webhook
+	if err := ctx.Bind(&webhook); err != nil {
+		return InvalidRequest(ctx, "Invalid JSON payload", err)
+	}
+	webhook.ID = id
+	writable := webhook.Writable()
+	if err := h.validateWebhook(writable); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	pre := ifMatch(ctx)
+	reqCtx := ctx.Request().Context()
+	var updated models.Webhook
+	err = h.Db.WithTx(reqCtx, func(tx database.Db) error {
+		if pre.present {
+			current, err := tx.ReadWebhookByID(reqCtx, id)
+			if err != nil {
+				return err
+			}
+			if err := pre.check("webhook", current.Version); err != nil {
+				return err
+			}
+			webhook.Version = current.Version
+		}
+		var err error
+		updated, err = tx.UpdateWebhook(reqCtx, webhook)
+		return err
+	})
+	if err != nil {
+		return DbError(ctx, "Failed to update webhook", err)
+	}
+
+	setETag(ctx, updated.Version)
+	return ctx.JSON(http.StatusOK, updated)
+}
+
+func (h Handler) DeleteWebhook(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("webhook_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for webhook_id", err)
+	}
+
+	if err := h.Db.DeleteWebhook(ctx.Request().Context(), id); err != nil {
+		return DbError(ctx, "Failed to delete webhook", err)
+	}
+	return ctx.NoContent(http.StatusNoContent)
+}
+
+// ReadWebhookDeliveries lists a webhook's delivery log.
+func (h Handler) ReadWebhookDeliveries(ctx echo.Context) error {
+	id, err := uuid.Parse(ctx.Param("webhook_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for webhook_id", err)
+	}
+	var query models.WebhookDeliveryQuery
+	if err := ctx.Bind(&query); err != nil {
+		return InvalidRequest(ctx, "Invalid query parameters", err)
+	}
+	if err := query.Validate(); err != nil {
+		return ValidationFailed(ctx, err)
+	}
+
+	reqCtx := ctx.Request().Context()
+	if _, err := h.Db.ReadWebhookByID(reqCtx, id); err != nil {
+		return DbError(ctx, "Failed to read webhook", err)
+	}
+	deliveries, err := h.Db.ReadWebhookDeliveries(reqCtx, id, query)
+	if err != nil {
+		return DbError(ctx, "Failed to read webhook deliveries", err)
+	}
+	return ctx.JSON(http.StatusOK, deliveries)
+}
+
+// ReplayWebhookDelivery queues a past delivery's event to be sent again.
+// It responds 202 with the new delivery, which is sent in the background.
+func (h Handler) ReplayWebhookDelivery(ctx echo.Context) error {
+	webhookID, err := uuid.Parse(ctx.Param("webhook_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid UUID for webhook_id", err)
+	}
+	deliveryID, err := strconv.Atoi(ctx.Param("delivery_id"))
+	if err != nil {
+		return InvalidRequest(ctx, "Invalid delivery_id", err)
+	}
+
+	delivery, err := h.Db.ReplayWebhookDelivery(ctx.Request().Context(), webhookID, deliveryID)
+	if err != nil {
+		return DbError(ctx, "Failed to replay webhook delivery", err)
+	}
+	return ctx.JSON(http.StatusAccepted, delivery)
+}
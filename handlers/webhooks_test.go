@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/database/dbtest"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhooks_CRUDAndReplay(t *testing.T) {
+	dbtest.EachBackend(t, func(t *testing.T, db database.Db) {
+		h := Handler{Db: db}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.POST("/funds", h.CreateFund)
+		e.GET("/webhooks", h.ReadWebhooks)
+		e.POST("/webhooks", h.CreateWebhook)
+		e.GET("/webhooks/:webhook_id", h.ReadWebhookByID)
+		e.PUT("/webhooks/:webhook_id", h.UpdateWebhook)
+		e.DELETE("/webhooks/:webhook_id", h.DeleteWebhook)
+		e.GET("/webhooks/:webhook_id/deliveries", h.ReadWebhookDeliveries)
+		e.POST("/webhooks/:webhook_id/deliveries/:delivery_id/replay", h.ReplayWebhookDelivery)
+
+		serve := func(method, target, body string, header http.Header) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			for name, values := range header {
+				req.Header[name] = values
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			return rec
+		}
+
+		rec := serve(http.MethodPost, "/webhooks", `{"url":"ftp://example.com","event_types":["FundExploded"],"secret":"short"}`, nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Len(t, decodeProblem(t, rec).Errors, 3)
+
+		rec = serve(http.MethodPost, "/webhooks", `{"url":"https://example.com/hook","event_types":["FundCreated"]}`, nil)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		var created struct {
+			models.Webhook
+			Secret string `json:"secret"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &created)
+		assert.NotEmpty(t, created.Secret, "a secret is generated and returned once")
+		assert.Equal(t, `"1"`, rec.Header().Get(headerETag))
+		path := "/webhooks/" + created.ID.String()
+
+		rec = serve(http.MethodGet, path, "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), created.Secret)
+
+		rec = serve(http.MethodPut, path, `{"url":"https://example.com/v2","event_types":["FundCreated"],"enabled":true}`, http.Header{headerIfMatch: {`"7"`}})
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		rec = serve(http.MethodPut, path, `{"url":"https://example.com/v2","event_types":["FundCreated"],"enabled":true}`, http.Header{headerIfMatch: {`"1"`}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var updated models.Webhook
+		_ = json.Unmarshal(rec.Body.Bytes(), &updated)
+		assert.Equal(t, "https://example.com/v2", updated.URL)
+		assert.Equal(t, 2, updated.Version)
+
+		rec = serve(http.MethodPost, "/funds", `{"name":"Fund A","vintage_year":2021,"target_size_usd":1000000,"status":"Fundraising"}`, nil)
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		rec = serve(http.MethodGet, path+"/deliveries", "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var deliveries models.Page[models.WebhookDelivery]
+		_ = json.Unmarshal(rec.Body.Bytes(), &deliveries)
+		if assert.Len(t, deliveries.Items, 1) {
+			assert.Equal(t, "FundCreated", deliveries.Items[0].EventType)
+			rec = serve(http.MethodPost, path+"/deliveries/"+strconv.Itoa(deliveries.Items[0].ID)+"/replay", "", nil)
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+			var replay models.WebhookDelivery
+			_ = json.Unmarshal(rec.Body.Bytes(), &replay)
+			assert.Equal(t, &deliveries.Items[0].ID, replay.ReplayOf)
+		}
+		rec = serve(http.MethodGet, path+"/deliveries?status=lost", "", nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		rec = serve(http.MethodPost, path+"/deliveries/999/replay", "", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+
+		rec = serve(http.MethodGet, "/webhooks", "", nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var page models.Page[models.Webhook]
+		_ = json.Unmarshal(rec.Body.Bytes(), &page)
+		assert.Len(t, page.Items, 1)
+
+		rec = serve(http.MethodDelete, path, "", nil)
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		rec = serve(http.MethodGet, path, "", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = serve(http.MethodGet, path+"/deliveries", "", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		rec = serve(http.MethodDelete, "/webhooks/"+uuid.NewString(), "", nil)
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
+
+func TestWebhooks_RequireHTTPS(t *testing.T) {
+	for _, insecure := range []bool{false, true} {
+		h := Handler{Db: database.NewMockDb(), InsecureWebhooks: insecure}
+		e := echo.New()
+		e.HTTPErrorHandler = HTTPErrorHandler
+		e.POST("/webhooks", h.CreateWebhook)
+
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(`{"url":"http://localhost:8080/hook"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if insecure {
+			assert.Equal(t, http.StatusCreated, rec.Code, "http is allowed for development")
+		} else {
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, "url must use https", decodeProblem(t, rec).Errors[0].Message)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+)
+
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// idempotencyTTL reads how long idempotency keys are kept from
+// IDEMPOTENCY_TTL, a Go duration such as "48h".
+func idempotencyTTL() (time.Duration, error) {
+	value := os.Getenv("IDEMPOTENCY_TTL")
+	if value == "" {
+		return defaultIdempotencyTTL, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("IDEMPOTENCY_TTL must be a positive duration such as 24h, got %q", value)
+	}
+	return ttl, nil
+}
+
+// purgeIdempotencyKeys deletes expired idempotency keys every interval.
+// Expired keys are ignored anyway; this only reclaims their space.
+func purgeIdempotencyKeys(db database.Db, interval time.Duration) {
+	for range time.Tick(interval) {
+		if _, err := db.PurgeIdempotencyKeys(context.Background()); err != nil {
+			slog.Error("Failed to purge idempotency keys", "error", err)
+		}
+	}
+}
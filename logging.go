@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"github.com/iuhmirza/titanbay-take-home/logging"
+)
+
+// setupLogging makes JSON lines on stdout, at LOG_LEVEL and above, the
+// default log output, including that of the standard log package.
+func setupLogging() error {
+	level, err := logging.ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logging.New(os.Stdout, level))
+	return nil
+}
+
+// fatal logs err and exits.
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
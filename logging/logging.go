@@ -0,0 +1,68 @@
+// Package logging sets up structured logging with log/slog. Records are
+// written as JSON, one per line, and carry the attributes stored in the
+// context they are logged with, so that every line logged while serving a
+// request, from the handlers down to the database, names the request.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New returns a logger that writes records at level and above to w as
+// JSON, with the attributes of their context.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(contextHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})})
+}
+
+// ParseLevel parses debug, info, warn or error. The empty string is info.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q, want debug, info, warn or error", s)
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx whose log records carry attrs, as well
+// as any that ctx's records already carry.
+func NewContext(ctx context.Context, attrs ...slog.Attr) context.Context {
+	return context.WithValue(ctx, contextKey{}, append(Attrs(ctx), attrs...))
+}
+
+// Attrs returns the attributes that records logged with ctx carry.
+func Attrs(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(contextKey{}).([]slog.Attr)
+	return attrs[:len(attrs):len(attrs)]
+}
+
+// contextHandler adds the attributes of each record's context to it.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		r.AddAttrs(Attrs(ctx)...)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
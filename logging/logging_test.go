@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_AddsContextAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+	ctx := NewContext(context.Background(), slog.String("request_id", "req-1"))
+	ctx = NewContext(ctx, slog.String("tenant", "acme"))
+
+	logger.DebugContext(ctx, "hidden")
+	logger.With("component", "test").InfoContext(ctx, "hello", "n", 1)
+	var record map[string]any
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "hello", record["msg"])
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "acme", record["tenant"])
+	assert.Equal(t, "test", record["component"])
+	assert.EqualValues(t, 1, record["n"])
+
+	buf.Reset()
+	logger.Info("no context")
+	assert.NotContains(t, buf.String(), "request_id")
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelInfo, level)
+	level, err = ParseLevel("WARN")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
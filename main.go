@@ -1,33 +1,161 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
+	"github.com/iuhmirza/titanbay-take-home/auth"
 	"github.com/iuhmirza/titanbay-take-home/database"
 	"github.com/iuhmirza/titanbay-take-home/handlers"
 	"github.com/labstack/echo/v4"
 )
 
 func main() {
-	log.Println("Starting server..")
+	if err := setupLogging(); err != nil {
+		fatal(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		if err := runToken(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(os.Args[2:]); err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	slog.Info("Starting server")
 	port := os.Getenv("PORT")
 	if port == "" {
-		log.Fatal("Environment variable PORT not set.")
+		fatal(errors.New("environment variable PORT not set"))
+	}
+	ttl, err := idempotencyTTL()
+	if err != nil {
+		fatal(err)
+	}
+	verifier, err := authVerifier()
+	if err != nil {
+		fatal(err)
+	}
+	policy, err := rateLimitPolicy()
+	if err != nil {
+		fatal(err)
+	}
+	insecure, err := insecureWebhooks()
+	if err != nil {
+		fatal(err)
+	}
+	delay, err := shutdownDelay()
+	if err != nil {
+		fatal(err)
+	}
+	tp, err := tracerProvider(context.Background())
+	if err != nil {
+		fatal(err)
 	}
 	db, err := database.ConnectToDB()
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		fatal(fmt.Errorf("failed to connect to database: %w", err))
+	}
+	if err := startOutboxRelay(db); err != nil {
+		fatal(err)
 	}
-	h := handlers.Handler{Db: db}
+	go webhookDispatcher(db, insecure).Run(context.Background())
+	go purgeIdempotencyKeys(db, time.Hour)
+	limiter, err := rateLimitStore(db)
+	if err != nil {
+		fatal(err)
+	}
+	m, err := newMetrics(db)
+	if err != nil {
+		fatal(err)
+	}
+	h := handlers.Handler{Db: database.Traced(db, tp), InsecureWebhooks: insecure}
 	e := echo.New()
-	e.GET("/funds", h.ReadFunds)
-	e.POST("/funds", h.CreateFund)
-	e.PUT("/funds", h.UpdateFund)
-	e.GET("/funds/:fund_id", h.ReadFundByID)
-	e.GET("/investors", h.ReadInvestors)
-	e.POST("/investors", h.CreateInvestor)
-	e.GET("/funds/:fund_id/investments", h.ReadInvestments)
-	e.POST("/funds/:fund_id/investments", h.CreateInvestment)
-	e.Logger.Fatal(e.Start(port))
+	e.HideBanner, e.HidePort = true, true
+	e.HTTPErrorHandler = handlers.HTTPErrorHandler
+	// Trust X-Forwarded-For only from proxies on private networks, so that
+	// clients cannot pick their own address to be rate limited by.
+	e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	e.Use(handlers.RequestID)
+	e.Use(handlers.Trace(tp))
+	e.Use(handlers.Instrument(m))
+	e.Use(handlers.AccessLog)
+	serveMetrics(m)
+	health := newHealth(db)
+	e.GET("/healthz", health.Live)
+	e.GET("/readyz", health.Ready)
+	// The API's routes are in a group of their own, so that its middleware
+	// does not apply to the health probes.
+	api := e.Group("")
+	if limiter != nil {
+		api.Use(handlers.RateLimitAddress(limiter, policy))
+	} else {
+		slog.Warn("Rate limiting is off: RATE_LIMIT_STORE is none")
+	}
+	can := handlers.Authorize
+	if verifier != nil {
+		api.Use(h.Authenticate(verifier))
+	} else {
+		slog.Warn("Authentication is off: AUTH_MODE is none")
+		can = allowAll
+	}
+	api.Use(handlers.Tenant)
+	if limiter != nil {
+		api.Use(handlers.RateLimit(limiter, policy))
+	}
+	api.Use(handlers.AuditSource)
+	api.Use(h.Idempotency(ttl))
+	api.GET("/funds", h.ReadFunds, can(auth.FundRead))
+	api.POST("/funds", h.CreateFund, can(auth.FundCreate))
+	api.PUT("/funds", h.UpdateFund, can(auth.FundUpdate))
+	api.GET("/funds/:fund_id", h.ReadFundByID, can(auth.FundRead))
+	api.PATCH("/funds/:fund_id", h.PatchFund, can(auth.FundUpdate))
+	api.DELETE("/funds/:fund_id", h.DeleteFund, can(auth.FundDelete))
+	api.POST("/funds/:fund_id/restore", h.RestoreFund, can(auth.FundDelete))
+	api.GET("/funds/:fund_id/history", h.ReadFundHistory, can(auth.FundRead, auth.AuditRead))
+	api.GET("/investors", h.ReadInvestors, can(auth.InvestorRead))
+	api.POST("/investors", h.CreateInvestor, can(auth.InvestorCreate))
+	api.GET("/investors/:investor_id", h.ReadInvestorByID, can(auth.InvestorRead))
+	api.PATCH("/investors/:investor_id", h.PatchInvestor, can(auth.InvestorUpdate))
+	api.DELETE("/investors/:investor_id", h.DeleteInvestor, can(auth.InvestorDelete))
+	api.POST("/investors/:investor_id/restore", h.RestoreInvestor, can(auth.InvestorDelete))
+	api.GET("/investors/:investor_id/history", h.ReadInvestorHistory, can(auth.InvestorRead, auth.AuditRead))
+	api.GET("/funds/:fund_id/investments", h.ReadInvestments, can(auth.InvestmentRead))
+	api.POST("/funds/:fund_id/investments", h.CreateInvestment, can(auth.InvestmentCreate))
+	api.DELETE("/funds/:fund_id/investments/:investment_id", h.DeleteInvestment, can(auth.InvestmentDelete))
+	api.POST("/funds/:fund_id/investments/:investment_id/restore", h.RestoreInvestment, can(auth.InvestmentDelete))
+	api.GET("/search", h.Search, can(auth.FundRead, auth.InvestorRead))
+	api.GET("/audit", h.ReadAuditLog, can(auth.AuditRead))
+	api.GET("/webhooks", h.ReadWebhooks, can(auth.WebhookRead))
+	api.POST("/webhooks", h.CreateWebhook, can(auth.WebhookWrite))
+	api.GET("/webhooks/:webhook_id", h.ReadWebhookByID, can(auth.WebhookRead))
+	api.PUT("/webhooks/:webhook_id", h.UpdateWebhook, can(auth.WebhookWrite))
+	api.DELETE("/webhooks/:webhook_id", h.DeleteWebhook, can(auth.WebhookWrite))
+	api.GET("/webhooks/:webhook_id/deliveries", h.ReadWebhookDeliveries, can(auth.WebhookRead))
+	api.POST("/webhooks/:webhook_id/deliveries/:delivery_id/replay", h.ReplayWebhookDelivery, can(auth.WebhookWrite))
+	api.GET("/api-keys", h.ReadAPIKeys, can(auth.APIKeyManage))
+	api.POST("/api-keys", h.CreateAPIKey, can(auth.APIKeyManage))
+	api.GET("/api-keys/:api_key_id", h.ReadAPIKeyByID, can(auth.APIKeyManage))
+	api.POST("/api-keys/:api_key_id/rotate", h.RotateAPIKey, can(auth.APIKeyManage))
+	api.DELETE("/api-keys/:api_key_id", h.RevokeAPIKey, can(auth.APIKeyManage))
+	if err := checkRateLimitRoutes(e, policy); err != nil {
+		fatal(err)
+	}
+	if err := serve(e, port, health, delay, tp); err != nil {
+		fatal(err)
+	}
 }
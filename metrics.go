@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/metrics"
+)
+
+// newMetrics returns the service's metrics, including db's queries,
+// connection pool and fund gauges.
+func newMetrics(db database.Db) (*metrics.Metrics, error) {
+	maxFunds, err := metricsMaxFunds()
+	if err != nil {
+		return nil, err
+	}
+	m := metrics.New()
+	if err := database.Instrument(db, m); err != nil {
+		return nil, err
+	}
+	if err := m.Register(metrics.NewFundCollector(db, maxFunds)); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// defaultMetricsMaxFunds is how many funds get a series of the per-fund
+// gauge unless METRICS_MAX_FUNDS says otherwise.
+const defaultMetricsMaxFunds = 500
+
+// metricsMaxFunds reads from METRICS_MAX_FUNDS how many of the funds with
+// the most capital committed get a series of the per-fund gauge. 0 turns
+// the gauge off.
+func metricsMaxFunds() (int, error) {
+	value := os.Getenv("METRICS_MAX_FUNDS")
+	if value == "" {
+		return defaultMetricsMaxFunds, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("METRICS_MAX_FUNDS must be a whole number of at least 0, got %q", value)
+	}
+	return n, nil
+}
+
+// defaultMetricsAddr is where metrics are served unless METRICS_ADDR says
+// otherwise.
+const defaultMetricsAddr = ":9090"
+
+// serveMetrics serves m at /metrics, without authentication, on a server of
+// its own at METRICS_ADDR, so that it is never exposed on the API's public
+// port. METRICS_ADDR=none turns it off.
+func serveMetrics(m *metrics.Metrics) {
+	addr := os.Getenv("METRICS_ADDR")
+	switch addr {
+	case "":
+		addr = defaultMetricsAddr
+	case "none":
+		slog.Warn("Metrics are off: METRICS_ADDR is none")
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", m.Handler())
+	go func() {
+		slog.Info("Serving metrics", "addr", addr)
+		fatal(http.ListenAndServe(addr, mux))
+	}()
+}
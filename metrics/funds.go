@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// fundsTimeout bounds the queries a scrape makes for the fund gauges.
+const fundsTimeout = 5 * time.Second
+
+var (
+	fundsDesc = prometheus.NewDesc(namespace+"_funds",
+		"Live funds, by tenant and status.", []string{"tenant", "status"}, nil)
+	committedDesc = prometheus.NewDesc(namespace+"_funds_committed_usd",
+		"Capital committed to live funds by their live investments, by tenant and fund status, in USD.", []string{"tenant", "status"}, nil)
+	fundCommittedDesc = prometheus.NewDesc(namespace+"_fund_committed_usd",
+		"Capital committed to each live fund by its live investments, in USD, for the funds with the most.", []string{"tenant", "fund_id"}, nil)
+)
+
+// FundReader reads the stats that FundCollector reports, such as a
+// database.Db.
+type FundReader interface {
+	ReadFundStats(context.Context) ([]models.FundStats, error)
+	ReadFundCommitments(ctx context.Context, limit int) ([]models.FundCommitment, error)
+}
+
+// FundCollector reports the fund gauges, reading them from db at every
+// scrape, so that they are always current and cost nothing between
+// scrapes. If a read fails, its gauges are left out of that scrape.
+type FundCollector struct {
+	db       FundReader
+	maxFunds int
+}
+
+// NewFundCollector returns a collector of the stats in db. The per-fund
+// gauge has a series for each of the maxFunds live funds with the most
+// capital committed, so that the number of series stays bounded however
+// many funds there are; 0 leaves it out.
+func NewFundCollector(db FundReader, maxFunds int) *FundCollector {
+	return &FundCollector{db: db, maxFunds: maxFunds}
+}
+
+func (c *FundCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fundsDesc
+	ch <- committedDesc
+	ch <- fundCommittedDesc
+}
+
+func (c *FundCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), fundsTimeout)
+	defer cancel()
+	c.collectStats(ctx, ch)
+	if c.maxFunds > 0 {
+		c.collectCommitments(ctx, ch)
+	}
+}
+
+func (c *FundCollector) collectStats(ctx context.Context, ch chan<- prometheus.Metric) {
+	stats, err := c.db.ReadFundStats(ctx)
+	if err != nil {
+		slog.Error("Failed to read fund metrics", "error", err)
+		return
+	}
+	for _, s := range stats {
+		committed, _ := s.CommittedUsd.Float64()
+		ch <- prometheus.MustNewConstMetric(fundsDesc, prometheus.GaugeValue, float64(s.Funds), s.TenantID, s.Status)
+		ch <- prometheus.MustNewConstMetric(committedDesc, prometheus.GaugeValue, committed, s.TenantID, s.Status)
+	}
+}
+
+func (c *FundCollector) collectCommitments(ctx context.Context, ch chan<- prometheus.Metric) {
+	commitments, err := c.db.ReadFundCommitments(ctx, c.maxFunds)
+	if err != nil {
+		slog.Error("Failed to read fund commitment metrics", "error", err)
+		return
+	}
+	for _, f := range commitments {
+		committed, _ := f.CommittedUsd.Float64()
+		ch <- prometheus.MustNewConstMetric(fundCommittedDesc, prometheus.GaugeValue, committed, f.TenantID, f.FundID.String())
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	m.ObserveRequest("GET", "/funds/:fund_id", 200, 30*time.Millisecond)
+	m.ObserveRequest("GET", "/funds/:fund_id", 200, 10*time.Millisecond)
+	m.ObserveRequest("GET", "/funds/:fund_id", 404, time.Millisecond)
+	m.ObserveQuery("query", "funds", 2*time.Millisecond)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/funds/:fund_id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.requests.WithLabelValues("GET", "/funds/:fund_id", "404")))
+	body := scrape(t, m)
+	assert.Contains(t, body, `titanbay_http_request_duration_seconds_count{method="GET",route="/funds/:fund_id",status="200"} 2`)
+	assert.Contains(t, body, `titanbay_db_query_duration_seconds_count{operation="query",table="funds"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+// fundReader returns stats and commitments, or err.
+type fundReader struct {
+	stats       []models.FundStats
+	commitments []models.FundCommitment
+	err         error
+}
+
+func (r *fundReader) ReadFundStats(context.Context) ([]models.FundStats, error) {
+	return r.stats, r.err
+}
+
+func (r *fundReader) ReadFundCommitments(_ context.Context, limit int) ([]models.FundCommitment, error) {
+	return r.commitments[:min(limit, len(r.commitments))], r.err
+}
+
+func TestFundCollector(t *testing.T) {
+	large, small := uuid.New(), uuid.New()
+	db := &fundReader{
+		stats: []models.FundStats{
+			{TenantID: "default", Status: "Fundraising", Funds: 2, CommittedUsd: decimal.RequireFromString("350.75")},
+			{TenantID: "acme", Status: "Closed", Funds: 1},
+		},
+		commitments: []models.FundCommitment{
+			{TenantID: "default", FundID: large, CommittedUsd: decimal.RequireFromString("250.25")},
+			{TenantID: "default", FundID: small, CommittedUsd: decimal.RequireFromString("100.50")},
+		},
+	}
+	m := New()
+	assert.NoError(t, m.Register(NewFundCollector(db, 1)))
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `titanbay_funds{status="Fundraising",tenant="default"} 2`)
+	assert.Contains(t, body, `titanbay_funds{status="Closed",tenant="acme"} 1`)
+	assert.Contains(t, body, `titanbay_funds_committed_usd{status="Fundraising",tenant="default"} 350.75`)
+	assert.Contains(t, body, `titanbay_funds_committed_usd{status="Closed",tenant="acme"} 0`)
+	assert.Contains(t, body, `titanbay_fund_committed_usd{fund_id="`+large.String()+`",tenant="default"} 250.25`)
+	assert.NotContains(t, body, small.String(), "only the funds with the most committed get a series")
+
+	db.err = errors.New("database unavailable")
+	body = scrape(t, m)
+	assert.NotContains(t, body, "titanbay_fund", "gauges are left out when they cannot be read")
+	assert.Contains(t, body, "go_goroutines")
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up | down | status | to <version>")
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+	db, err := database.Open()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = migrator.To(ctx, version)
+	case "status":
+		return printMigrationStatus(ctx, migrator)
+	default:
+		return errMigrateUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := migrator.Current(ctx)
+	if err != nil {
+		return err
+	}
+	slog.Info("Migrated database schema", "version", current, "latest", migrator.Latest())
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *database.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	return w.Flush()
+}
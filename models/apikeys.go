@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyScopes lists the scopes an API key can be given: every action in
+// package auth.
+var APIKeyScopes = []string{
+	"fund:read", "fund:create", "fund:update", "fund:delete",
+	"investor:read", "investor:create", "investor:update", "investor:delete",
+	"investment:read", "investment:create", "investment:delete",
+	"audit:read", "webhook:read", "webhook:write", "api-key:manage",
+}
+
+const maxAPIKeyNameLength = 100
+
+type CreateAPIKey struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (key *CreateAPIKey) Validate() error {
+	var errs ValidationErrors
+	if strings.TrimSpace(key.Name) == "" {
+		errs.Add("name", "name is required")
+	} else if len(key.Name) > maxAPIKeyNameLength {
+		errs.Add("name", fmt.Sprintf("name must be at most %d characters", maxAPIKeyNameLength))
+	}
+	if len(key.Scopes) == 0 {
+		errs.Add("scopes", "scopes is required")
+	}
+	for _, s := range key.Scopes {
+		if !slices.Contains(APIKeyScopes, s) {
+			errs.Add("scopes", fmt.Sprintf("scopes must only contain '%s'", strings.Join(APIKeyScopes, "', '")))
+			break
+		}
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		errs.Add("expires_at", "expires_at must be in the future")
+	}
+	return errs.OrNil()
+}
+
+// APIKey authenticates a machine client, which may do what Scopes permit.
+// Only a hash of the key is stored, so it cannot be shown again after it
+// is issued or rotated; Prefix, its first few characters, tells keys apart.
+// Revoked and expired keys are kept, but no longer authenticate. A key acts
+// for the tenant it was issued in.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" gorm:"primaryKey;type:uuid"`
+	Name       string     `json:"name" gorm:"not null"`
+	Prefix     string     `json:"prefix" gorm:"not null"`
+	Hash       string     `json:"-" gorm:"not null"`
+	Scopes     []string   `json:"scopes" gorm:"not null;serializer:json"`
+	CreatedBy  string     `json:"created_by" gorm:"not null"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Tenancy
+}
+
+func (APIKey) TableName() string { return "api_keys" }
+
+// Active reports whether the key authenticates at now.
+func (key APIKey) Active(now time.Time) bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(now))
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// IdempotencyKey records a request made with an Idempotency-Key header and,
+// once it has completed, its response, so that retries of the request get
+// the same response instead of repeating it. Keys belong to the subject
+// that sent them, in the tenant it acted for, so different callers may use
+// the same key. RequestHash identifies the request the key was first used
+// for. StatusCode is zero while the request is in progress.
+type IdempotencyKey struct {
+	TenantID       string              `gorm:"primaryKey"`
+	Subject        string              `gorm:"primaryKey"`
+	Key            string              `gorm:"column:idempotency_key;primaryKey"`
+	RequestHash    string              `gorm:"not null"`
+	StatusCode     int                 `gorm:"not null"`
+	ResponseHeader map[string][]string `gorm:"serializer:json"`
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time `gorm:"not null"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Completed reports whether the key's response has been stored.
+func (k IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
@@ -1,7 +1,7 @@
 package models
 
 import (
-	"errors"
+	"encoding/json"
 	"fmt"
 	"net/mail"
 	"time"
@@ -18,28 +18,26 @@ type CreateFund struct {
 }
 
 func (fund *CreateFund) Validate() error {
+	var errs ValidationErrors
 	if fund.Name == "" {
-		return errors.New("name is required")
+		errs.Add("name", "name is required")
 	}
 	if fund.VintageYear < 1900 {
-		return errors.New("vintage_year must be greater than 1900")
-	}
-	if fund.VintageYear >= 2100 {
-		return errors.New("vintage_year must be less than 2100")
+		errs.Add("vintage_year", "vintage_year must be greater than 1900")
+	} else if fund.VintageYear >= 2100 {
+		errs.Add("vintage_year", "vintage_year must be less than 2100")
 	}
 	if fund.TargetSizeUsd.LessThan(decimal.NewFromInt(1_000_000)) {
-		return errors.New("target_size_usd must be greater than or equal to 1,000,000.00")
+		errs.Add("target_size_usd", "target_size_usd must be greater than or equal to 1,000,000.00")
 	}
 
 	if fund.Status == "" {
-		return errors.New("status is required")
-	}
-
-	if fund.Status != "Fundraising" && fund.Status != "Investing" && fund.Status != "Closed" {
-		return errors.New("status must be either 'Fundraising', 'Investing', or 'Closed'")
+		errs.Add("status", "status is required")
+	} else if fund.Status != "Fundraising" && fund.Status != "Investing" && fund.Status != "Closed" {
+		errs.Add("status", "status must be either 'Fundraising', 'Investing', or 'Closed'")
 	}
 
-	return nil
+	return errs.OrNil()
 }
 
 type CreateInvestor struct {
@@ -49,26 +47,24 @@ type CreateInvestor struct {
 }
 
 func (investor *CreateInvestor) Validate() error {
+	var errs ValidationErrors
 	if investor.Name == "" {
-		return errors.New("name is required")
+		errs.Add("name", "name is required")
 	}
 	if investor.InvestorType == "" {
-		return errors.New("investor_type is required")
-	}
-	if
-	investor.InvestorType != "Individual" &&
-	investor.InvestorType != "Institution" &&
-	investor.InvestorType != "Family Office" {
-		return errors.New("investor_type must be either 'Individual', 'Institution' or 'Family Office'")
+		errs.Add("investor_type", "investor_type is required")
+	} else if investor.InvestorType != "Individual" &&
+		investor.InvestorType != "Institution" &&
+		investor.InvestorType != "Family Office" {
+		errs.Add("investor_type", "investor_type must be either 'Individual', 'Institution' or 'Family Office'")
 	}
 	if investor.Email == "" {
-		return errors.New("email is required")
-	}
-	if _, err := mail.ParseAddress(investor.Email); err != nil {
-		return fmt.Errorf("email is invalid: %w", err)
+		errs.Add("email", "email is required")
+	} else if _, err := mail.ParseAddress(investor.Email); err != nil {
+		errs.Add("email", fmt.Sprintf("email is invalid: %v", err))
 	}
-	
-	return nil
+
+	return errs.OrNil()
 }
 
 type CreateInvestment struct {
@@ -79,34 +75,28 @@ type CreateInvestment struct {
 }
 
 func (investment *CreateInvestment) Validate() error {
-	if err := uuid.Validate(investment.InvestorID.String()); err != nil {
-		return fmt.Errorf("invalid uuid for investor_id: %w", err)
+	var errs ValidationErrors
+	if investment.InvestorID == uuid.Nil {
+		errs.Add("investor_id", "investor_id is required")
 	}
-	if err := uuid.Validate(investment.FundID.String()); err != nil {
-		return fmt.Errorf("invalid uuid for fund_id: %w", err)
+	if investment.FundID == uuid.Nil {
+		errs.Add("fund_id", "fund_id is required")
 	}
 	if investment.AmountUsd.LessThan(decimal.NewFromInt(1)) {
-		return errors.New("amount_usd must be greater than 1")
+		errs.Add("amount_usd", "amount_usd must be greater than 1")
 	}
 	if investment.InvestmentDate == "" {
-		return errors.New("investment_date is required")
-	}
-	
-	ti, err := time.Parse("2006-01-02", investment.InvestmentDate)
-	if err != nil {
-		return errors.New("investment_date is invalid, use 'yyyy-mm-dd'")
-	}
-	year, _, _ := ti.Date()
-	if year <= 1900 {
-		return errors.New("year of investement_date must be greater than 1900")
-	}
-	if year >= 2100 {
-		return errors.New("year of investement_date must be less than 2100")
-	} 
-	return nil
+		errs.Add("investment_date", "investment_date is required")
+	} else if ti, err := time.Parse("2006-01-02", investment.InvestmentDate); err != nil {
+		errs.Add("investment_date", "investment_date is invalid, use 'yyyy-mm-dd'")
+	} else if year := ti.Year(); year <= 1900 {
+		errs.Add("investment_date", "year of investment_date must be greater than 1900")
+	} else if year >= 2100 {
+		errs.Add("investment_date", "year of investment_date must be less than 2100")
+	}
+	return errs.OrNil()
 }
 
-
 type Fund struct {
 	ID            uuid.UUID       `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	Name          string          `json:"name" gorm:"not null"`
@@ -114,16 +104,88 @@ type Fund struct {
 	TargetSizeUsd decimal.Decimal `json:"target_size_usd" gorm:"type:numeric(20,2);not null;default:0"`
 	Status        string          `json:"status" gorm:"type:text;not null;check:fund_status_chk,status IN ('Fundraising','Investing','Closed')"`
 	CreatedAt     time.Time       `json:"created_at"`
+	Version       int             `json:"version" gorm:"not null;default:1"`
 	Investments   []Investment    `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Tenancy
+	Deletion
 }
 
 type Investor struct {
 	ID           uuid.UUID    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	Name         string       `json:"name" gorm:"not null"`
 	InvestorType string       `json:"investor_type" gorm:"type:text;not null;check:investor_type_chk,investor_type IN ('Individual','Institution','Family Office')"`
-	Email        string       `json:"email" gorm:"not null;uniqueIndex;size:320"`
+	Email        string       `json:"email" gorm:"not null;uniqueIndex:idx_investors_email,where:deleted_at IS NULL;size:320"`
 	CreatedAt    time.Time    `json:"created_at"`
+	Version      int          `json:"version" gorm:"not null;default:1"`
 	Investments  []Investment `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Tenancy
+	Deletion
+}
+
+// DefaultTenant owns the rows written before tenants were introduced, and
+// those written for callers that name no tenant.
+const DefaultTenant = "default"
+
+// Tenancy records the tenant, a management company, that a row belongs to.
+// Callers only ever see the rows of their own tenant; see access.Scope.
+type Tenancy struct {
+	TenantID string `json:"tenant_id" gorm:"not null"`
+}
+
+// EnsureTenant gives t the default tenant if it names none, as the events
+// recorded before tenants were introduced do not.
+func (t *Tenancy) EnsureTenant() {
+	if t.TenantID == "" {
+		t.TenantID = DefaultTenant
+	}
+}
+
+// Deletion records when, by whom and why a row was soft-deleted. Rows with a
+// nil DeletedAt are live; deleted rows are kept so that they can be restored.
+type Deletion struct {
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy    string     `json:"deleted_by,omitempty" gorm:"not null;default:''"`
+	DeleteReason string     `json:"delete_reason,omitempty" gorm:"not null;default:''"`
+}
+
+func (d Deletion) Deleted() bool {
+	return d.DeletedAt != nil
+}
+
+// DeleteResource is the body of a DELETE request. Who deleted the resource
+// is not up to the client: it is recorded from the audit actor of the
+// request, as in the audit log.
+type DeleteResource struct {
+	Reason string `json:"reason"`
+}
+
+func (del *DeleteResource) Validate() error {
+	var errs ValidationErrors
+	if del.Reason == "" {
+		errs.Add("reason", "reason is required")
+	}
+	return errs.OrNil()
+}
+
+// Writable returns the client-editable fields of fund, for validating and
+// patching it.
+func (fund Fund) Writable() CreateFund {
+	return CreateFund{
+		Name:          fund.Name,
+		VintageYear:   fund.VintageYear,
+		TargetSizeUsd: fund.TargetSizeUsd,
+		Status:        fund.Status,
+	}
+}
+
+// Writable returns the client-editable fields of investor, for validating
+// and patching it.
+func (investor Investor) Writable() CreateInvestor {
+	return CreateInvestor{
+		Name:         investor.Name,
+		InvestorType: investor.InvestorType,
+		Email:        investor.Email,
+	}
 }
 
 type Investment struct {
@@ -132,7 +194,41 @@ type Investment struct {
 	FundID         uuid.UUID       `json:"fund_id" gorm:"type:uuid;not null;index"`
 	AmountUsd      decimal.Decimal `json:"amount_usd" gorm:"type:numeric(20,2);not null;check:amount_usd_nonneg,amount_usd >= 0"`
 	CreatedAt      time.Time       `json:"created_at"`
-	InvestmentDate string       `json:"investment_date" gorm:"type:date;not null"`
+	InvestmentDate string       `json:"investment_date" gorm:"type:date;not null;serializer:date"`
 	Fund           Fund            `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
 	Investor       Investor        `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	Tenancy
+	Deletion
+}
+
+// Audit log actions.
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+)
+
+// AuditEntry records one change to a fund, investor or investment. Changes
+// maps every field that changed to its value before and after; Before is
+// absent on create.
+type AuditEntry struct {
+	ID        int                    `json:"id" gorm:"primaryKey;autoIncrement"`
+	Entity    string                 `json:"entity" gorm:"not null"`
+	EntityID  uuid.UUID              `json:"entity_id" gorm:"type:uuid;not null"`
+	Action    string                 `json:"action" gorm:"not null"`
+	Actor     string                 `json:"actor" gorm:"not null"`
+	RequestID string                 `json:"request_id,omitempty" gorm:"not null;default:''"`
+	Changes   map[string]FieldChange `json:"changes" gorm:"not null;serializer:json"`
+	CreatedAt time.Time              `json:"created_at"`
+	Tenancy
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
+
+type FieldChange struct {
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
 }
@@ -0,0 +1,393 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// Sortable is implemented by every model returned from a list endpoint so that
+// keyset cursors can be built from, and compared against, its sort columns.
+type Sortable interface {
+	SortValue(field string) any
+}
+
+// PageQuery holds the pagination and sorting parameters shared by all list
+// endpoints. Sort is a column name, optionally prefixed with '-' for
+// descending order; ties are always broken by id in the same direction.
+type PageQuery struct {
+	Limit  int    `query:"limit"`
+	Cursor string `query:"cursor"`
+	Sort   string `query:"sort"`
+}
+
+// Page is the response envelope of every list endpoint. NextCursor is empty
+// when there are no further results.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type cursor struct {
+	Sort  string          `json:"sort"`
+	After json.RawMessage `json:"after"`
+}
+
+// PageSize returns the requested limit, falling back to DefaultPageLimit.
+func (q PageQuery) PageSize() int {
+	if q.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	return min(q.Limit, MaxPageLimit)
+}
+
+// Order returns the column to sort by and whether the order is descending.
+func (q PageQuery) Order() (string, bool) {
+	sort := q.Sort
+	if sort == "" {
+		sort = "created_at"
+	}
+	if field, ok := strings.CutPrefix(sort, "-"); ok {
+		return field, true
+	}
+	return sort, false
+}
+
+func (q PageQuery) validate(errs *ValidationErrors, sortable []string) {
+	if q.Limit < 0 || q.Limit > MaxPageLimit {
+		errs.Add("limit", fmt.Sprintf("limit must be between 1 and %d, or 0 for the default of %d", MaxPageLimit, DefaultPageLimit))
+	}
+	field, _ := q.Order()
+	if !slices.Contains(sortable, field) {
+		errs.Add("sort", fmt.Sprintf("sort must be one of '%s', optionally prefixed with '-'", strings.Join(sortable, "', '")))
+	}
+	if q.Cursor != "" {
+		if c, err := decodeCursor(q.Cursor); err != nil {
+			errs.Add("cursor", err.Error())
+		} else if c.Sort != q.normalisedSort() {
+			errs.Add("cursor", "cursor was issued for a different sort order")
+		}
+	}
+}
+
+func (q PageQuery) normalisedSort() string {
+	field, desc := q.Order()
+	if desc {
+		return "-" + field
+	}
+	return field
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("cursor is invalid")
+	}
+	if err := json.Unmarshal(b, &c); err != nil || len(c.After) == 0 {
+		return c, errors.New("cursor is invalid")
+	}
+	return c, nil
+}
+
+// After decodes the cursor into the last row of the previous page, or returns
+// nil when the query has no cursor.
+func After[T any](q PageQuery) (*T, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	c, err := decodeCursor(q.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	var after T
+	if err := json.Unmarshal(c.After, &after); err != nil {
+		return nil, errors.New("cursor is invalid")
+	}
+	return &after, nil
+}
+
+// NewPage builds a page from rows fetched with a limit of PageSize()+1: the
+// extra row, if present, is dropped and signals that a next cursor is needed.
+func NewPage[T Sortable](rows []T, q PageQuery) (Page[T], error) {
+	limit := q.PageSize()
+	if len(rows) <= limit {
+		return Page[T]{Items: rows}, nil
+	}
+	rows = rows[:limit]
+	field, _ := q.Order()
+	last := rows[limit-1]
+	after, err := json.Marshal(map[string]any{field: last.SortValue(field), "id": last.SortValue("id")})
+	if err != nil {
+		return Page[T]{}, err
+	}
+	b, err := json.Marshal(cursor{Sort: q.normalisedSort(), After: after})
+	if err != nil {
+		return Page[T]{}, err
+	}
+	return Page[T]{Items: rows, NextCursor: base64.RawURLEncoding.EncodeToString(b)}, nil
+}
+
+var (
+	FundSortFields       = []string{"created_at", "name", "vintage_year", "target_size_usd"}
+	InvestorSortFields   = []string{"created_at", "name", "email"}
+	InvestmentSortFields = []string{"created_at", "investment_date", "amount_usd"}
+	AuditSortFields      = []string{"created_at"}
+	WebhookSortFields    = []string{"created_at", "url"}
+	DeliverySortFields   = []string{"created_at"}
+	APIKeySortFields     = []string{"created_at", "name"}
+)
+
+type FundQuery struct {
+	PageQuery
+	Status         string `query:"status"`
+	VintageYearMin *int   `query:"vintage_year_min"`
+	VintageYearMax *int   `query:"vintage_year_max"`
+	IncludeDeleted bool   `query:"include_deleted"`
+}
+
+func (query *FundQuery) Validate() error {
+	var errs ValidationErrors
+	query.validate(&errs, FundSortFields)
+	if query.Status != "" && query.Status != "Fundraising" && query.Status != "Investing" && query.Status != "Closed" {
+		errs.Add("status", "status must be either 'Fundraising', 'Investing', or 'Closed'")
+	}
+	if query.VintageYearMin != nil && query.VintageYearMax != nil && *query.VintageYearMin > *query.VintageYearMax {
+		errs.Add("vintage_year_min", "vintage_year_min must be less than or equal to vintage_year_max")
+	}
+	return errs.OrNil()
+}
+
+type InvestorQuery struct {
+	PageQuery
+	InvestorType   string `query:"investor_type"`
+	IncludeDeleted bool   `query:"include_deleted"`
+}
+
+func (query *InvestorQuery) Validate() error {
+	var errs ValidationErrors
+	query.validate(&errs, InvestorSortFields)
+	if query.InvestorType != "" &&
+		query.InvestorType != "Individual" &&
+		query.InvestorType != "Institution" &&
+		query.InvestorType != "Family Office" {
+		errs.Add("investor_type", "investor_type must be either 'Individual', 'Institution' or 'Family Office'")
+	}
+	return errs.OrNil()
+}
+
+type InvestmentQuery struct {
+	PageQuery
+	InvestorID         *uuid.UUID       `query:"investor_id"`
+	InvestmentDateFrom string           `query:"investment_date_from"`
+	InvestmentDateTo   string           `query:"investment_date_to"`
+	AmountUsdMin       *decimal.Decimal `query:"amount_usd_min"`
+	AmountUsdMax       *decimal.Decimal `query:"amount_usd_max"`
+	IncludeDeleted     bool             `query:"include_deleted"`
+}
+
+func (query *InvestmentQuery) Validate() error {
+	var errs ValidationErrors
+	query.validate(&errs, InvestmentSortFields)
+	from, fromErr := time.Parse("2006-01-02", query.InvestmentDateFrom)
+	if query.InvestmentDateFrom != "" && fromErr != nil {
+		errs.Add("investment_date_from", "investment_date_from is invalid, use 'yyyy-mm-dd'")
+	}
+	to, toErr := time.Parse("2006-01-02", query.InvestmentDateTo)
+	if query.InvestmentDateTo != "" && toErr != nil {
+		errs.Add("investment_date_to", "investment_date_to is invalid, use 'yyyy-mm-dd'")
+	}
+	if fromErr == nil && toErr == nil && from.After(to) {
+		errs.Add("investment_date_from", "investment_date_from must be on or before investment_date_to")
+	}
+	if query.AmountUsdMin != nil && query.AmountUsdMax != nil && query.AmountUsdMin.GreaterThan(*query.AmountUsdMax) {
+		errs.Add("amount_usd_min", "amount_usd_min must be less than or equal to amount_usd_max")
+	}
+	return errs.OrNil()
+}
+
+var AuditEntities = []string{"fund", "investor", "investment"}
+
+// AuditQuery filters the audit log. Entries written at the same time are
+// ordered by id, which follows the order they were written in.
+type AuditQuery struct {
+	PageQuery
+	Entity   string     `query:"entity"`
+	EntityID *uuid.UUID `query:"id"`
+}
+
+func (query *AuditQuery) Validate() error {
+	var errs ValidationErrors
+	query.validate(&errs, AuditSortFields)
+	if query.Entity != "" && !slices.Contains(AuditEntities, query.Entity) {
+		errs.Add("entity", fmt.Sprintf("entity must be one of '%s'", strings.Join(AuditEntities, "', '")))
+	}
+	return errs.OrNil()
+}
+
+func (f Fund) SortValue(field string) any {
+	switch field {
+	case "id":
+		return f.ID
+	case "name":
+		return f.Name
+	case "vintage_year":
+		return f.VintageYear
+	case "target_size_usd":
+		return f.TargetSizeUsd
+	default:
+		return f.CreatedAt
+	}
+}
+
+func (i Investor) SortValue(field string) any {
+	switch field {
+	case "id":
+		return i.ID
+	case "name":
+		return i.Name
+	case "email":
+		return i.Email
+	default:
+		return i.CreatedAt
+	}
+}
+
+func (i Investment) SortValue(field string) any {
+	switch field {
+	case "id":
+		return i.ID
+	case "investment_date":
+		return i.InvestmentDate
+	case "amount_usd":
+		return i.AmountUsd
+	default:
+		return i.CreatedAt
+	}
+}
+
+func (e AuditEntry) SortValue(field string) any {
+	switch field {
+	case "id":
+		return e.ID
+	default:
+		return e.CreatedAt
+	}
+}
+
+type WebhookQuery struct {
+	PageQuery
+	Enabled *bool `query:"enabled"`
+}
+
+func (query *WebhookQuery) Validate() error {
+	var errs ValidationErrors
+	query.validate(&errs, WebhookSortFields)
+	return errs.OrNil()
+}
+
+type WebhookDeliveryQuery struct {
+	PageQuery
+	Status string `query:"status"`
+}
+
+func (query *WebhookDeliveryQuery) Validate() error {
+	var errs ValidationErrors
+	query.validate(&errs, DeliverySortFields)
+	if query.Status != "" && !slices.Contains(DeliveryStatuses, query.Status) {
+		errs.Add("status", fmt.Sprintf("status must be one of '%s'", strings.Join(DeliveryStatuses, "', '")))
+	}
+	return errs.OrNil()
+}
+
+type APIKeyQuery struct {
+	PageQuery
+	IncludeRevoked bool `query:"include_revoked"`
+}
+
+func (query *APIKeyQuery) Validate() error {
+	var errs ValidationErrors
+	query.validate(&errs, APIKeySortFields)
+	return errs.OrNil()
+}
+
+func (w Webhook) SortValue(field string) any {
+	switch field {
+	case "id":
+		return w.ID
+	case "url":
+		return w.URL
+	default:
+		return w.CreatedAt
+	}
+}
+
+func (key APIKey) SortValue(field string) any {
+	switch field {
+	case "id":
+		return key.ID
+	case "name":
+		return key.Name
+	default:
+		return key.CreatedAt
+	}
+}
+
+func (d WebhookDelivery) SortValue(field string) any {
+	switch field {
+	case "id":
+		return d.ID
+	default:
+		return d.CreatedAt
+	}
+}
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+type SearchQuery struct {
+	Q     string `query:"q"`
+	Limit int    `query:"limit"`
+}
+
+func (query *SearchQuery) Validate() error {
+	var errs ValidationErrors
+	if len([]rune(strings.TrimSpace(query.Q))) < 2 {
+		errs.Add("q", "q must be at least 2 characters")
+	}
+	if query.Limit < 0 || query.Limit > MaxSearchLimit {
+		errs.Add("limit", fmt.Sprintf("limit must be between 1 and %d", MaxSearchLimit))
+	}
+	return errs.OrNil()
+}
+
+// PageSize returns the requested limit, falling back to DefaultSearchLimit.
+func (query SearchQuery) PageSize() int {
+	if query.Limit <= 0 {
+		return DefaultSearchLimit
+	}
+	return min(query.Limit, MaxSearchLimit)
+}
+
+// SearchResult is a single ranked match. Exactly one of Fund and Investor is
+// set, according to Type.
+type SearchResult struct {
+	Type     string    `json:"type"`
+	Score    float64   `json:"score"`
+	Fund     *Fund     `json:"fund,omitempty"`
+	Investor *Investor `json:"investor,omitempty"`
+}
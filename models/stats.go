@@ -0,0 +1,23 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// FundStats summarises a tenant's live funds of one status: how many there
+// are, and the capital committed to them by their live investments.
+type FundStats struct {
+	TenantID     string
+	Status       string
+	Funds        int
+	CommittedUsd decimal.Decimal
+}
+
+// FundCommitment is the capital committed to a live fund by its live
+// investments.
+type FundCommitment struct {
+	TenantID     string
+	FundID       uuid.UUID
+	CommittedUsd decimal.Decimal
+}
@@ -0,0 +1,34 @@
+package models
+
+import "strings"
+
+// FieldError describes why a single request field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors collects every field error found while validating a
+// request, rather than stopping at the first.
+type ValidationErrors []FieldError
+
+func (errs *ValidationErrors) Add(field, message string) {
+	*errs = append(*errs, FieldError{Field: field, Message: message})
+}
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// OrNil returns errs as an error, or nil when nothing was collected, so that
+// Validate methods never return a non-nil error wrapping an empty slice.
+func (errs ValidationErrors) OrNil() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
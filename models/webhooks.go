@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventTypes lists the event types a webhook can subscribe to: every
+// type in package events that a write emits.
+var WebhookEventTypes = []string{
+	"FundCreated", "FundUpdated", "FundDeleted", "FundRestored",
+	"InvestorRegistered", "InvestorUpdated", "InvestorDeleted", "InvestorRestored",
+	"CommitmentMade", "CommitmentWithdrawn", "CommitmentRestored",
+}
+
+// MinWebhookSecretLength is the shortest secret a client may choose.
+const MinWebhookSecretLength = 16
+
+type CreateWebhook struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// Secret signs deliveries. If empty, one is generated.
+	Secret string `json:"secret"`
+}
+
+func (webhook *CreateWebhook) Validate() error {
+	var errs ValidationErrors
+	if webhook.URL == "" {
+		errs.Add("url", "url is required")
+	} else if u, err := url.Parse(webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.Add("url", "url must be an absolute http or https URL")
+	}
+	for _, t := range webhook.EventTypes {
+		if !slices.Contains(WebhookEventTypes, t) {
+			errs.Add("event_types", fmt.Sprintf("event_types must only contain '%s'", strings.Join(WebhookEventTypes, "', '")))
+			break
+		}
+	}
+	if webhook.Secret != "" && len(webhook.Secret) < MinWebhookSecretLength {
+		errs.Add("secret", fmt.Sprintf("secret must be at least %d characters", MinWebhookSecretLength))
+	}
+	return errs.OrNil()
+}
+
+// Webhook subscribes URL to events of the listed types, or of every type
+// when EventTypes is empty. A webhook whose deliveries keep failing is
+// disabled, with DisabledReason saying why, until a client enables it again.
+type Webhook struct {
+	ID                  uuid.UUID `json:"id" gorm:"primaryKey;type:uuid"`
+	URL                 string    `json:"url" gorm:"not null"`
+	EventTypes          []string  `json:"event_types" gorm:"not null;serializer:json"`
+	Secret              string    `json:"-" gorm:"not null"`
+	Enabled             bool      `json:"enabled" gorm:"not null"`
+	ConsecutiveFailures int       `json:"consecutive_failures" gorm:"not null"`
+	DisabledReason      string    `json:"disabled_reason,omitempty" gorm:"not null"`
+	CreatedAt           time.Time `json:"created_at"`
+	Version             int       `json:"version" gorm:"not null"`
+	Tenancy
+}
+
+// Subscribes reports whether events of type t are delivered to the webhook.
+func (webhook Webhook) Subscribes(t string) bool {
+	return len(webhook.EventTypes) == 0 || slices.Contains(webhook.EventTypes, t)
+}
+
+// Writable returns the client-editable fields of webhook, for validating
+// it. The secret cannot be changed.
+func (webhook Webhook) Writable() CreateWebhook {
+	return CreateWebhook{URL: webhook.URL, EventTypes: webhook.EventTypes}
+}
+
+// Webhook delivery statuses. A pending delivery is retried until it
+// succeeds or runs out of attempts and fails.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+var DeliveryStatuses = []string{DeliveryPending, DeliverySucceeded, DeliveryFailed}
+
+// WebhookDelivery is one event sent, or to be sent, to one webhook. Payload
+// is the event, as published by the outbox. A replay is a new delivery of
+// the same event, with ReplayOf naming the delivery it repeats.
+type WebhookDelivery struct {
+	ID             int             `json:"id" gorm:"primaryKey;autoIncrement"`
+	WebhookID      uuid.UUID       `json:"webhook_id" gorm:"type:uuid;not null"`
+	EventSeq       int64           `json:"event_seq" gorm:"not null"`
+	EventType      string          `json:"event_type" gorm:"not null"`
+	Payload        json.RawMessage `json:"payload" gorm:"not null;serializer:json"`
+	Status         string          `json:"status" gorm:"not null"`
+	Attempts       int             `json:"attempts" gorm:"not null"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty" gorm:"not null"`
+	LastError      string          `json:"last_error,omitempty" gorm:"not null"`
+	ReplayOf       *int            `json:"replay_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
+
+// DueDelivery is a delivery claimed for sending, with the endpoint to send
+// it to.
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+// WebhookAttempt is the outcome of one attempt to send a delivery. An empty
+// Error means it succeeded. After a failure, the delivery is retried at
+// RetryAt or, if that is nil, fails for good; and the webhook is disabled
+// once DisableAfter attempts in a row have failed, unless that is zero.
+type WebhookAttempt struct {
+	DeliveryID   int
+	StatusCode   int
+	Error        string
+	RetryAt      *time.Time
+	DisableAfter int
+}
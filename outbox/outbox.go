@@ -0,0 +1,50 @@
+// Package outbox publishes events to downstream systems. Every write queues
+// its event in the outbox table in the same transaction (see migration
+// 0008_outbox), and a Relay publishes the queue afterwards, so that a
+// message is sent if and only if its change was committed. Delivery is at
+// least once: a message may be published again if the relay stops between
+// publishing it and recording that it did, so consumers should discard
+// events whose seq they have already seen.
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database/events"
+)
+
+// Message is an event queued for publishing. Topic is "funds", "investors"
+// or "investments", and Key is the entity's ID, so that partitioned brokers
+// keep each entity's events in order.
+type Message struct {
+	ID            int64           `json:"id" gorm:"primaryKey;autoIncrement"`
+	Topic         string          `json:"topic" gorm:"not null"`
+	Key           string          `json:"key" gorm:"column:partition_key;not null"`
+	Payload       json.RawMessage `json:"payload" gorm:"not null;serializer:json"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Attempts      int             `json:"-" gorm:"not null;default:0"`
+	NextAttemptAt time.Time       `json:"-" gorm:"not null"`
+	PublishedAt   *time.Time      `json:"-"`
+	LastError     string          `json:"-" gorm:"not null;default:''"`
+}
+
+func (Message) TableName() string {
+	return "outbox"
+}
+
+// NewMessage returns the message announcing ev, due at once. The payload is
+// the event itself.
+func NewMessage(ev events.Event) (*Message, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	return &Message{
+		Topic:         ev.Stream + "s",
+		Key:           ev.StreamID.String(),
+		Payload:       payload,
+		CreatedAt:     ev.OccurredAt,
+		NextAttemptAt: ev.OccurredAt,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type natsConn struct {
+	subjects []string
+}
+
+func (c *natsConn) Publish(subject string, _ []byte) error {
+	c.subjects = append(c.subjects, subject)
+	return nil
+}
+
+func (c *natsConn) FlushWithContext(context.Context) error {
+	return nil
+}
+
+type kafkaProducer struct {
+	topic, key string
+}
+
+func (p *kafkaProducer) Produce(_ context.Context, topic string, key, _ []byte) error {
+	p.topic, p.key = topic, string(key)
+	return nil
+}
+
+func TestPublishers(t *testing.T) {
+	ctx := context.Background()
+	m := Message{ID: 7, Topic: "funds", Key: "42", Payload: json.RawMessage(`{"seq":1}`)}
+
+	var buf bytes.Buffer
+	assert.NoError(t, (&WriterPublisher{W: &buf}).Publish(ctx, m))
+	assert.JSONEq(t, `{"id":7,"topic":"funds","key":"42","payload":{"seq":1},"created_at":"0001-01-01T00:00:00Z"}`, buf.String())
+	assert.True(t, bytes.HasSuffix(buf.Bytes(), []byte("\n")))
+
+	conn := &natsConn{}
+	assert.NoError(t, NATSPublisher{Conn: conn, Prefix: "titanbay."}.Publish(ctx, m))
+	assert.Equal(t, []string{"titanbay.funds"}, conn.subjects)
+
+	producer := &kafkaProducer{}
+	assert.NoError(t, KafkaPublisher{Producer: producer}.Publish(ctx, m))
+	assert.Equal(t, "funds", producer.topic)
+	assert.Equal(t, "42", producer.key)
+}
+
+func TestRelay_BackoffDoublesUpToMax(t *testing.T) {
+	r := &Relay{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	var got []time.Duration
+	for attempts := 1; attempts <= 5; attempts++ {
+		got = append(got, r.backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, got)
+}
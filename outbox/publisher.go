@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"slices"
+	"sync"
+)
+
+// Publisher delivers messages to a broker. Publish returns once the broker
+// has accepted the message; an error means it will be retried.
+type Publisher interface {
+	Publish(context.Context, Message) error
+}
+
+// WriterPublisher writes each message to W as a line of JSON. Use it with
+// os.Stdout to pipe events into another process, or to watch them locally.
+type WriterPublisher struct {
+	mu sync.Mutex
+	W  io.Writer
+}
+
+func (p *WriterPublisher) Publish(_ context.Context, m Message) error {
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, err = p.W.Write(append(line, '\n'))
+	return err
+}
+
+// NATSConn is the part of a NATS connection that NATSPublisher uses.
+// *nats.Conn from github.com/nats-io/nats.go satisfies it.
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+	FlushWithContext(context.Context) error
+}
+
+// NATSPublisher publishes each message's payload to the subject Prefix +
+// topic, e.g. "titanbay.funds", and waits for the server to receive it. The
+// context must have a deadline, as the Relay's always do.
+type NATSPublisher struct {
+	Conn   NATSConn
+	Prefix string
+}
+
+func (p NATSPublisher) Publish(ctx context.Context, m Message) error {
+	if err := p.Conn.Publish(p.Prefix+m.Topic, m.Payload); err != nil {
+		return err
+	}
+	return p.Conn.FlushWithContext(ctx)
+}
+
+// KafkaProducer is the part of a Kafka client that KafkaPublisher uses. It
+// must wait for the broker to acknowledge the record. With
+// github.com/segmentio/kafka-go, for example:
+//
+//	type producer struct{ w *kafka.Writer }
+//
+//	func (p producer) Produce(ctx context.Context, topic string, key, value []byte) error {
+//		return p.w.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: value})
+//	}
+type KafkaProducer interface {
+	Produce(ctx context.Context, topic string, key, value []byte) error
+}
+
+// KafkaPublisher produces each message's payload to the topic Prefix +
+// topic, keyed by entity ID.
+type KafkaPublisher struct {
+	Producer KafkaProducer
+	Prefix   string
+}
+
+func (p KafkaPublisher) Publish(ctx context.Context, m Message) error {
+	return p.Producer.Produce(ctx, p.Prefix+m.Topic, []byte(m.Key), m.Payload)
+}
+
+// MemoryPublisher keeps published messages in memory, for tests. If Fail is
+// set, messages for which it returns an error are not kept.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+	Fail     func(Message) error
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, m Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Fail != nil {
+		if err := p.Fail(m); err != nil {
+			return err
+		}
+	}
+	p.messages = append(p.messages, m)
+	return nil
+}
+
+// Messages returns the messages published so far, in order.
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.messages)
+}
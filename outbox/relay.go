@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Relay publishes queued messages in the order they were queued. A message
+// that fails is retried after a backoff that doubles with every attempt,
+// from MinBackoff up to MaxBackoff, while later messages carry on, so a
+// failure can reorder an entity's events. Several relays may share an
+// outbox: each claims a batch for Lease, in a short transaction that locks
+// it, and the others skip it. Messages are published after that
+// transaction commits, so a slow broker holds no locks, and a batch left
+// unfinished, e.g. by a relay that stopped, is retried once its lease ends.
+type Relay struct {
+	db        *gorm.DB
+	publisher Publisher
+
+	BatchSize  int
+	Interval   time.Duration
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Lease is how long a claimed batch is left to this relay. Messages not
+	// attempted by then are left for the next claim.
+	Lease time.Duration
+	// PublishTimeout bounds each call to the publisher.
+	PublishTimeout time.Duration
+	// Retention is how long published messages are kept before being
+	// deleted.
+	Retention time.Duration
+}
+
+// NewRelay returns a Relay that publishes the outbox in db to p.
+func NewRelay(db *gorm.DB, p Publisher) *Relay {
+	return &Relay{
+		db:             db,
+		publisher:      p,
+		BatchSize:      100,
+		Interval:       time.Second,
+		MinBackoff:     time.Second,
+		MaxBackoff:     10 * time.Minute,
+		Lease:          time.Minute,
+		PublishTimeout: 10 * time.Second,
+		Retention:      24 * time.Hour,
+	}
+}
+
+// Run publishes messages until ctx is done, polling every Interval, or
+// again at once after a full batch. Errors are logged and retried.
+func (r *Relay) Run(ctx context.Context) {
+	for {
+		n, err := r.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Outbox relay failed", "error", err)
+		}
+		wait := r.Interval
+		if err == nil && n == r.BatchSize {
+			wait = 0
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RunOnce claims one batch of due messages and attempts to publish them,
+// and deletes published messages older than Retention. It returns the
+// number of messages claimed.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	batch, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	leaseEnd := time.Now().Add(r.Lease)
+	// The outcome is recorded even if ctx ends mid-batch, so that a
+	// published message is not sent again.
+	recordCtx := context.WithoutCancel(ctx)
+	for _, m := range batch {
+		if ctx.Err() != nil || time.Now().After(leaseEnd) {
+			break
+		}
+		if err := r.record(recordCtx, m, r.publish(ctx, m)); err != nil {
+			return len(batch), err
+		}
+	}
+	return len(batch), nil
+}
+
+// claim locks the due messages, counts an attempt at each and defers them
+// for Lease, so that no other relay claims them meanwhile.
+func (r *Relay) claim(ctx context.Context) ([]Message, error) {
+	var batch []Message
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND next_attempt_at <= ?", now).
+			Order("id").Limit(r.BatchSize).Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		if len(batch) > 0 {
+			ids := make([]int64, len(batch))
+			for i := range batch {
+				batch[i].Attempts++
+				ids[i] = batch[i].ID
+			}
+			err := tx.Model(&Message{}).Where("id IN ?", ids).Updates(map[string]any{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": now.Add(r.Lease),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Where("published_at < ?", now.Add(-r.Retention)).Delete(&Message{}).Error
+	})
+	return batch, err
+}
+
+// publish sends m, giving up after PublishTimeout.
+func (r *Relay) publish(ctx context.Context, m Message) error {
+	ctx, cancel := context.WithTimeout(ctx, r.PublishTimeout)
+	defer cancel()
+	return r.publisher.Publish(ctx, m)
+}
+
+// record stores the outcome of publishing m: published, or due again after
+// a backoff if publishErr is set.
+func (r *Relay) record(ctx context.Context, m Message, publishErr error) error {
+	db := r.db.WithContext(ctx)
+	now := db.NowFunc()
+	updates := map[string]any{"published_at": now, "last_error": ""}
+	if publishErr != nil {
+		updates = map[string]any{
+			"next_attempt_at": now.Add(r.backoff(m.Attempts)),
+			"last_error":      publishErr.Error(),
+		}
+	}
+	return db.Model(&Message{}).Where("id = ?", m.ID).Updates(updates).Error
+}
+
+// backoff returns the delay before the attempt after the given one.
+func (r *Relay) backoff(attempts int) time.Duration {
+	d := r.MinBackoff
+	for i := 1; i < attempts && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.MaxBackoff)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/ratelimit"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultRateLimit        = "600/1m"
+	defaultAddressRateLimit = "1200/1m"
+)
+
+// rateLimitPolicy reads the default limit from RATE_LIMIT, such as
+// "600/1m", per-route limits from RATE_LIMIT_ROUTES, such as
+// "GET /search=30/1m,GET /funds/:fund_id/investments=60/1m", and the limit
+// per IP address from RATE_LIMIT_ADDRESS.
+func rateLimitPolicy() (ratelimit.Policy, error) {
+	limit, err := ratelimit.ParseLimit(cmp.Or(os.Getenv("RATE_LIMIT"), defaultRateLimit))
+	if err != nil {
+		return ratelimit.Policy{}, fmt.Errorf("RATE_LIMIT: %w", err)
+	}
+	routes, err := ratelimit.ParseRoutes(os.Getenv("RATE_LIMIT_ROUTES"))
+	if err != nil {
+		return ratelimit.Policy{}, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err)
+	}
+	address, err := ratelimit.ParseLimit(cmp.Or(os.Getenv("RATE_LIMIT_ADDRESS"), defaultAddressRateLimit))
+	if err != nil {
+		return ratelimit.Policy{}, fmt.Errorf("RATE_LIMIT_ADDRESS: %w", err)
+	}
+	return ratelimit.Policy{Default: limit, Routes: routes, Address: address}, nil
+}
+
+// checkRateLimitRoutes fails if policy limits a route e does not have, which
+// is most likely a typo that would otherwise leave the route unlimited.
+func checkRateLimitRoutes(e *echo.Echo, policy ratelimit.Policy) error {
+	routes := make(map[string]bool)
+	for _, r := range e.Routes() {
+		routes[r.Method+" "+r.Path] = true
+	}
+	for route := range policy.Routes {
+		if !routes[route] {
+			return fmt.Errorf("RATE_LIMIT_ROUTES: no route %q", route)
+		}
+	}
+	return nil
+}
+
+// rateLimitStore builds the store chosen by RATE_LIMIT_STORE:
+//
+//	memory    buckets in memory, per replica (the default)
+//	database  buckets in db, shared by every replica
+//	none      no rate limiting
+//
+// It returns nil for none.
+func rateLimitStore(db database.Db) (ratelimit.Store, error) {
+	switch name := os.Getenv("RATE_LIMIT_STORE"); name {
+	case "", "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "database":
+		store := database.NewRateLimitStore(db)
+		if store == nil {
+			return nil, fmt.Errorf("%T cannot store rate limits", db)
+		}
+		go purgeRateLimits(store, time.Hour)
+		return store, nil
+	case "none":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q, want memory, database or none", name)
+	}
+}
+
+// purgeRateLimits deletes refilled buckets every interval. They would be
+// reset on their next use anyway; this only reclaims their space.
+func purgeRateLimits(store *ratelimit.SQLStore, interval time.Duration) {
+	for range time.Tick(interval) {
+		if _, err := store.Purge(context.Background()); err != nil {
+			slog.Error("Failed to purge rate limits", "error", err)
+		}
+	}
+}
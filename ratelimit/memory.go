@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often MemoryStore forgets buckets that have refilled.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in memory. Each replica has its own, so a
+// client spread over n replicas gets up to n times its limit.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	// full is when the bucket will have refilled, after which it is the same
+	// as no bucket at all.
+	full time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]memoryBucket), now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok || !now.Before(b.full) {
+		b.bucket = bucket{Tokens: float64(limit.Requests), Updated: now}
+	}
+	var res Result
+	b.bucket, res = take(b.bucket, limit, now)
+	b.full = now.Add(res.Reset)
+	s.buckets[key] = b
+	return res, nil
+}
+
+// sweep forgets full buckets, at most every sweepInterval, so that clients
+// that have gone away do not use memory forever.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Package ratelimit limits how often each client may call the API. Every
+// client has a token bucket per limit: each request takes a token, and
+// tokens are put back at a steady rate, up to the bucket's capacity, so a
+// client may burst up to the capacity and then keep to the rate. Buckets
+// live in a Store, in memory for a single replica, or in the database so
+// that replicas share them.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests every Per, in bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses a limit written as "<requests>/<duration>", such as
+// "100/1m".
+func ParseLimit(s string) (Limit, error) {
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q must look like 100/1m", s)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must allow a positive number of requests", s)
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must be per a positive duration", s)
+	}
+	return Limit{Requests: n, Per: d}, nil
+}
+
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// interval is how long it takes to put back one token.
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed bool
+	Limit   Limit
+	// Remaining is the number of tokens left in the bucket.
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token, if none were left.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets. Take takes a token from the bucket named key,
+// which is full when first used.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket is a token bucket, as of Updated.
+type bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// take refills b for the time since it was last updated, then takes a
+// token from it if one is left. Both stores use it, so they agree.
+func take(b bucket, limit Limit, now time.Time) (bucket, Result) {
+	capacity := float64(limit.Requests)
+	if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = min(capacity, b.Tokens+float64(elapsed)/float64(limit.interval()))
+	}
+	b.Updated = now
+	res := Result{Limit: limit}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = tokensTime(1-b.Tokens, limit)
+	}
+	res.Remaining = int(math.Floor(b.Tokens))
+	res.Reset = tokensTime(capacity-b.Tokens, limit)
+	return b, res
+}
+
+// tokensTime is how long it takes to put back n tokens.
+func tokensTime(n float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil(n * float64(limit.interval())))
+}
+
+// Policy sets the limits per route. Routes, keyed by method and path
+// pattern such as "GET /funds/:fund_id/investments", each have a bucket of
+// their own; every other route shares a bucket limited by Default. Address
+// limits every request from one IP address, whoever makes it, including
+// requests that fail authentication.
+type Policy struct {
+	Default Limit
+	Routes  map[string]Limit
+	Address Limit
+}
+
+// For returns the name of route's bucket and its limit.
+func (p Policy) For(route string) (string, Limit) {
+	if limit, ok := p.Routes[route]; ok {
+		return route, limit
+	}
+	return "*", p.Default
+}
+
+// ParseRoutes parses per-route limits written as a comma-separated list of
+// "<method> <path>=<limit>", such as "GET /search=30/1m".
+func ParseRoutes(s string) (map[string]Limit, error) {
+	routes := make(map[string]Limit)
+	for _, rule := range strings.Split(s, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		route, value, ok := strings.Cut(rule, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPath || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("route limit %q must look like GET /search=30/1m", rule)
+		}
+		limit, err := ParseLimit(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		routes[strings.ToUpper(method)+" "+path] = limit
+	}
+	return routes, nil
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("100/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 100, Per: time.Minute}, limit)
+	for _, s := range []string{"", "100", "0/1m", "-1/1m", "100/0s", "100/soon"} {
+		_, err := ParseLimit(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes("GET /search=30/1m, get /funds/:fund_id/investments=60/1m,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"GET /search":                     {Requests: 30, Per: time.Minute},
+		"GET /funds/:fund_id/investments": {Requests: 60, Per: time.Minute},
+	}, routes)
+	_, err = ParseRoutes("/search=30/1m")
+	assert.Error(t, err)
+
+	policy := Policy{Default: Limit{Requests: 600, Per: time.Minute}, Routes: routes}
+	bucket, limit := policy.For("GET /search")
+	assert.Equal(t, "GET /search", bucket)
+	assert.Equal(t, 30, limit.Requests)
+	bucket, limit = policy.For("GET /funds")
+	assert.Equal(t, "*", bucket)
+	assert.Equal(t, 600, limit.Requests)
+}
+
+func TestMemoryStore_RefillsAtRate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		res, err := s.Take(ctx, "alice", limit)
+		assert.NoError(t, err)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, i, res.Remaining)
+	}
+	res, err := s.Take(ctx, "alice", limit)
+	assert.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+	assert.Equal(t, 3*time.Second, res.Reset)
+
+	res, err = s.Take(ctx, "bob", limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed, "each key has its own bucket")
+
+	now = now.Add(1500 * time.Millisecond)
+	res, err = s.Take(ctx, "alice", limit)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+
+	now = now.Add(time.Hour)
+	res, err = s.Take(ctx, "alice", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, res.Remaining, "buckets refill no further than their capacity")
+	assert.Len(t, s.buckets, 1, "refilled buckets are forgotten")
+}
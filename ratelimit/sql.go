@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sqlBucket is a bucket in the rate_limits table (see migration
+// 0013_rate_limits). FullAt is when it will have refilled; rows past it can
+// be deleted.
+type sqlBucket struct {
+	Key       string    `gorm:"column:bucket_key;primaryKey"`
+	Tokens    float64   `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime:false"`
+	FullAt    time.Time `gorm:"not null"`
+}
+
+func (sqlBucket) TableName() string {
+	return "rate_limits"
+}
+
+// SQLStore keeps buckets in a database table, so that every replica sharing
+// the database shares the buckets. Each Take is a short transaction that
+// locks the client's row.
+type SQLStore struct {
+	db *gorm.DB
+}
+
+// NewSQLStore returns a Store that keeps buckets in db.
+func NewSQLStore(db *gorm.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var res Result
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		row := sqlBucket{Key: key, Tokens: float64(limit.Requests), UpdatedAt: now, FullAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&row, "bucket_key = ?", key).Error; err != nil {
+			return err
+		}
+		b := bucket{Tokens: row.Tokens, Updated: row.UpdatedAt}
+		if !now.Before(row.FullAt) {
+			b = bucket{Tokens: float64(limit.Requests), Updated: now}
+		}
+		b, res = take(b, limit, now)
+		return tx.Model(&row).Updates(map[string]any{
+			"tokens":     b.Tokens,
+			"updated_at": b.Updated,
+			"full_at":    now.Add(res.Reset),
+		}).Error
+	})
+	return res, err
+}
+
+// Purge deletes buckets that have refilled, which are the same as no bucket
+// at all, and returns how many it deleted.
+func (s *SQLStore) Purge(ctx context.Context) (int, error) {
+	res := s.db.WithContext(ctx).Where("full_at <= ?", s.db.NowFunc()).Delete(&sqlBucket{})
+	return int(res.RowsAffected), res.Error
+}
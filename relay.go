@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/outbox"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// defaultOutboxTopicPrefix is prefixed to the NATS subjects and Kafka
+// topics events are published to unless OUTBOX_TOPIC_PREFIX says
+// otherwise.
+const defaultOutboxTopicPrefix = "titanbay."
+
+// startOutboxRelay starts publishing db's outbox to the publisher named by
+// OUTBOX_PUBLISHER. Without one, events stay queued until a relay runs.
+func startOutboxRelay(db database.Db) error {
+	name := os.Getenv("OUTBOX_PUBLISHER")
+	if name == "" || name == "none" {
+		return nil
+	}
+	p, err := outboxPublisher(name)
+	if err != nil {
+		return err
+	}
+	relay := database.NewOutboxRelay(db, p)
+	if relay == nil {
+		return fmt.Errorf("%T has no outbox to publish", db)
+	}
+	go relay.Run(context.Background())
+	slog.Info("Publishing outbox", "publisher", name)
+	return nil
+}
+
+// outboxPublisher returns the publisher called name, connecting to its
+// broker as configured by the OUTBOX_* environment variables.
+func outboxPublisher(name string) (outbox.Publisher, error) {
+	prefix, ok := os.LookupEnv("OUTBOX_TOPIC_PREFIX")
+	if !ok {
+		prefix = defaultOutboxTopicPrefix
+	}
+	switch name {
+	case "stdout":
+		return &outbox.WriterPublisher{W: os.Stdout}, nil
+	case "nats":
+		url := os.Getenv("OUTBOX_NATS_URL")
+		if url == "" {
+			url = nats.DefaultURL
+		}
+		conn, err := nats.Connect(url, nats.Name("titanbay outbox"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+		}
+		return outbox.NATSPublisher{Conn: conn, Prefix: prefix}, nil
+	case "kafka":
+		brokers := splitList(os.Getenv("OUTBOX_KAFKA_BROKERS"))
+		if len(brokers) == 0 {
+			return nil, errors.New("OUTBOX_KAFKA_BROKERS must list the Kafka brokers, e.g. kafka:9092")
+		}
+		w := &kafka.Writer{
+			Addr: kafka.TCP(brokers...),
+			// Records with the same key, an entity's events, go to the same
+			// partition, so that they stay in order.
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+		}
+		return outbox.KafkaPublisher{Producer: kafkaProducer{w}, Prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("unknown OUTBOX_PUBLISHER %q, want stdout, nats, kafka or none", name)
+}
+
+// kafkaProducer produces records with a kafka.Writer, waiting for the
+// brokers to acknowledge them.
+type kafkaProducer struct {
+	w *kafka.Writer
+}
+
+func (p kafkaProducer) Produce(ctx context.Context, topic string, key, value []byte) error {
+	return p.w.WriteMessages(ctx, kafka.Message{Topic: topic, Key: key, Value: value})
+}
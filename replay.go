@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+)
+
+var errReplayUsage = errors.New("usage: replay")
+
+// runReplay rebuilds the funds, investors and investments tables, and their
+// histories, from the event store. It runs in one transaction, so readers
+// see either the old tables or the rebuilt ones.
+func runReplay(args []string) error {
+	if len(args) != 0 {
+		return errReplayUsage
+	}
+	db, err := database.ConnectToDB()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	n, err := db.RebuildProjections(context.Background())
+	if err != nil {
+		return err
+	}
+	slog.Info("Rebuilt projections", "events", n)
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/handlers"
+	"github.com/labstack/echo/v4"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// shutdownTimeout bounds how long in-flight requests are given to finish
+// once the server stops taking new ones.
+const shutdownTimeout = 30 * time.Second
+
+// shutdownDelay reads SHUTDOWN_DELAY, a Go duration such as "5s": how long
+// the server keeps serving, reporting not ready, after it is told to stop,
+// so that load balancers notice before connections are refused.
+func shutdownDelay() (time.Duration, error) {
+	value := os.Getenv("SHUTDOWN_DELAY")
+	if value == "" {
+		return 0, nil
+	}
+	delay, err := time.ParseDuration(value)
+	if err != nil || delay < 0 {
+		return 0, fmt.Errorf("SHUTDOWN_DELAY must be a duration such as 5s, got %q", value)
+	}
+	return delay, nil
+}
+
+// newHealth returns the health probes, checking that db can be reached and
+// that its schema is up to date.
+func newHealth(db database.Db) *handlers.Health {
+	return handlers.NewHealth(map[string]handlers.Check{
+		"database": func(ctx context.Context) (any, error) {
+			return nil, database.Ping(ctx, db)
+		},
+		"migrations": func(ctx context.Context) (any, error) {
+			version, err := database.CheckSchema(ctx, db)
+			return map[string]int{"version": version}, err
+		},
+	})
+}
+
+// serve runs e on addr until SIGINT or SIGTERM, reporting ready once it is
+// listening. It then reports stopping, waits for delay, and shuts down
+// gracefully, flushing any spans that tp has yet to export.
+func serve(e *echo.Echo, addr string, health *handlers.Health, delay time.Duration, tp trace.TracerProvider) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	done := make(chan error, 1)
+	go func() { done <- e.Start(addr) }()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	for e.ListenerAddr() == nil {
+		select {
+		case err := <-done:
+			ticker.Stop()
+			return err
+		case <-ticker.C:
+		}
+	}
+	ticker.Stop()
+	health.SetState(handlers.StateReady)
+	slog.Info("Listening", "addr", e.ListenerAddr().String())
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+	slog.Info("Shutting down", "delay", delay.String())
+	health.SetState(handlers.StateStopping)
+	time.Sleep(delay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	if err := <-done; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if tp, ok := tp.(*sdktrace.TracerProvider); ok {
+		if err := tp.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to flush traces: %w", err)
+		}
+	}
+	slog.Info("Stopped")
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.40.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// serviceName is the service.name of the spans, unless OTEL_SERVICE_NAME
+// says otherwise.
+const serviceName = "titanbay"
+
+// tracerProvider builds the tracer provider chosen by OTEL_TRACES_EXPORTER:
+//
+//	otlp  spans are exported over OTLP/HTTP, as configured by the standard
+//	      OTEL_EXPORTER_OTLP_* variables, and sampled by OTEL_TRACES_SAMPLER
+//	none  no spans are recorded (the default)
+//
+// It also makes the provider, and W3C trace context propagation, the
+// global defaults.
+func tracerProvider(ctx context.Context) (trace.TracerProvider, error) {
+	var tp trace.TracerProvider
+	switch name := os.Getenv("OTEL_TRACES_EXPORTER"); name {
+	case "", "none":
+		tp = noop.NewTracerProvider()
+	case "otlp":
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+		}
+		res, err := resource.New(ctx,
+			resource.WithAttributes(semconv.ServiceName(serviceName)),
+			resource.WithFromEnv(),
+			resource.WithTelemetrySDK(),
+		)
+		if err != nil {
+			return nil, err
+		}
+		tp = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+		slog.Info("Exporting traces over OTLP")
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q, want otlp or none", name)
+	}
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/webhooks"
+)
+
+// insecureWebhooks reads WEBHOOKS_INSECURE, which lets webhooks use plain
+// http and private addresses, for development against local receivers.
+func insecureWebhooks() (bool, error) {
+	value := os.Getenv("WEBHOOKS_INSECURE")
+	if value == "" {
+		return false, nil
+	}
+	insecure, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("WEBHOOKS_INSECURE must be true or false, got %q", value)
+	}
+	return insecure, nil
+}
+
+// webhookDispatcher returns the dispatcher of db's deliveries, which only
+// delivers to public addresses unless insecure.
+func webhookDispatcher(db database.Db, insecure bool) *webhooks.Dispatcher {
+	d := webhooks.NewDispatcher(db)
+	if insecure {
+		slog.Warn("Webhooks may use http and private addresses: WEBHOOKS_INSECURE is set")
+		d.Client = &http.Client{Timeout: d.Client.Timeout}
+	}
+	return d
+}
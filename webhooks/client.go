@@ -0,0 +1,77 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrNotPublic is returned, wrapped, when a delivery would connect to an
+// address that is not on the public internet.
+var ErrNotPublic = errors.New("address is not public")
+
+// nonPublic lists the ranges, beyond those the netip.Addr predicates cover,
+// that are not reachable on the public internet or reach internal networks.
+var nonPublic = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2002::/16"),
+}
+
+// NewClient returns a client for deliveries, giving up on each after
+// timeout, that only connects to public addresses. Webhook URLs are chosen
+// by clients, so this keeps deliveries away from the deployment's own
+// network, such as 169.254.169.254, the cloud metadata service. The check
+// is made on the address being dialled, after DNS resolution and for every
+// redirect, so a host cannot be resolved to a public address when checked
+// and an internal one when used. It uses no proxy, which would dial on its
+// behalf, past the check.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublic}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// dialPublic refuses connections to addresses that are not public.
+func dialPublic(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublic(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrNotPublic, addrPort.Addr())
+	}
+	return nil
+}
+
+// isPublic reports whether addr is a unicast address on the public
+// internet: not loopback, private, link-local, multicast or reserved.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublic {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+// Package webhooks sends the deliveries that writes queue for webhook
+// subscribers (see database.Db), signing each with the webhook's secret and
+// retrying failures with exponential backoff.
+//
+// A delivery is a POST of the event as JSON, the same payload the outbox
+// publishes, with these headers:
+//
+//	X-Webhook-Delivery   the delivery's ID
+//	X-Webhook-Event      the event type, e.g. FundCreated
+//	X-Webhook-Timestamp  when it was sent, in Unix seconds
+//	X-Webhook-Signature  "sha256=" and the hex HMAC-SHA256 of the timestamp,
+//	                     a '.', and the body, keyed with the secret
+//
+// Any 2xx response is a success. Deliveries are at least once, and replays
+// repeat an event on purpose, so receivers should discard events whose seq
+// they have already seen.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/models"
+)
+
+const (
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign returns the signature of body sent at timestamp, in Unix seconds.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is Sign's for the request, in constant
+// time. Receivers should also reject timestamps too far from their clock,
+// so that a captured request cannot be replayed later.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Dispatcher sends due deliveries. A failed delivery is retried after a
+// backoff that doubles with every attempt, from MinBackoff up to
+// MaxBackoff, and fails for good after MaxAttempts. A webhook is disabled
+// once DisableAfter attempts in a row have failed, across all its
+// deliveries, and its pending deliveries wait until it is enabled again.
+// Several dispatchers may share a database: a claimed delivery is not
+// claimed again until Lease has passed, which must exceed the client's
+// timeout.
+type Dispatcher struct {
+	db     database.Db
+	Client *http.Client
+
+	BatchSize    int
+	Interval     time.Duration
+	Lease        time.Duration
+	MinBackoff   time.Duration
+	MaxBackoff   time.Duration
+	MaxAttempts  int
+	DisableAfter int
+}
+
+// NewDispatcher returns a Dispatcher that sends db's deliveries, only to
+// public addresses; see NewClient.
+func NewDispatcher(db database.Db) *Dispatcher {
+	return &Dispatcher{
+		db:           db,
+		Client:       NewClient(10 * time.Second),
+		BatchSize:    50,
+		Interval:     time.Second,
+		Lease:        time.Minute,
+		MinBackoff:   30 * time.Second,
+		MaxBackoff:   time.Hour,
+		MaxAttempts:  10,
+		DisableAfter: 20,
+	}
+}
+
+// Run sends deliveries until ctx is done, polling every Interval, or again
+// at once after a full batch. Errors are logged and retried.
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		n, err := d.RunOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "Webhook dispatcher failed", "error", err)
+		}
+		wait := d.Interval
+		if err == nil && n == d.BatchSize {
+			wait = 0
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// RunOnce claims a batch of due deliveries and sends them concurrently. It
+// returns the number of deliveries attempted.
+func (d *Dispatcher) RunOnce(ctx context.Context) (int, error) {
+	due, err := d.db.ClaimWebhookDeliveries(ctx, d.BatchSize, d.Lease)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(due))
+	for i, delivery := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = d.db.RecordWebhookAttempt(ctx, d.send(ctx, delivery))
+		}()
+	}
+	wg.Wait()
+	return len(due), errors.Join(errs...)
+}
+
+// send makes one attempt at delivery and describes its outcome.
+func (d *Dispatcher) send(ctx context.Context, delivery models.DueDelivery) models.WebhookAttempt {
+	attempt := models.WebhookAttempt{DeliveryID: delivery.ID, DisableAfter: d.DisableAfter}
+	attempt.StatusCode, attempt.Error = d.post(ctx, delivery)
+	if attempt.Error != "" && delivery.Attempts+1 < d.MaxAttempts {
+		retryAt := time.Now().UTC().Add(d.backoff(delivery.Attempts + 1))
+		attempt.RetryAt = &retryAt
+	}
+	return attempt
+}
+
+// post sends delivery, returning the response status, if any, and an error
+// message unless it succeeded.
+func (d *Dispatcher) post(ctx context.Context, delivery models.DueDelivery) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err.Error()
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderDelivery, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	// Drain a little of the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Sprintf("unexpected response status %s", resp.Status)
+	}
+	return resp.StatusCode, ""
+}
+
+// backoff returns the delay before the attempt after the given one.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	b := d.MinBackoff
+	for i := 1; i < attempts && b < d.MaxBackoff; i++ {
+		b *= 2
+	}
+	return min(b, d.MaxBackoff)
+}
@@ -0,0 +1,230 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iuhmirza/titanbay-take-home/database"
+	"github.com/iuhmirza/titanbay-take-home/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const secret = "0123456789abcdef"
+
+// receiver is a webhook endpoint that checks signatures and answers with
+// the statuses it is given, then 200.
+type receiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	events   []string
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	assert.NoError(rc.t, err)
+	assert.True(rc.t, Verify(secret, timestamp, body, r.Header.Get(HeaderSignature)), "signature must verify")
+	assert.NotEmpty(rc.t, r.Header.Get(HeaderDelivery))
+	var ev struct {
+		Type string `json:"type"`
+	}
+	assert.NoError(rc.t, json.Unmarshal(body, &ev))
+	assert.Equal(rc.t, ev.Type, r.Header.Get(HeaderEvent))
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.events = append(rc.events, ev.Type)
+	status := http.StatusOK
+	if len(rc.statuses) > 0 {
+		status, rc.statuses = rc.statuses[0], rc.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() []string {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return slices.Clone(rc.events)
+}
+
+func setup(t *testing.T, statuses ...int) (*database.MockDb, *Dispatcher, *receiver, models.Webhook) {
+	t.Helper()
+	rc := &receiver{t: t, statuses: statuses}
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+
+	db := database.NewMockDb()
+	webhook, err := db.CreateWebhook(context.Background(), models.CreateWebhook{URL: srv.URL, Secret: secret})
+	assert.NoError(t, err)
+	_, err = db.CreateFund(context.Background(), models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+
+	d := NewDispatcher(db)
+	d.Client = srv.Client()
+	d.MinBackoff = 20 * time.Millisecond
+	d.MaxBackoff = 20 * time.Millisecond
+	return db, d, rc, webhook
+}
+
+func onlyDelivery(t *testing.T, db database.Db, webhook models.Webhook) models.WebhookDelivery {
+	t.Helper()
+	page, err := db.ReadWebhookDeliveries(context.Background(), webhook.ID, models.WebhookDeliveryQuery{})
+	assert.NoError(t, err)
+	if !assert.Len(t, page.Items, 1) {
+		t.FailNow()
+	}
+	return page.Items[0]
+}
+
+func TestDispatcher_RetriesUntilDelivered(t *testing.T) {
+	ctx := context.Background()
+	db, d, rc, webhook := setup(t, http.StatusInternalServerError)
+
+	n, err := d.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	delivery := onlyDelivery(t, db, webhook)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Equal(t, http.StatusInternalServerError, delivery.LastStatusCode)
+	assert.Contains(t, delivery.LastError, "500")
+
+	n, err = d.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, n, "failed deliveries wait for their backoff")
+
+	time.Sleep(30 * time.Millisecond)
+	n, err = d.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	delivery = onlyDelivery(t, db, webhook)
+	assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Empty(t, delivery.LastError)
+	assert.Equal(t, []string{"FundCreated", "FundCreated"}, rc.received())
+
+	webhook, err = db.ReadWebhookByID(ctx, webhook.ID)
+	assert.NoError(t, err)
+	assert.Zero(t, webhook.ConsecutiveFailures, "a success resets the failure count")
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	db, d, _, webhook := setup(t, http.StatusBadGateway, http.StatusBadGateway)
+	d.MaxAttempts = 2
+
+	for range 2 {
+		_, err := d.RunOnce(ctx)
+		assert.NoError(t, err)
+		time.Sleep(30 * time.Millisecond)
+	}
+	delivery := onlyDelivery(t, db, webhook)
+	assert.Equal(t, models.DeliveryFailed, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+}
+
+func TestDispatcher_DisablesFailingWebhooks(t *testing.T) {
+	ctx := context.Background()
+	db, d, rc, webhook := setup(t, http.StatusNotFound, http.StatusNotFound)
+	d.DisableAfter = 2
+
+	for range 3 {
+		_, err := d.RunOnce(ctx)
+		assert.NoError(t, err)
+		time.Sleep(30 * time.Millisecond)
+	}
+	assert.Len(t, rc.received(), 2, "disabled webhooks are not sent to")
+	webhook, err := db.ReadWebhookByID(ctx, webhook.ID)
+	assert.NoError(t, err)
+	assert.False(t, webhook.Enabled)
+	assert.Contains(t, webhook.DisabledReason, "2 consecutive")
+	assert.Equal(t, models.DeliveryPending, onlyDelivery(t, db, webhook).Status)
+
+	webhook.Enabled = true
+	_, err = db.UpdateWebhook(ctx, webhook)
+	assert.NoError(t, err)
+	_, err = d.RunOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliverySucceeded, onlyDelivery(t, db, webhook).Status, "enabling a webhook resumes its deliveries")
+}
+
+func TestDispatcher_Unreachable(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMockDb()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	webhook, err := db.CreateWebhook(ctx, models.CreateWebhook{URL: srv.URL, Secret: secret})
+	assert.NoError(t, err)
+	_, err = db.CreateFund(ctx, models.CreateFund{Name: "Fund I", VintageYear: 2020, Status: "Closed"})
+	assert.NoError(t, err)
+
+	d := NewDispatcher(db)
+	d.Client = srv.Client()
+	_, err = d.RunOnce(ctx)
+	assert.NoError(t, err)
+	delivery := onlyDelivery(t, db, webhook)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Zero(t, delivery.LastStatusCode)
+	assert.NotEmpty(t, delivery.LastError)
+}
+
+func TestDispatcher_NotPublic(t *testing.T) {
+	db, d, rc, webhook := setup(t)
+	d.Client = NewClient(time.Second)
+	_, err := d.RunOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, rc.received(), "loopback receivers are not dialled")
+	delivery := onlyDelivery(t, db, webhook)
+	assert.Equal(t, models.DeliveryPending, delivery.Status)
+	assert.Contains(t, delivery.LastError, ErrNotPublic.Error())
+}
+
+func TestIsPublic(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":      true,
+		"2606:2800:220:1::1": true,
+		"127.0.0.1":          false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"::1":                false,
+		"fe80::1":            false,
+		"fd00::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"64:ff9b::a00:1":     false,
+	} {
+		assert.Equal(t, public, isPublic(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"seq":1}`)
+	signature := Sign(secret, 1700000000, body)
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.True(t, Verify(secret, 1700000000, body, signature))
+	assert.False(t, Verify(secret, 1700000001, body, signature))
+	assert.False(t, Verify(secret, 1700000000, []byte(`{"seq":2}`), signature))
+	assert.False(t, Verify("another secret!!", 1700000000, body, signature))
+}
+
+func TestDispatcher_BackoffDoublesUpToMax(t *testing.T) {
+	d := &Dispatcher{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}
+	var got []time.Duration
+	for attempts := 1; attempts <= 5; attempts++ {
+		got = append(got, d.backoff(attempts))
+	}
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, got)
+}